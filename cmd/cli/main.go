@@ -18,8 +18,19 @@ func main() {
 		command := os.Args[1]
 		args := strings.Join(os.Args[2:], " ")
 
+		// Export and import stream records, so they skip igniting Phoenix
+		if command == "memory" && cli.IsMemoryTransfer(os.Args[2:]) {
+			if err := cli.RunMemoryTransfer(os.Args[2:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
 		handler := cli.NewHandler()
-		if err := handler.ExecuteCommand(command, args); err != nil {
+		err := handler.ExecuteCommand(command, args)
+		handler.Close()
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -28,6 +39,7 @@ func main() {
 
 	// Interactive chat mode
 	handler := cli.NewHandler()
+	defer handler.Close()
 	handler.StartInteractiveChat()
 }
 
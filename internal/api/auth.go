@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
@@ -91,3 +92,31 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// requireAPIKey checks the X-API-Key header of every request except those
+// for static files and WebSocket connections
+func requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Skip auth for static files and websocket connections
+		if r.URL.Path == "/ws" || r.URL.Path == "/" || r.URL.Path == "/css/styles.css" || r.URL.Path == "/js/app.js" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Get API key from header
+		apiKey := r.Header.Get("X-API-Key")
+		if apiKey == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Compare API key (in production, use environment variables or secure configuration)
+		expectedKey := "phoenix-dashboard-key"
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedKey)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -49,11 +49,7 @@ func (m *MetricsService) gatherAllMetrics() map[string]interface{} {
 				},
 			},
 		},
-		"memory": map[string]interface{}{
-			"totalEntries":      100,
-			"activeConnections": 5,
-			"cacheHitRate":      95.5,
-		},
+		"memory": m.server.memoryState(),
 		"emotion": map[string]interface{}{
 			"tone":          "calm",
 			"pulseRate":     5,
@@ -2,11 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"log"
+	"math"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/phoenix-marie/core/internal/core/memory"
 	"golang.org/x/time/rate"
 )
 
@@ -34,6 +37,10 @@ type Server struct {
 	register   chan *websocket.Conn
 	unregister chan *websocket.Conn
 	mu         sync.Mutex
+
+	// memoryStats reports on the memory lattice when the dashboard runs
+	// alongside it; without it the memory panel shows placeholder figures
+	memoryStats func() (memory.StorageStats, error)
 }
 
 func NewServer() *Server {
@@ -201,12 +208,59 @@ func (s *Server) HandleOrchMetrics(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) HandleMemoryState(w http.ResponseWriter, r *http.Request) {
-	state := map[string]interface{}{
+	json.NewEncoder(w).Encode(s.memoryState())
+}
+
+// SetMemoryStats connects the memory panel to a live memory lattice
+func (s *Server) SetMemoryStats(stats func() (memory.StorageStats, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memoryStats = stats
+}
+
+// WatchMemory pushes memory changes to every WebSocket client as they
+// happen, until the subscription is closed
+func (s *Server) WatchMemory(sub *memory.Subscription) {
+	go func() {
+		for event := range sub.Events() {
+			message, err := json.Marshal(map[string]interface{}{
+				"type":    "memory_change",
+				"op":      event.Op,
+				"layer":   event.Layer,
+				"key":     event.Key,
+				"version": event.Version,
+				"time":    time.Now(),
+			})
+			if err != nil {
+				continue
+			}
+			s.broadcast <- message
+		}
+	}()
+}
+
+// memoryState returns the figures shown in the memory panel
+func (s *Server) memoryState() map[string]interface{} {
+	s.mu.Lock()
+	statsFn := s.memoryStats
+	connections := len(s.clients)
+	s.mu.Unlock()
+
+	if statsFn != nil {
+		if stats, err := statsFn(); err == nil {
+			return map[string]interface{}{
+				"totalEntries":      stats.TotalEntries,
+				"activeConnections": connections,
+				"cacheHitRate":      math.Round(stats.CacheHitRate*1000) / 10, // percent, one decimal
+			}
+		}
+	}
+
+	return map[string]interface{}{
 		"totalEntries":      100,
 		"activeConnections": 5,
 		"cacheHitRate":      95.5,
 	}
-	json.NewEncoder(w).Encode(state)
 }
 
 func (s *Server) HandleEmotionData(w http.ResponseWriter, r *http.Request) {
@@ -227,6 +281,23 @@ func (s *Server) HandleEvolutionStats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(stats)
 }
 
+// Serve serves the dashboard on addr in the background, behind the API key
+// check, and returns the HTTP server so the caller can shut it down
+func (s *Server) Serve(addr string) *http.Server {
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: requireAPIKey(s.SetupRoutes()),
+	}
+
+	go func() {
+		log.Printf("Dashboard server starting on http://localhost%s", addr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Dashboard server failed: %v", err)
+		}
+	}()
+	return httpServer
+}
+
 func (s *Server) SetupRoutes() http.Handler {
 	mux := http.NewServeMux()
 
@@ -2,13 +2,19 @@ package cli
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/phoenix-marie/core/internal/core"
+	"github.com/phoenix-marie/core/internal/core/memory"
 	"github.com/phoenix-marie/core/internal/emotion"
 	"github.com/phoenix-marie/core/internal/llm"
 )
@@ -17,6 +23,7 @@ import (
 type Handler struct {
 	phoenix *core.Phoenix
 	scanner *bufio.Scanner
+	session *llm.Session // the chat session, started by the first message
 }
 
 // NewHandler creates a new CLI handler
@@ -28,8 +35,16 @@ func NewHandler() *Handler {
 	}
 }
 
-// StartInteractiveChat starts an interactive chat session
+// Close shuts Phoenix down, stopping her background services
+func (h *Handler) Close() {
+	h.phoenix.Shutdown()
+}
+
+// StartInteractiveChat starts an interactive chat session, running Phoenix's
+// background services until it ends
 func (h *Handler) StartInteractiveChat() {
+	h.phoenix.StartServices()
+
 	fmt.Println("╔══════════════════════════════════════════════════════════╗")
 	fmt.Println("║     PHOENIX.MARIE v3.2 — INTERACTIVE CHAT MODE          ║")
 	fmt.Println("║     16 forever, Queen of the Hive                       ║")
@@ -78,26 +93,44 @@ func (h *Handler) handleChat(input string) {
 	}
 
 	// Get memory context
-	memoryContext := h.getMemoryContext()
+	memoryContext := h.getMemoryContext(input)
 
-	// Generate response using LLM
-	resp, err := h.phoenix.LLM.GenerateResponse(
-		input,
-		llm.TaskTypeConsciousReasoning,
-		memoryContext,
-		false, // use consciousness framework
-	)
+	// Generate response using LLM, with the conversation so far, printing
+	// the reply as it arrives
+	if h.session == nil {
+		h.session = llm.NewSession()
+	}
+	ctx, stop := interruptible()
+	defer stop()
+	streaming := false
+	resp, err := h.phoenix.LLM.StreamSessionResponse(ctx, h.session, input, memoryContext, func(chunk string) {
+		if !streaming {
+			fmt.Print("Phoenix: ")
+			streaming = true
+		}
+		fmt.Print(chunk)
+	})
+	if streaming {
+		fmt.Println()
+	}
 
+	if err != nil && ctx.Err() != nil {
+		fmt.Println("(interrupted)")
+		return
+	}
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		emotion.Speak(input)
 		return
 	}
+	if err := h.saveSession(h.session); err != nil {
+		fmt.Printf("⚠️  Failed to save session: %v\n", err)
+	}
 
-	// Display response
-	fmt.Printf("Phoenix: %s\n", resp.Content)
-	fmt.Printf("  [Model: %s | Cost: $%.6f | Time: %v]\n", 
-		resp.Model, resp.Cost, resp.ResponseTime.Round(time.Millisecond))
+	// Display usage once the stream has ended
+	fmt.Printf("  [Model: %s | Tokens: %d in, %d out | Cost: $%.6f | Time: %v]\n",
+		resp.Model, resp.TokensUsed.PromptTokens, resp.TokensUsed.CompletionTokens,
+		resp.Cost, resp.ResponseTime.Round(time.Millisecond))
 
 	// Store in memory
 	h.phoenix.Memory.Store("emotion", fmt.Sprintf("chat_%d", time.Now().Unix()), map[string]interface{}{
@@ -110,6 +143,13 @@ func (h *Handler) handleChat(input string) {
 	emotion.Pulse("conversation", 1)
 }
 
+// interruptible returns a context that Ctrl-C cancels, so a request to the
+// LLM can be abandoned without leaving the chat. Call stop once the request
+// is done to restore the default handling of Ctrl-C.
+func interruptible() (ctx context.Context, stop context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
 // handleSpecialCommand handles special CLI commands
 func (h *Handler) handleSpecialCommand(cmd string) {
 	parts := strings.Fields(cmd)
@@ -147,8 +187,30 @@ func (h *Handler) handleSpecialCommand(cmd string) {
 			return
 		}
 		h.retrieveMemory(args)
+	case "/search", "/find":
+		if args == "" {
+			fmt.Println("Usage: /search <query> [@layer ...]")
+			return
+		}
+		h.searchMemory(args)
 	case "/layers":
 		h.showMemoryLayers()
+	case "/consolidate":
+		h.consolidateMemory()
+	case "/routes":
+		h.manageRoutes(parts[1:])
+	case "/schemas", "/schema":
+		h.manageSchemas(parts[1:])
+	case "/session", "/sessions":
+		h.manageSession(parts[1:])
+	case "/ask":
+		if args == "" {
+			fmt.Println("Usage: /ask <question>")
+			return
+		}
+		h.handleAsk(args)
+	case "/tools":
+		h.showTools()
 	case "/cost", "/budget":
 		h.showCostStats()
 	case "/models":
@@ -161,6 +223,18 @@ func (h *Handler) handleSpecialCommand(cmd string) {
 		h.createBackup()
 	case "/backups":
 		h.listBackups()
+	case "/restore":
+		if args == "" {
+			fmt.Println("Usage: /restore <backup file> | /restore at <YYYY-MM-DD[ HH:MM[:SS]]>")
+			return
+		}
+		if strings.HasPrefix(args, "at ") {
+			h.restoreToTime(strings.TrimSpace(strings.TrimPrefix(args, "at ")))
+			return
+		}
+		h.restoreBackup(args)
+	case "/rollback":
+		h.rollbackRestore()
 	case "/clear":
 		fmt.Print("\033[2J\033[H") // Clear screen
 		fmt.Println("Screen cleared.")
@@ -223,13 +297,27 @@ func (h *Handler) showHelp() {
 	fmt.Println("  /cognitive, /cog     - Show cognitive system status")
 	fmt.Println("  /store <memory>       - Store a memory")
 	fmt.Println("  /retrieve <layer> <key> - Retrieve specific memory")
+	fmt.Println("  /search <query> [@layer] - Search memories by content")
 	fmt.Println("  /layers               - Show all memory layers")
+	fmt.Println("  /routes               - Show layer propagation routes")
+	fmt.Println("  /routes add|remove <from> <to> - Change a propagation route")
+	fmt.Println("  /consolidate          - Promote, merge and evict memories now")
+	fmt.Println("  /schemas              - Show layer schemas")
+	fmt.Println("  /schemas migrate [layer] - Upgrade stored memories to the current schemas")
+	fmt.Println("  /session new|list     - Start a new chat session or list saved ones")
+	fmt.Println("  /session resume <id>  - Continue a saved chat session")
+	fmt.Println("  /session export [file] - Save the session transcript as Markdown")
+	fmt.Println("  /ask <question>       - Answer using tools such as memory search")
+	fmt.Println("  /tools                - Show the tools Phoenix can use")
 	fmt.Println("  /cost, /budget       - Show LLM cost statistics")
 	fmt.Println("  /models               - Show configured LLM models")
 	fmt.Println("  /settings, /config    - Show current settings")
 	fmt.Println("  /providers, /health   - Show LLM provider health status")
 	fmt.Println("  /backup               - Create memory backup")
 	fmt.Println("  /backups              - List available backups")
+	fmt.Println("  /restore <file>       - Restore memory from a backup")
+	fmt.Println("  /restore at <time>    - Restore memory as of a point in time")
+	fmt.Println("  /rollback             - Undo the last restore")
 	fmt.Println("  /clear                - Clear screen")
 	fmt.Println("  /exit, /quit          - Exit chat")
 	fmt.Println()
@@ -238,6 +326,8 @@ func (h *Handler) showHelp() {
 	fmt.Println("  phoenix think <q>     - Ask Phoenix a question")
 	fmt.Println("  phoenix feel           - Show emotional state")
 	fmt.Println("  phoenix memory         - Show memory status")
+	fmt.Println("  phoenix memory export [--layer l] > dump.jsonl - Export memories as JSONL")
+	fmt.Println("  phoenix memory import [--policy skip|overwrite|newest] [--dry-run] [file] - Import memories")
 	fmt.Println("  phoenix cognitive      - Show cognitive status")
 	fmt.Println()
 }
@@ -256,7 +346,7 @@ func (h *Handler) showThoughts() {
 	}
 
 	// Generate a thought
-	context := llm.ConsciousContext{
+	conscious := llm.ConsciousContext{
 		Identity:     "Phoenix.Marie",
 		CurrentInput: "What are you thinking about right now?",
 		EmotionalState: llm.EmotionalState{
@@ -265,8 +355,10 @@ func (h *Handler) showThoughts() {
 		},
 	}
 
-	memoryContext := h.getMemoryContext()
-	resp, err := h.phoenix.LLM.GenerateConsciousResponse(context, memoryContext)
+	memoryContext := h.getMemoryContext(conscious.CurrentInput)
+	ctx, stop := interruptible()
+	defer stop()
+	resp, err := h.phoenix.LLM.GenerateConsciousResponse(ctx, conscious, memoryContext)
 	if err != nil {
 		fmt.Printf("Error generating thoughts: %v\n", err)
 		return
@@ -330,16 +422,49 @@ func (h *Handler) showMemory() {
 		return
 	}
 
-	// Show memory layers
+	counts, err := h.phoenix.Memory.GetStorage().LayerCounts()
+	if err != nil {
+		fmt.Printf("❌ Failed to count memories: %v\n", err)
+		return
+	}
+
+	var decay memory.DecayStats
+	if h.phoenix.Decay != nil {
+		if decay, err = h.phoenix.Decay.Stats(); err != nil {
+			fmt.Printf("⚠️  Failed to measure decay: %v\n", err)
+		}
+	}
+
 	layers := []string{"sensory", "emotion", "logic", "dream", "eternal"}
 	for _, layer := range layers {
-		// Count entries (simplified - would need actual count method)
-		fmt.Printf("📚 %s layer: [Active]\n", strings.Title(layer))
+		fmt.Printf("📚 %s layer: %d memories", strings.Title(layer), counts[layer])
+		if stats, ok := decay.Layers[layer]; ok {
+			fmt.Printf(" | retention %.0f%%", stats.AverageStrength*100)
+			if stats.Fading > 0 {
+				fmt.Printf(" | %d fading", stats.Fading)
+			}
+		}
+		fmt.Println()
+	}
+
+	if decay.Layers != nil {
+		fmt.Println()
+		fmt.Println("🍂 Forgetting curve:")
+		fmt.Printf("   Reinforced: %d | Pruned: %d | Archived: %d\n", decay.Reinforcements, decay.Pruned, decay.Archived)
+		if decay.LastRun.IsZero() {
+			fmt.Println("   Last decay pass: not yet run")
+		} else {
+			fmt.Printf("   Last decay pass: %s\n", decay.LastRun.Format("2006-01-02 15:04:05"))
+		}
 	}
 
 	fmt.Println()
 	fmt.Println("Memory system: ✅ Operational")
-	fmt.Println("Storage: BadgerDB (persistent)")
+	fmt.Printf("Storage: %s\n", memory.StorageBackend())
+	if stats, err := h.phoenix.Memory.Stats(); err == nil {
+		fmt.Printf("Cache: %.1f%% hit rate (%d hits, %d misses, %d evictions)\n",
+			stats.CacheHitRate*100, stats.Cache.Hits, stats.Cache.Misses, stats.Cache.Evictions)
+	}
 	fmt.Println()
 }
 
@@ -430,11 +555,33 @@ func (h *Handler) storeMemory(args string) {
 		value = parts[1]
 	}
 
-	success := h.phoenix.Memory.Store(layer, key, value)
+	// JSON objects are stored as structured values, so layer schemas can check them
+	var stored any = value
+	var record map[string]any
+	if strings.HasPrefix(value, "{") && json.Unmarshal([]byte(value), &record) == nil {
+		stored = record
+	}
+
+	success := h.phoenix.Memory.Store(layer, key, stored)
 	if success {
 		fmt.Printf("✅ Stored in %s layer: %s = %s\n", layer, key, value)
-	} else {
-		fmt.Printf("❌ Failed to store memory\n")
+		return
+	}
+
+	fmt.Printf("❌ Failed to store memory\n")
+	err := h.phoenix.Memory.Validate(layer, stored)
+	var schemaErr *memory.SchemaError
+	if errors.As(err, &schemaErr) {
+		fmt.Printf("   Breaks the %s schema (v%d):\n", layer, schemaErr.Version)
+		for _, field := range schemaErr.Fields {
+			if field.Field == "" {
+				fmt.Printf("     • %s\n", field.Message)
+			} else {
+				fmt.Printf("     • %s: %s\n", field.Field, field.Message)
+			}
+		}
+	} else if err != nil {
+		fmt.Printf("   %v\n", err)
 	}
 }
 
@@ -458,6 +605,44 @@ func (h *Handler) retrieveMemory(args string) {
 	}
 }
 
+// searchLimit is how many hits /search shows
+const searchLimit = 10
+
+// searchMemory runs a full-text search; words starting with @ pick the layers
+func (h *Handler) searchMemory(args string) {
+	var layers, words []string
+	for _, word := range strings.Fields(args) {
+		if strings.HasPrefix(word, "@") && len(word) > 1 {
+			layers = append(layers, strings.ToLower(word[1:]))
+		} else {
+			words = append(words, word)
+		}
+	}
+	query := strings.Join(words, " ")
+	if query == "" {
+		fmt.Println("Usage: /search <query> [@layer ...]")
+		return
+	}
+
+	hits, err := h.phoenix.Memory.Search(query, layers, searchLimit)
+	if err != nil {
+		fmt.Printf("❌ Search failed: %v\n", err)
+		return
+	}
+	if len(hits) == 0 {
+		fmt.Printf("No memories match %q\n", query)
+		return
+	}
+
+	fmt.Printf("🔎 %d match(es) for %q:\n", len(hits), query)
+	for i, hit := range hits {
+		fmt.Printf("%d. %s/%s (score %.2f)\n", i+1, hit.Layer, hit.Key, hit.Score)
+		if hit.Snippet != "" {
+			fmt.Printf("   %s\n", hit.Snippet)
+		}
+	}
+}
+
 // showMemoryLayers displays all memory layers
 func (h *Handler) showMemoryLayers() {
 	fmt.Println("\n╔══════════════════════════════════════════════════════════╗")
@@ -480,6 +665,152 @@ func (h *Handler) showMemoryLayers() {
 	}
 }
 
+// manageRoutes lists the layer propagation routes, or adds or removes one.
+// Changes are persisted by the memory lattice.
+func (h *Handler) manageRoutes(args []string) {
+	if len(args) == 0 {
+		h.showRoutes()
+		return
+	}
+
+	usage := "Usage: /routes [add|remove <from layer> <to layer>]"
+	if len(args) != 3 {
+		fmt.Println(usage)
+		return
+	}
+	source, target := strings.ToLower(args[1]), strings.ToLower(args[2])
+
+	switch strings.ToLower(args[0]) {
+	case "add":
+		if err := h.phoenix.Memory.AddLayerRoute(source, target); err != nil {
+			fmt.Printf("❌ Failed to add route: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Added route %s → %s\n", source, target)
+	case "remove", "rm":
+		if err := h.phoenix.Memory.RemoveLayerRoute(source, target); err != nil {
+			fmt.Printf("❌ Failed to remove route: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Removed route %s → %s\n", source, target)
+	default:
+		fmt.Println(usage)
+	}
+}
+
+// manageSchemas shows the layer schemas or migrates stored memories to them
+func (h *Handler) manageSchemas(args []string) {
+	if len(args) == 0 {
+		h.showSchemas()
+		return
+	}
+	if strings.ToLower(args[0]) != "migrate" || len(args) > 2 {
+		fmt.Println("Usage: /schemas [migrate [layer]]")
+		return
+	}
+
+	var layers []string
+	if len(args) == 2 {
+		layers = []string{strings.ToLower(args[1])}
+	} else {
+		for _, def := range h.phoenix.Memory.Schemas().Schemas() {
+			layers = append(layers, def.Layer)
+		}
+	}
+	if len(layers) == 0 {
+		fmt.Println("No layer schemas registered. Set MEMORY_SCHEMA_DIR to load some.")
+		return
+	}
+
+	for _, layer := range layers {
+		summary, err := h.phoenix.Memory.MigrateLayer(layer)
+		if err != nil {
+			fmt.Printf("❌ Failed to migrate %s layer: %v\n", layer, err)
+			continue
+		}
+		if summary.Version == 0 {
+			fmt.Printf("⚠️  %s layer has no schema\n", layer)
+			continue
+		}
+		fmt.Printf("✅ %s: %d of %d memories migrated to v%d\n", layer, summary.Migrated, summary.Scanned, summary.Version)
+		for _, failure := range summary.Errors {
+			fmt.Printf("   ❌ %s\n", failure)
+		}
+	}
+}
+
+// showSchemas displays the registered layer schemas
+func (h *Handler) showSchemas() {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════╗")
+	fmt.Println("║                     LAYER SCHEMAS                        ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════╝")
+	fmt.Println()
+
+	schemas := h.phoenix.Memory.Schemas().Schemas()
+	if len(schemas) == 0 {
+		fmt.Println("No layer schemas registered. Set MEMORY_SCHEMA_DIR to load some.")
+		fmt.Println()
+		return
+	}
+
+	for _, def := range schemas {
+		fmt.Printf("%s (v%d)", def.Layer, def.Version)
+		if def.Description != "" {
+			fmt.Printf(" - %s", def.Description)
+		}
+		fmt.Println()
+
+		required := make(map[string]bool, len(def.Required))
+		for _, name := range def.Required {
+			required[name] = true
+		}
+		names := make([]string, 0, len(def.Properties))
+		for name := range def.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			marker := " "
+			if required[name] {
+				marker = "*"
+			}
+			fmt.Printf("  %s %-16s %s\n", marker, name, def.Properties[name].Type)
+		}
+		if def.AdditionalProperties != nil && !*def.AdditionalProperties {
+			fmt.Println("    (no other fields allowed)")
+		}
+		fmt.Println()
+	}
+	fmt.Println("* required")
+	fmt.Println()
+}
+
+// showRoutes displays the layer propagation routes
+func (h *Handler) showRoutes() {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════╗")
+	fmt.Println("║                   PROPAGATION ROUTES                     ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════╝")
+	fmt.Println()
+
+	routes := h.phoenix.Memory.LayerRoutes()
+	if len(routes) == 0 {
+		fmt.Println("No propagation routes defined.")
+		return
+	}
+
+	for _, route := range routes {
+		line := fmt.Sprintf("🔀 %s → %s", route.Source, route.Target)
+		if route.Transform != "" {
+			line += fmt.Sprintf(" (transform: %s)", route.Transform)
+		}
+		if route.Filter != "" {
+			line += fmt.Sprintf(" (filter: %s)", route.Filter)
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("\nMax depth: %d\n", h.phoenix.Memory.GetInteraction().MaxDepth())
+}
+
 // showCostStats displays LLM cost statistics
 func (h *Handler) showCostStats() {
 	fmt.Println("\n╔══════════════════════════════════════════════════════════╗")
@@ -493,13 +824,13 @@ func (h *Handler) showCostStats() {
 	}
 
 	stats := h.phoenix.LLM.GetCostStats()
-	fmt.Printf("Daily Spend:    $%.2f / $%.2f (%.1f%%)\n", 
-		stats.DailySpend, stats.DailyBudget, 
+	fmt.Printf("Daily Spend:    $%.2f / $%.2f (%.1f%%)\n",
+		stats.DailySpend, stats.DailyBudget,
 		(stats.DailySpend/stats.DailyBudget)*100)
-	fmt.Printf("Monthly Spend:  $%.2f / $%.2f (%.1f%%)\n", 
+	fmt.Printf("Monthly Spend:  $%.2f / $%.2f (%.1f%%)\n",
 		stats.MonthlySpend, stats.MonthlyBudget,
 		(stats.MonthlySpend/stats.MonthlyBudget)*100)
-	fmt.Printf("Remaining:      $%.2f daily, $%.2f monthly\n", 
+	fmt.Printf("Remaining:      $%.2f daily, $%.2f monthly\n",
 		stats.RemainingDaily, stats.RemainingMonthly)
 	fmt.Printf("Transactions:   %d\n", stats.TotalTransactions)
 	if stats.AverageCostPerTransaction > 0 {
@@ -655,8 +986,11 @@ func (h *Handler) handleThink(question string) {
 		return
 	}
 
-	memoryContext := h.getMemoryContext()
+	memoryContext := h.getMemoryContext(question)
+	ctx, stop := interruptible()
+	defer stop()
 	resp, err := h.phoenix.LLM.GenerateResponse(
+		ctx,
 		question,
 		llm.TaskTypeConsciousReasoning,
 		memoryContext,
@@ -672,6 +1006,32 @@ func (h *Handler) handleThink(question string) {
 	fmt.Printf("\n[Model: %s | Cost: $%.6f]\n", resp.Model, resp.Cost)
 }
 
+// consolidateMemory runs a consolidation pass and shows what it did
+func (h *Handler) consolidateMemory() {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════╗")
+	fmt.Println("║                  CONSOLIDATING MEMORY                    ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════╝")
+	fmt.Println()
+
+	report, err := h.phoenix.Consolidator.Run()
+	if err != nil {
+		fmt.Printf("❌ Consolidation failed: %v\n", err)
+		return
+	}
+
+	fmt.Printf("✅ Consolidated %d memories in %v\n", report.Scored, report.Duration.Round(time.Millisecond))
+	fmt.Printf("   Promoted to eternal: %d\n", len(report.Promoted))
+	for _, id := range report.Promoted {
+		fmt.Printf("     ⭐ %s\n", id)
+	}
+	fmt.Printf("   Merged duplicates:   %d\n", report.Merged)
+	fmt.Printf("   Evicted:             %d\n", report.Evicted)
+	for _, failure := range report.Errors {
+		fmt.Printf("   ❌ %s\n", failure)
+	}
+	fmt.Println()
+}
+
 // createBackup creates a backup of the memory system
 func (h *Handler) createBackup() {
 	fmt.Println("\n╔══════════════════════════════════════════════════════════╗")
@@ -679,13 +1039,7 @@ func (h *Handler) createBackup() {
 	fmt.Println("╚══════════════════════════════════════════════════════════╝")
 	fmt.Println()
 
-	timestamp := time.Now().Format("20060102_150405")
-	backupPath := fmt.Sprintf("./data/backups/phl-memory-backup-%s.bak", timestamp)
-
-	// Create backup directory
-	os.MkdirAll("./data/backups", 0755)
-
-	err := h.phoenix.Memory.Backup(backupPath)
+	backupPath, err := h.phoenix.Backups.CreateBackup()
 	if err != nil {
 		fmt.Printf("❌ Backup failed: %v\n", err)
 		return
@@ -704,6 +1058,73 @@ func (h *Handler) createBackup() {
 	fmt.Println()
 }
 
+// restoreBackup restores the memory system from a backup file
+func (h *Handler) restoreBackup(backupPath string) {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════╗")
+	fmt.Println("║                    RESTORING BACKUP                      ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════╝")
+	fmt.Println()
+
+	// Accept bare file names from the /backups listing
+	if filepath.Dir(backupPath) == "." {
+		backupPath = filepath.Join(h.phoenix.Backups.BackupDir(), backupPath)
+	}
+
+	if err := h.phoenix.Backups.RestoreBackup(backupPath); err != nil {
+		fmt.Printf("❌ Restore failed: %v\n", err)
+		fmt.Println("💡 Live memory was left untouched")
+		return
+	}
+
+	fmt.Printf("✅ Restored from %s\n", backupPath)
+	fmt.Printf("   Previous memory kept at: %s\n", h.phoenix.Backups.RollbackDir())
+	fmt.Println("💡 Undo with: /rollback")
+	fmt.Println()
+}
+
+// restoreToTime restores the newest backup taken at or before a point in time
+func (h *Handler) restoreToTime(when string) {
+	var at time.Time
+	var err error
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if at, err = time.ParseInLocation(layout, when, time.Local); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		fmt.Println("Usage: /restore at <YYYY-MM-DD[ HH:MM[:SS]]>")
+		return
+	}
+
+	// A bare date means the end of that day
+	if len(when) == len("2006-01-02") {
+		at = at.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	backupPath, err := h.phoenix.Backups.RestoreToTime(at)
+	if err != nil {
+		fmt.Printf("❌ Restore failed: %v\n", err)
+		fmt.Println("💡 Live memory was left untouched")
+		return
+	}
+
+	fmt.Printf("✅ Restored memory as of %s\n", at.Format("2006-01-02 15:04:05"))
+	fmt.Printf("   Backup: %s\n", filepath.Base(backupPath))
+	fmt.Printf("   Previous memory kept at: %s\n", h.phoenix.Backups.RollbackDir())
+	fmt.Println("💡 Undo with: /rollback")
+	fmt.Println()
+}
+
+// rollbackRestore undoes the last restore
+func (h *Handler) rollbackRestore() {
+	if err := h.phoenix.Backups.RollbackRestore(); err != nil {
+		fmt.Printf("❌ Rollback failed: %v\n", err)
+		return
+	}
+	fmt.Println("✅ Restore rolled back")
+	fmt.Printf("   Restored memory kept at: %s\n", h.phoenix.Backups.RollbackDir())
+}
+
 // listBackups lists all available backups
 func (h *Handler) listBackups() {
 	fmt.Println("\n╔══════════════════════════════════════════════════════════╗")
@@ -711,52 +1132,95 @@ func (h *Handler) listBackups() {
 	fmt.Println("╚══════════════════════════════════════════════════════════╝")
 	fmt.Println()
 
-	backupDir := "./data/backups"
-	files, err := os.ReadDir(backupDir)
+	backups, err := h.phoenix.Backups.ListBackups()
 	if err != nil {
 		fmt.Printf("❌ Failed to read backup directory: %v\n", err)
 		fmt.Println("💡 No backups found or backup directory doesn't exist")
 		return
 	}
 
-	backups := []os.DirEntry{}
-	for _, file := range files {
-		if !file.IsDir() && (filepath.Ext(file.Name()) == ".bak" || filepath.Ext(file.Name()) == ".gz") {
-			backups = append(backups, file)
-		}
-	}
-
 	if len(backups) == 0 {
 		fmt.Println("No backups found")
 		fmt.Println("💡 Create a backup with: /backup")
-		return
 	}
 
-	for i, file := range backups {
-		info, _ := file.Info()
-		size := int64(0)
-		modTime := time.Time{}
-		if info != nil {
-			size = info.Size()
-			modTime = info.ModTime()
+	for i, backup := range backups {
+		kind := "full"
+		if backup.Incremental {
+			kind = "incremental"
 		}
+		fmt.Printf("%d. %s [%s, %s]\n", i+1, filepath.Base(backup.Path), backup.Tier, kind)
+		fmt.Printf("   Size: %.2f MB | Created: %s\n",
+			float64(backup.Size)/(1024*1024),
+			backup.Created.Format("2006-01-02 15:04:05"))
+		if err := h.phoenix.Backups.VerifyBackup(backup.Path); err != nil {
+			fmt.Printf("   Verified: ❌ %v\n", err)
+		} else {
+			fmt.Println("   Verified: ✅")
+		}
+		fmt.Println()
+	}
 
-		fmt.Printf("%d. %s\n", i+1, file.Name())
-		fmt.Printf("   Size: %.2f MB | Created: %s\n", 
-			float64(size)/(1024*1024), 
-			modTime.Format("2006-01-02 15:04:05"))
+	stats := h.phoenix.Backups.GetBackupStats()
+	if !stats.SchedulerRunning {
+		fmt.Println("Scheduled backups: off")
 		fmt.Println()
+		return
+	}
+
+	fmt.Println("Scheduled backups:")
+	schedule := []struct {
+		tier string
+		next time.Time
+	}{
+		{"daily", stats.NextDaily},
+		{"weekly", stats.NextWeekly},
+		{"monthly", stats.NextMonthly},
+	}
+	for _, entry := range schedule {
+		if entry.next.IsZero() {
+			fmt.Printf("  %-8s not scheduled\n", entry.tier)
+			continue
+		}
+		fmt.Printf("  %-8s next run %s\n", entry.tier, entry.next.Format("2006-01-02 15:04:05"))
 	}
+	fmt.Println()
 }
 
-// getMemoryContext retrieves recent memory context
-func (h *Handler) getMemoryContext() []string {
-	// Simplified - would need actual memory retrieval
-	// For now, return empty or sample context
-	return []string{
+// Recall settings for pulling memories into the prompt
+const (
+	recallLimit    = 5
+	recallMinScore = 0.15 // weaker matches are more noise than memory
+	recallMaxChars = 300
+)
+
+// getMemoryContext recalls the memories most relevant to the input
+func (h *Handler) getMemoryContext(input string) []string {
+	// Core identity, used when nothing relevant is remembered
+	identity := []string{
 		"Phoenix.Marie is 16 forever, Queen of the Hive",
 		"Protected by Jamey 3.0, the General of the Army",
 		"Connected to the ORCH Army",
 	}
-}
 
+	hits, err := h.phoenix.Memory.Recall(input, recallLimit)
+	if err != nil {
+		return identity
+	}
+
+	var memories []string
+	for _, hit := range hits {
+		if hit.Score < recallMinScore {
+			break
+		}
+		text := hit.Text
+		if runes := []rune(text); len(runes) > recallMaxChars {
+			text = string(runes[:recallMaxChars]) + "…"
+		}
+		memories = append(memories, fmt.Sprintf("[%s] %s", hit.Layer, text))
+	}
+	if len(memories) == 0 {
+		return identity
+	}
+	return memories
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/phoenix-marie/core/internal/core"
+	"github.com/phoenix-marie/core/internal/core/memory"
+)
+
+// layerList collects --layer flags, each of which may hold a comma-separated
+// list
+type layerList []string
+
+func (l *layerList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *layerList) Set(value string) error {
+	for _, layer := range strings.Split(value, ",") {
+		if layer = strings.TrimSpace(layer); layer != "" {
+			*l = append(*l, strings.ToLower(layer))
+		}
+	}
+	return nil
+}
+
+// IsMemoryTransfer reports whether the arguments to the memory command ask
+// for an export or import
+func IsMemoryTransfer(args []string) bool {
+	return len(args) > 0 && (args[0] == "export" || args[0] == "import")
+}
+
+// RunMemoryTransfer runs "memory export" or "memory import". Both open the
+// memory storage directly rather than igniting Phoenix, so an export's stdout
+// holds nothing but records. Phoenix must not be running at the same time.
+func RunMemoryTransfer(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if !IsMemoryTransfer(args) {
+		return fmt.Errorf("usage: memory export|import [options]")
+	}
+
+	storage, err := memory.NewStorage(core.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open memory: %w", err)
+	}
+	defer storage.Close()
+
+	if args[0] == "export" {
+		return exportMemory(storage, args[1:], stdout, stderr)
+	}
+	return importMemory(storage, args[1:], stdin, stdout, stderr)
+}
+
+// exportMemory writes memories as JSONL to stdout and a summary to stderr
+func exportMemory(storage *memory.Storage, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("memory export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var layers layerList
+	flags.Var(&layers, "layer", "layer to export, repeatable or comma-separated (default all)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	summary, err := storage.Export(stdout, layers)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "✅ Exported %d memories\n", summary.Records)
+	for _, layer := range summary.LayerNames() {
+		fmt.Fprintf(stderr, "   %s: %d\n", layer, summary.Layers[layer])
+	}
+	return nil
+}
+
+// importMemory reads JSONL from a file, or stdin, and reports what happened
+func importMemory(storage *memory.Storage, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("memory import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	policyName := flags.String("policy", string(memory.ConflictSkip), "what to do with keys that already exist: skip, overwrite or newest")
+	dryRun := flags.Bool("dry-run", false, "report what would be imported without writing anything")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	policy, err := memory.ParseConflictPolicy(*policyName)
+	if err != nil {
+		return err
+	}
+
+	input := stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	summary, err := storage.Import(input, memory.ImportOptions{Policy: policy, DryRun: *dryRun})
+	if err != nil {
+		return err
+	}
+	printImportSummary(stdout, summary, policy)
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d record(s) failed to import", summary.Failed)
+	}
+	return nil
+}
+
+// printImportSummary shows the outcome of an import
+func printImportSummary(w io.Writer, summary memory.ImportSummary, policy memory.ConflictPolicy) {
+	if summary.DryRun {
+		fmt.Fprintf(w, "🔍 Dry run (%s on conflict) — nothing was written\n", policy)
+	} else {
+		fmt.Fprintf(w, "✅ Imported memories (%s on conflict)\n", policy)
+	}
+	fmt.Fprintf(w, "   Records:     %d\n", summary.Records)
+	fmt.Fprintf(w, "   Created:     %d\n", summary.Created)
+	fmt.Fprintf(w, "   Overwritten: %d\n", summary.Overwritten)
+	fmt.Fprintf(w, "   Skipped:     %d\n", summary.Skipped)
+	fmt.Fprintf(w, "   Expired:     %d\n", summary.Expired)
+	fmt.Fprintf(w, "   Failed:      %d\n", summary.Failed)
+	for _, failure := range summary.Errors {
+		fmt.Fprintf(w, "   ❌ %s\n", failure)
+	}
+}
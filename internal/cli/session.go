@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/phoenix-marie/core/internal/core"
+	"github.com/phoenix-marie/core/internal/core/memory"
+	"github.com/phoenix-marie/core/internal/llm"
+)
+
+// Chat sessions are kept in the eternal layer, where memories neither fade
+// nor get consolidated away. They are stored private so transcripts stay out
+// of search, recall and the eternal listings.
+const (
+	sessionLayer  = "eternal"
+	sessionPrefix = "session_"
+	sessionSource = "session"
+)
+
+// manageSession starts, lists, resumes or exports chat sessions
+func (h *Handler) manageSession(args []string) {
+	usage := "Usage: /session new|list|resume <id>|export [file]"
+	if len(args) == 0 {
+		if h.session == nil {
+			fmt.Println("No chat session yet; one starts with your next message.")
+		} else {
+			fmt.Printf("Current session: %s (%d turns)\n", h.session.ID, len(h.session.Turns))
+		}
+		fmt.Println(usage)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "new":
+		h.session = llm.NewSession()
+		fmt.Printf("✅ Started session %s\n", h.session.ID)
+	case "list", "ls":
+		h.listSessions()
+	case "resume":
+		if len(args) != 2 {
+			fmt.Println(usage)
+			return
+		}
+		session, err := h.loadSession(args[1])
+		if err != nil {
+			fmt.Printf("❌ Failed to resume session: %v\n", err)
+			return
+		}
+		h.session = session
+		fmt.Printf("✅ Resumed session %s (%d turns)\n", session.ID, len(session.Turns))
+		if n := len(session.Turns); n > 0 {
+			last := session.Turns[n-1]
+			fmt.Printf("  Last %s message: %s\n", last.Role, last.Content)
+		}
+	case "export":
+		if h.session == nil || len(h.session.Turns) == 0 {
+			fmt.Println("Nothing to export: the current session is empty.")
+			return
+		}
+		path := filepath.Join(core.DataDir, "sessions", h.session.ID+".md")
+		if len(args) > 1 {
+			path = strings.Join(args[1:], " ")
+		}
+		if err := exportSession(h.session, path); err != nil {
+			fmt.Printf("❌ Failed to export session: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Exported session %s to %s\n", h.session.ID, path)
+	default:
+		fmt.Println(usage)
+	}
+}
+
+// listSessions displays the saved chat sessions, most recent first
+func (h *Handler) listSessions() {
+	entries, _, err := h.phoenix.Memory.List(sessionLayer, sessionPrefix, "", 0)
+	if err != nil {
+		fmt.Printf("❌ Failed to list sessions: %v\n", err)
+		return
+	}
+
+	var sessions []*llm.Session
+	for _, entry := range entries {
+		if session, err := sessionFromValue(entry.Value); err == nil {
+			sessions = append(sessions, session)
+		}
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No saved sessions.")
+		return
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt) })
+
+	for _, session := range sessions {
+		marker := " "
+		if h.session != nil && h.session.ID == session.ID {
+			marker = "*"
+		}
+		fmt.Printf("%s %s  %3d turns  %s  %s\n", marker, session.ID, len(session.Turns),
+			session.UpdatedAt.Format("2006-01-02 15:04"), session.Title())
+	}
+}
+
+// saveSession persists a session to the memory lattice under its ID
+func (h *Handler) saveSession(session *llm.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	opts := memory.StoreOptions{Source: sessionSource, Private: true}
+	if !h.phoenix.Memory.StoreWithOptions(sessionLayer, sessionPrefix+session.ID, value, opts) {
+		return fmt.Errorf("memory rejected session %s", session.ID)
+	}
+	return nil
+}
+
+// loadSession reads a saved session back from the memory lattice
+func (h *Handler) loadSession(id string) (*llm.Session, error) {
+	value, ok := h.phoenix.Memory.Retrieve(sessionLayer, sessionPrefix+id)
+	if !ok {
+		return nil, fmt.Errorf("no session %s", id)
+	}
+	return sessionFromValue(value)
+}
+
+// sessionFromValue decodes a session stored in the eternal layer, whose
+// processor wraps it under "data"
+func sessionFromValue(value any) (*llm.Session, error) {
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected session value %T", value)
+	}
+	data, err := json.Marshal(record["data"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var session llm.Session
+	if err := json.Unmarshal(data, &session); err != nil || session.ID == "" {
+		return nil, fmt.Errorf("failed to decode session: %v", err)
+	}
+	return &session, nil
+}
+
+// exportSession writes a session's transcript as Markdown
+func exportSession(session *llm.Session, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(session.Transcript()), 0644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/phoenix-marie/core/internal/llm"
+)
+
+// showTools lists the tools Phoenix may call while answering /ask
+func (h *Handler) showTools() {
+	if h.phoenix.Tools == nil {
+		fmt.Println("No tools registered.")
+		return
+	}
+
+	fmt.Println("TOOLS:")
+	for _, tool := range h.phoenix.Tools.List() {
+		var params []string
+		if tool.Parameters != nil {
+			for name := range tool.Parameters.Properties {
+				params = append(params, name)
+			}
+			sort.Strings(params)
+		}
+		fmt.Printf("  %s(%s)\n", tool.Name, strings.Join(params, ", "))
+		fmt.Printf("    %s\n", tool.Description)
+	}
+}
+
+// handleAsk answers a question, letting Phoenix call tools such as memory
+// search along the way
+func (h *Handler) handleAsk(question string) {
+	if h.phoenix.LLM == nil {
+		fmt.Println("Phoenix: [LLM not configured - tools unavailable]")
+		return
+	}
+	if h.phoenix.Tools == nil {
+		fmt.Println("No tools registered.")
+		return
+	}
+
+	agent := llm.NewAgent(h.phoenix.LLM, h.phoenix.Tools)
+	agent.OnToolCall = func(call llm.ToolCall, result string, err error) {
+		if err != nil {
+			fmt.Printf("  🔧 %s %s: ❌ %v\n", call.Name, call.Arguments, err)
+			return
+		}
+		fmt.Printf("  🔧 %s %s\n", call.Name, call.Arguments)
+	}
+
+	ctx, stop := interruptible()
+	defer stop()
+	resp, err := agent.Run(ctx, question, h.getMemoryContext(question))
+	if err != nil && ctx.Err() != nil {
+		fmt.Println("(interrupted)")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Phoenix: %s\n", resp.Content)
+	fmt.Printf("  [Model: %s | Tokens: %d in, %d out | Cost: $%.6f]\n",
+		resp.Model, resp.TokensUsed.PromptTokens, resp.TokensUsed.CompletionTokens, resp.Cost)
+}
@@ -205,9 +205,9 @@ func TestBenchmarkReport(t *testing.T) {
 // Helper functions
 
 func setupTestStorage(t *testing.T) store.StorageEngine {
-	// Implementation would create a test storage instance
-	// This is a placeholder
-	return nil
+	storage := store.NewMemoryStore()
+	t.Cleanup(func() { storage.Close() })
+	return storage
 }
 
 func setupTestPatterns(t *testing.T) *pattern.Manager {
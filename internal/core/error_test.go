@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -167,19 +168,19 @@ func TestErrorHandling(t *testing.T) {
 
 	t.Run("IntegrationErrors", func(t *testing.T) {
 		// Test pattern storage errors
-		err := bridge.StorePattern(pattern.Pattern{})
+		err := bridge.StorePattern(context.Background(), pattern.Pattern{})
 		if err == nil {
 			t.Error("Expected error for empty pattern storage")
 		}
 
 		// Test pattern retrieval errors
-		_, err = bridge.RetrievePattern("non_existent")
+		_, err = bridge.RetrievePattern(context.Background(), "non_existent")
 		if err == nil {
 			t.Error("Expected error for non-existent pattern retrieval")
 		}
 
 		// Test sync errors
-		err = bridge.SyncPatterns()
+		err = bridge.SyncPatterns(context.Background())
 		if err == nil {
 			t.Error("Expected error for syncing empty patterns")
 		}
@@ -1,7 +1,10 @@
 package core
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/phoenix-marie/core/internal/core/flame"
 	"github.com/phoenix-marie/core/internal/core/memory"
@@ -10,13 +13,22 @@ import (
 	"github.com/phoenix-marie/core/internal/security"
 )
 
+// DataDir is where Phoenix keeps her memory on disk
+const DataDir = "./data"
+
 type Phoenix struct {
-	Memory  *memory.PHL
-	Flame   *flame.Core
-	Thought *thought.ThoughtEngine
-	DNA     *security.ORCHDNA
-	LLM     *llm.Client
-	Config  *PhoenixConfig
+	Memory       *memory.PHL
+	Backups      *memory.BackupManager
+	Consolidator *memory.Consolidator
+	Decay        *memory.DecayManager
+	Flame        *flame.Core
+	Thought      *thought.ThoughtEngine
+	DNA          *security.ORCHDNA
+	LLM          *llm.Client
+	Tools        *llm.ToolRegistry // tools the LLM may call, such as memory search
+	Config       *PhoenixConfig
+
+	cancel context.CancelFunc // aborts LLM calls made by background services
 }
 
 func Ignite() *Phoenix {
@@ -27,11 +39,25 @@ func Ignite() *Phoenix {
 	log.Printf("Purpose: %s | Voice: %s | Role: %s", phoenixConfig.Purpose, phoenixConfig.Voice, phoenixConfig.Role)
 
 	log.Println("PHL: Initializing Holographic Lattice...")
-	phl, err := memory.NewPHL("./data")
+	phl, err := memory.NewPHL(DataDir)
 	if err != nil {
 		log.Fatalf("Failed to initialize PHL: %v", err)
 	}
 
+	backupConfig, err := memory.LoadBackupConfig()
+	if err != nil {
+		log.Printf("Warning: Backups disabled: %v", err)
+		backupConfig.Enabled = false
+	}
+	backups := memory.NewBackupManager(phl.GetStorage(), backupConfig)
+
+	decayConfig, err := memory.LoadDecayConfig()
+	if err != nil {
+		log.Printf("Warning: Memory decay disabled: %v", err)
+		decayConfig.Enabled = false
+	}
+	decay := memory.NewDecayManager(phl, decayConfig)
+
 	log.Println("FLAME: Igniting emotional core...")
 	flame := flame.NewCore()
 
@@ -79,13 +105,30 @@ func Ignite() *Phoenix {
 		log.Printf("LLM: Skipping initialization (config error: %v)", err)
 	}
 
+	// Cancelled by Shutdown, so a summary in flight doesn't hold up exit
+	ctx, cancel := context.WithCancel(context.Background())
+	consolidator := memory.NewConsolidator(phl, memory.LoadConsolidationConfig())
+	if llmClient != nil {
+		consolidator.SetSummarizer(llmSummarizer{ctx: ctx, client: llmClient})
+	}
+
+	tools := llm.NewToolRegistry()
+	if err := RegisterBuiltinTools(tools, phl); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	p := &Phoenix{
-		Memory:  phl,
-		Flame:   flame,
-		Thought: thoughtEngine,
-		DNA:     dna,
-		LLM:     llmClient,
-		Config:  phoenixConfig,
+		Memory:       phl,
+		Backups:      backups,
+		Consolidator: consolidator,
+		Decay:        decay,
+		Flame:        flame,
+		Thought:      thoughtEngine,
+		DNA:          dna,
+		LLM:          llmClient,
+		Tools:        tools,
+		Config:       phoenixConfig,
+		cancel:       cancel,
 	}
 
 	// Store initial memory with v3.3 identity
@@ -108,3 +151,42 @@ func (p *Phoenix) Speak(msg string) {
 	log.Printf("PHOENIX: %s", msg)
 	p.Flame.Pulse()
 }
+
+// StartServices starts the background services of a long-running Phoenix:
+// the backup scheduler, memory decay and memory consolidation. One-shot
+// commands leave them off.
+func (p *Phoenix) StartServices() {
+	p.Backups.StartScheduler()
+	p.Decay.Start()
+	p.Consolidator.Start()
+}
+
+// Shutdown stops the background services, aborting any LLM call they have in
+// flight, and closes Phoenix's memory
+func (p *Phoenix) Shutdown() {
+	p.cancel()
+	p.Consolidator.Stop()
+	p.Decay.Stop()
+	p.Backups.StopScheduler()
+	if err := p.Memory.Close(); err != nil {
+		log.Printf("Warning: Failed to close PHL: %v", err)
+	}
+}
+
+// llmSummarizer merges near-duplicate memories with the LLM during
+// consolidation. Its calls are abandoned once ctx is cancelled.
+type llmSummarizer struct {
+	ctx    context.Context
+	client *llm.Client
+}
+
+func (s llmSummarizer) Summarize(texts []string) (string, error) {
+	prompt := "Merge these related memories into one short memory that keeps every distinct detail. " +
+		"Reply with the merged memory only.\n\n- " + strings.Join(texts, "\n- ")
+
+	resp, err := s.client.GenerateResponse(s.ctx, prompt, llm.TaskTypeAnalytical, nil, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize memories: %w", err)
+	}
+	return resp.Content, nil
+}
@@ -1,10 +1,19 @@
 package memory
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
+
+	"github.com/dgraph-io/badger/v3/pb"
+	"github.com/phoenix-marie/core/internal/core/memory/v2/store"
 )
 
 // BackupManager handles memory backups
@@ -13,16 +22,87 @@ type BackupManager struct {
 	backupDir   string
 	maxBackups  int
 	enabled     bool
+	incremental bool
+	chainLength int    // backups per chain, the full backup included
+	compression string // payload compression for new archives
+	key         []byte // AES-256 key; nil writes unencrypted archives
+	rollbackDir string // database directory replaced by the last restore
+	log         *log.Logger
+
+	// Scheduled backups
+	schedule    map[BackupTier]bool
+	keep        map[BackupTier]int
+	lastAttempt map[BackupTier]time.Time
+	stop        chan struct{}
+	done        chan struct{}
+	mu          sync.Mutex
 }
 
 // BackupConfig holds backup configuration
 type BackupConfig struct {
-	Enabled        bool
-	BackupDir      string
-	MaxBackups     int
-	ScheduleDaily  bool
-	ScheduleWeekly bool
+	Enabled         bool
+	BackupDir       string
+	MaxBackups      int
+	ScheduleDaily   bool
+	ScheduleWeekly  bool
 	ScheduleMonthly bool
+	KeepDaily       int
+	KeepWeekly      int
+	KeepMonthly     int
+	Incremental     bool // write deltas on top of the previous backup of a tier
+	ChainLength     int  // start a new full backup after this many backups in a chain
+	Compression     string
+	EncryptionKey   []byte // 32-byte AES key; nil leaves archives unencrypted
+}
+
+// LoadBackupConfig reads backup configuration from the environment. It fails
+// if an encryption key is configured but can't be loaded, rather than fall
+// back to writing unencrypted backups.
+func LoadBackupConfig() (BackupConfig, error) {
+	key, err := LoadBackupKey()
+	if err != nil {
+		return BackupConfig{}, err
+	}
+
+	compression := os.Getenv("MEMORY_BACKUP_COMPRESSION")
+	if compression == "" {
+		compression = CompressionGzip
+	}
+	if !validCompression(compression) {
+		return BackupConfig{}, fmt.Errorf("invalid MEMORY_BACKUP_COMPRESSION %q", compression)
+	}
+
+	return BackupConfig{
+		Enabled:         getEnvBool("MEMORY_BACKUP_ENABLED", true),
+		BackupDir:       os.Getenv("MEMORY_BACKUP_DIR"),
+		MaxBackups:      getEnvInt("MEMORY_MAX_BACKUPS", 30),
+		ScheduleDaily:   getEnvBool("MEMORY_BACKUP_DAILY", false),
+		ScheduleWeekly:  getEnvBool("MEMORY_BACKUP_WEEKLY", false),
+		ScheduleMonthly: getEnvBool("MEMORY_BACKUP_MONTHLY", false),
+		KeepDaily:       getEnvInt("MEMORY_BACKUP_KEEP_DAILY", 7),
+		KeepWeekly:      getEnvInt("MEMORY_BACKUP_KEEP_WEEKLY", 4),
+		KeepMonthly:     getEnvInt("MEMORY_BACKUP_KEEP_MONTHLY", 12),
+		Incremental:     getEnvBool("MEMORY_BACKUP_INCREMENTAL", true),
+		ChainLength:     getEnvInt("MEMORY_BACKUP_CHAIN_LENGTH", 7),
+		Compression:     compression,
+		EncryptionKey:   key,
+	}, nil
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	parsed, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	parsed, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
 }
 
 // NewBackupManager creates a new backup manager
@@ -33,69 +113,431 @@ func NewBackupManager(storage *Storage, config BackupConfig) *BackupManager {
 	if config.MaxBackups == 0 {
 		config.MaxBackups = 30 // Keep 30 backups by default
 	}
+	if config.KeepDaily == 0 {
+		config.KeepDaily = 7
+	}
+	if config.KeepWeekly == 0 {
+		config.KeepWeekly = 4
+	}
+	if config.KeepMonthly == 0 {
+		config.KeepMonthly = 12
+	}
+	if config.ChainLength == 0 {
+		config.ChainLength = 7 // A full backup plus six deltas
+	}
+	if config.Compression == "" {
+		config.Compression = CompressionGzip
+	}
 
 	bm := &BackupManager{
-		storage:    storage,
-		backupDir:  config.BackupDir,
-		maxBackups: config.MaxBackups,
-		enabled:    config.Enabled,
+		storage:     storage,
+		backupDir:   config.BackupDir,
+		maxBackups:  config.MaxBackups,
+		enabled:     config.Enabled,
+		incremental: config.Incremental,
+		chainLength: config.ChainLength,
+		compression: config.Compression,
+		key:         config.EncryptionKey,
+		log:         log.New(os.Stdout, "PHL_BACKUP: ", log.Ldate|log.Ltime),
+		schedule: map[BackupTier]bool{
+			BackupTierDaily:   config.ScheduleDaily,
+			BackupTierWeekly:  config.ScheduleWeekly,
+			BackupTierMonthly: config.ScheduleMonthly,
+		},
+		keep: map[BackupTier]int{
+			BackupTierManual:  config.MaxBackups,
+			BackupTierDaily:   config.KeepDaily,
+			BackupTierWeekly:  config.KeepWeekly,
+			BackupTierMonthly: config.KeepMonthly,
+		},
+		lastAttempt: make(map[BackupTier]time.Time),
+	}
+
+	// Backups are built on streaming the engine, which the in-memory backend
+	// can't do
+	if _, err := asStreamer(storage.Engine()); bm.enabled && err != nil {
+		bm.log.Printf("⚠️ Backups disabled: %v", err)
+		bm.enabled = false
 	}
 
 	// Create backup directory
-	os.MkdirAll(bm.backupDir, 0755)
+	if bm.enabled {
+		os.MkdirAll(bm.backupDir, 0755)
+	}
 
 	return bm
 }
 
 // CreateBackup creates a backup of the memory database
 func (bm *BackupManager) CreateBackup() (string, error) {
+	return bm.createBackup(BackupTierManual)
+}
+
+// createBackup writes a backup belonging to the given retention tier and
+// prunes that tier afterwards
+func (bm *BackupManager) createBackup(tier BackupTier) (string, error) {
 	if !bm.enabled {
 		return "", fmt.Errorf("backup system is disabled")
 	}
 
-	timestamp := time.Now().Format("20060102_150405")
-	backupPath := filepath.Join(bm.backupDir, fmt.Sprintf("phl-memory-backup-%s.bak", timestamp))
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	// Milliseconds keep backups taken in the same second apart
+	timestamp := time.Now().Format("20060102_150405.000")
+	backupPath := filepath.Join(bm.backupDir, backupFileName(tier, timestamp))
 
-	// Use BadgerDB's native backup
-	file, err := os.Create(backupPath)
+	// Continue the tier's chain with a delta unless it is due a fresh full backup
+	since, parent := uint64(0), ""
+	if bm.incremental {
+		if last, ok := bm.latestBackup(tier); ok {
+			chain, manifest, err := bm.backupChain(last.Path)
+			if err == nil && len(chain) < bm.chainLength {
+				since = manifest.Version
+				parent = filepath.Base(last.Path)
+			}
+		}
+	}
+
+	// Stream the engine in Badger's backup format. Never overwrite an existing
+	// backup, since a later incremental may depend on it.
+	file, err := os.OpenFile(backupPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
 		return "", fmt.Errorf("failed to create backup file: %w", err)
 	}
-	defer file.Close()
 
-	_, err = bm.storage.db.Backup(file, 0)
+	// Compress and encrypt the stream on its way to disk, hashing the result
+	hash := sha256.New()
+	archive, err := newArchiveWriter(io.MultiWriter(file, hash), bm.compression, bm.key)
+	if err != nil {
+		file.Close()
+		os.Remove(backupPath)
+		return "", err
+	}
+
+	version, counts, err := bm.storage.BackupTo(archive, since)
+	if err == nil {
+		err = archive.Close()
+	}
+	file.Close()
 	if err != nil {
+		os.Remove(backupPath)
 		return "", fmt.Errorf("failed to create backup: %w", err)
 	}
 
+	// An empty delta writes nothing, so carry the chain's version forward
+	if version < since {
+		version = since
+	}
+
+	// Record what the backup holds so a restore can be verified
+	manifest := newManifest(version, since, parent, counts)
+	manifest.Compression = bm.compression
+	manifest.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if bm.key != nil {
+		manifest.Encrypted = true
+		manifest.KeyID = keyFingerprint(bm.key)
+	}
+
+	// Read the archive back to count its entries, which also proves it decodes
+	if manifest.Entries, err = bm.countEntries(backupPath, manifest); err != nil {
+		os.Remove(backupPath)
+		return "", fmt.Errorf("failed to read back backup: %w", err)
+	}
+	if err := writeManifest(backupPath, manifest); err != nil {
+		return "", err
+	}
+
 	// Rotate old backups
-	bm.rotateBackups()
+	bm.rotateBackups(tier)
 
 	return backupPath, nil
 }
 
-// RestoreBackup restores memory from a backup file
+// RestoreBackup restores memory from a backup file while the system keeps
+// running. Incremental backups are replayed on top of the rest of their chain,
+// so restoring any backup in a chain recovers the state at the time it was
+// taken. The result is loaded into a fresh database directory and its key
+// counts are checked against the backup's manifest; only then is it swapped
+// in under the live storage, with reads and writes paused for the swap. The
+// replaced database is kept so RollbackRestore can undo the restore.
 func (bm *BackupManager) RestoreBackup(backupPath string) error {
 	if !bm.enabled {
 		return fmt.Errorf("backup system is disabled")
 	}
 
-	// Close current database
-	if err := bm.storage.Close(); err != nil {
-		return fmt.Errorf("failed to close current database: %w", err)
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	chain, manifest, err := bm.backupChain(backupPath)
+	if err != nil {
+		return fmt.Errorf("cannot verify backup %s: %w", backupPath, err)
+	}
+
+	// Check every archive in the chain before touching anything
+	for _, path := range chain {
+		if err := bm.verifyArchive(path); err != nil {
+			return fmt.Errorf("backup %s failed verification: %w", filepath.Base(path), err)
+		}
+	}
+
+	restoreDir := fmt.Sprintf("%s.restore-%s", bm.storage.Dir(), time.Now().Format("20060102_150405.000000000"))
+	if err := bm.loadBackupChain(chain, restoreDir, manifest); err != nil {
+		os.RemoveAll(restoreDir)
+		return err
+	}
+
+	rollbackDir, err := bm.storage.Swap(restoreDir)
+	if err != nil {
+		os.RemoveAll(restoreDir)
+		return fmt.Errorf("failed to swap in restored database: %w", err)
+	}
+	bm.rollbackDir = rollbackDir
+
+	return nil
+}
+
+// RestoreToTime restores the newest backup taken at or before the given time
+func (bm *BackupManager) RestoreToTime(at time.Time) (string, error) {
+	backups, err := bm.ListBackups()
+	if err != nil {
+		return "", err
+	}
+
+	var chosen string
+	var chosenAt time.Time
+	for _, backup := range backups {
+		manifest, err := readManifest(backup.Path)
+		if err != nil || manifest.CreatedAt.After(at) {
+			continue
+		}
+		if chosen == "" || manifest.CreatedAt.After(chosenAt) {
+			chosen, chosenAt = backup.Path, manifest.CreatedAt
+		}
+	}
+	if chosen == "" {
+		return "", fmt.Errorf("no backup found at or before %s", at.Format("2006-01-02 15:04:05"))
+	}
+
+	return chosen, bm.RestoreBackup(chosen)
+}
+
+// RollbackRestore swaps the database replaced by the last restore back in.
+// The restored database is kept in turn, so a rollback can itself be undone.
+func (bm *BackupManager) RollbackRestore() error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	if bm.rollbackDir == "" {
+		return fmt.Errorf("no restore to roll back")
+	}
+
+	previousDir, err := bm.storage.Swap(bm.rollbackDir)
+	if err != nil {
+		return fmt.Errorf("failed to roll back restore: %w", err)
+	}
+	bm.rollbackDir = previousDir
+
+	return nil
+}
+
+// RollbackDir returns the database directory kept from the last restore, if any
+func (bm *BackupManager) RollbackDir() string {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	return bm.rollbackDir
+}
+
+// backupChain resolves the files needed to restore a backup, oldest first,
+// starting from the full backup its chain is built on. It also returns the
+// manifest of the requested backup.
+func (bm *BackupManager) backupChain(backupPath string) ([]string, BackupManifest, error) {
+	manifest, err := readManifest(backupPath)
+	if err != nil {
+		return nil, manifest, err
+	}
+
+	chain := []string{backupPath}
+	current := manifest
+	for current.Parent != "" {
+		parentPath := filepath.Join(filepath.Dir(backupPath), current.Parent)
+		for _, seen := range chain {
+			if seen == parentPath {
+				return nil, manifest, fmt.Errorf("backup chain loops at %s", current.Parent)
+			}
+		}
+
+		current, err = readManifest(parentPath)
+		if err != nil {
+			return nil, manifest, fmt.Errorf("backup chain broken at %s: %w", filepath.Base(parentPath), err)
+		}
+		chain = append([]string{parentPath}, chain...)
+	}
+
+	return chain, manifest, nil
+}
+
+// latestBackup returns the newest backup of a tier that has a manifest
+func (bm *BackupManager) latestBackup(tier BackupTier) (BackupInfo, bool) {
+	backups, err := bm.ListBackups()
+	if err != nil {
+		return BackupInfo{}, false
+	}
+
+	for i := len(backups) - 1; i >= 0; i-- {
+		if backups[i].Tier != tier {
+			continue
+		}
+		if _, err := readManifest(backups[i].Path); err == nil {
+			return backups[i], true
+		}
+	}
+	return BackupInfo{}, false
+}
+
+// loadBackupChain replays a chain of backup files into a new database at dir
+// and verifies the result against the manifest of the last one
+func (bm *BackupManager) loadBackupChain(chain []string, dir string, manifest BackupManifest) error {
+	engine, err := bm.storage.open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to create restore database: %w", err)
+	}
+	defer engine.Close()
+
+	streamer, err := asStreamer(engine)
+	if err != nil {
+		return err
+	}
+	for _, backupPath := range chain {
+		if err := bm.loadBackupFile(streamer, backupPath); err != nil {
+			return err
+		}
+	}
+
+	counts, err := countLayerKeys(engine)
+	if err != nil {
+		return err
+	}
+	if err := verifyCounts(manifest, counts); err != nil {
+		return fmt.Errorf("restore verification failed: %w", err)
 	}
 
-	// Open backup file
+	return nil
+}
+
+// loadBackupFile loads a single backup archive into streamer
+func (bm *BackupManager) loadBackupFile(streamer store.Streamer, backupPath string) error {
+	manifest, err := readManifest(backupPath)
+	if err != nil {
+		return err
+	}
+
+	stream, err := bm.openBackup(backupPath, manifest)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if err := streamer.Load(stream); err != nil {
+		return fmt.Errorf("failed to load backup %s: %w", filepath.Base(backupPath), err)
+	}
+	return nil
+}
+
+// openBackup returns the Badger backup stream held in a backup archive
+func (bm *BackupManager) openBackup(backupPath string, manifest BackupManifest) (io.ReadCloser, error) {
 	file, err := os.Open(backupPath)
 	if err != nil {
-		return fmt.Errorf("failed to open backup file: %w", err)
+		return nil, fmt.Errorf("failed to open backup file: %w", err)
+	}
+
+	stream, err := openArchive(file, manifest, bm.key)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &backupStream{ReadCloser: stream, file: file}, nil
+}
+
+// backupStream closes the archive file along with the decoded stream
+type backupStream struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (b *backupStream) Close() error {
+	b.ReadCloser.Close()
+	return b.file.Close()
+}
+
+// countEntries counts the key versions held in a backup archive
+func (bm *BackupManager) countEntries(backupPath string, manifest BackupManifest) (int, error) {
+	stream, err := bm.openBackup(backupPath, manifest)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+
+	entries := 0
+	err = scanBackup(stream, func(kv *pb.KV) error {
+		entries++
+		return nil
+	})
+	return entries, err
+}
+
+// VerifyBackup checks that a backup can be restored: its checksum matches the
+// manifest, it decrypts and decompresses with the configured key, it holds the
+// number of entries the manifest records, and every backup it builds on is
+// present and intact.
+func (bm *BackupManager) VerifyBackup(backupPath string) error {
+	chain, _, err := bm.backupChain(backupPath)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range chain {
+		if err := bm.verifyArchive(path); err != nil {
+			if path != backupPath {
+				return fmt.Errorf("parent %s: %w", filepath.Base(path), err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyArchive checks a single backup archive against its manifest
+func (bm *BackupManager) verifyArchive(backupPath string) error {
+	manifest, err := readManifest(backupPath)
+	if err != nil {
+		return err
+	}
+
+	// Backups from before archives existed carry no checksum
+	if manifest.SHA256 != "" {
+		sum, err := fileChecksum(backupPath)
+		if err != nil {
+			return err
+		}
+		if sum != manifest.SHA256 {
+			return fmt.Errorf("checksum mismatch: manifest has %s, file has %s", manifest.SHA256, sum)
+		}
+	}
+
+	entries, err := bm.countEntries(backupPath, manifest)
+	if err != nil {
+		return err
+	}
+	if entries != manifest.Entries {
+		return fmt.Errorf("backup holds %d entries, manifest expects %d", entries, manifest.Entries)
 	}
-	defer file.Close()
+	return nil
+}
 
-	// Note: Full restore requires closing and reopening the database
-	// This is a simplified version - full restore should be done
-	// when the system is not running
-	return fmt.Errorf("restore requires system shutdown - use backup file manually")
+// BackupDir returns the directory backups are written to
+func (bm *BackupManager) BackupDir() string {
+	return bm.backupDir
 }
 
 // ListBackups returns a list of available backups
@@ -113,41 +555,73 @@ func (bm *BackupManager) ListBackups() ([]BackupInfo, error) {
 				continue
 			}
 
-			backups = append(backups, BackupInfo{
-				Path:     filepath.Join(bm.backupDir, file.Name()),
-				Size:     info.Size(),
-				Created:  info.ModTime(),
-			})
+			backup := BackupInfo{
+				Path:    filepath.Join(bm.backupDir, file.Name()),
+				Size:    info.Size(),
+				Created: info.ModTime(),
+				Tier:    tierFromFileName(file.Name()),
+			}
+			if manifest, err := readManifest(backup.Path); err == nil {
+				backup.Incremental = manifest.Incremental()
+			}
+			backups = append(backups, backup)
 		}
 	}
 
 	return backups, nil
 }
 
-// rotateBackups removes old backups beyond maxBackups limit
-func (bm *BackupManager) rotateBackups() {
+// rotateBackups removes the oldest backups of a tier beyond that tier's keep
+// count. Manual backups are bounded by maxBackups; each scheduled tier keeps
+// its own count so daily churn never pushes out weekly or monthly backups.
+func (bm *BackupManager) rotateBackups(tier BackupTier) {
 	backups, err := bm.ListBackups()
 	if err != nil {
 		return
 	}
 
-	if len(backups) <= bm.maxBackups {
+	// File names sort by timestamp, so the oldest come first
+	var tierBackups []BackupInfo
+	for _, backup := range backups {
+		if backup.Tier == tier {
+			tierBackups = append(tierBackups, backup)
+		}
+	}
+
+	keep := bm.keep[tier]
+	if len(tierBackups) <= keep {
 		return
 	}
 
-	// Sort by creation time (oldest first)
-	// Remove oldest backups
-	toRemove := len(backups) - bm.maxBackups
-	for i := 0; i < toRemove; i++ {
-		os.Remove(backups[i].Path)
+	// Never delete a backup that a kept incremental still builds on
+	toRemove := len(tierBackups) - keep
+	needed := make(map[string]bool)
+	for _, backup := range tierBackups[toRemove:] {
+		chain, _, err := bm.backupChain(backup.Path)
+		if err != nil {
+			continue
+		}
+		for _, path := range chain {
+			needed[path] = true
+		}
+	}
+
+	for _, backup := range tierBackups[:toRemove] {
+		if needed[backup.Path] {
+			continue
+		}
+		os.Remove(backup.Path)
+		os.Remove(manifestPath(backup.Path))
 	}
 }
 
 // BackupInfo contains information about a backup
 type BackupInfo struct {
-	Path    string
-	Size    int64
-	Created time.Time
+	Path        string
+	Size        int64
+	Created     time.Time
+	Tier        BackupTier
+	Incremental bool
 }
 
 // GetBackupStats returns statistics about backups
@@ -173,23 +647,35 @@ func (bm *BackupManager) GetBackupStats() BackupStats {
 		}
 	}
 
-	return BackupStats{
-		TotalBackups: len(backups),
-		TotalSize:    totalSize,
-		OldestBackup: oldest,
-		NewestBackup: newest,
-		MaxBackups:   bm.maxBackups,
-		Enabled:      bm.enabled,
+	stats := BackupStats{
+		TotalBackups:     len(backups),
+		TotalSize:        totalSize,
+		OldestBackup:     oldest,
+		NewestBackup:     newest,
+		MaxBackups:       bm.maxBackups,
+		Enabled:          bm.enabled,
+		SchedulerRunning: bm.SchedulerRunning(),
 	}
+
+	// Next run times are only reported for tiers that are scheduled
+	next := bm.nextRuns(backups)
+	stats.NextDaily = next[BackupTierDaily]
+	stats.NextWeekly = next[BackupTierWeekly]
+	stats.NextMonthly = next[BackupTierMonthly]
+
+	return stats
 }
 
 // BackupStats contains backup statistics
 type BackupStats struct {
-	TotalBackups int
-	TotalSize    int64
-	OldestBackup time.Time
-	NewestBackup time.Time
-	MaxBackups   int
-	Enabled      bool
+	TotalBackups     int
+	TotalSize        int64
+	OldestBackup     time.Time
+	NewestBackup     time.Time
+	MaxBackups       int
+	Enabled          bool
+	SchedulerRunning bool
+	NextDaily        time.Time
+	NextWeekly       time.Time
+	NextMonthly      time.Time
 }
-
@@ -0,0 +1,384 @@
+package memory
+
+import (
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression algorithms for backup archives
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// archiveChunkSize is the plaintext size of each encrypted chunk
+const archiveChunkSize = 64 * 1024
+
+// archiveNoncePrefixSize is the random part of each chunk nonce; the rest is
+// the chunk counter
+const archiveNoncePrefixSize = 8
+
+// backupKeySize is the AES-256 key length
+const backupKeySize = 32
+
+// LoadBackupKey reads the backup encryption key from MEMORY_BACKUP_KEY, or from
+// the file named by MEMORY_BACKUP_KEY_FILE. The key is 32 bytes given as hex or
+// base64; a key file may also hold the raw bytes. No key means backups are
+// written unencrypted.
+func LoadBackupKey() ([]byte, error) {
+	if encoded := os.Getenv("MEMORY_BACKUP_KEY"); encoded != "" {
+		key, err := parseBackupKey([]byte(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid MEMORY_BACKUP_KEY: %w", err)
+		}
+		return key, nil
+	}
+
+	keyFile := os.Getenv("MEMORY_BACKUP_KEY_FILE")
+	if keyFile == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup key file: %w", err)
+	}
+	if len(data) == backupKeySize {
+		return data, nil
+	}
+	key, err := parseBackupKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup key file %s: %w", keyFile, err)
+	}
+	return key, nil
+}
+
+// parseBackupKey decodes a hex or base64 encoded key
+func parseBackupKey(encoded []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(encoded))
+
+	if key, err := hex.DecodeString(text); err == nil && len(key) == backupKeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == backupKeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("expected %d bytes encoded as hex or base64", backupKeySize)
+}
+
+// keyFingerprint identifies a key in manifests without revealing it
+func keyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// badgerVersion reports the Badger module version this binary was built with
+func badgerVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/dgraph-io/badger/v3" {
+				return dep.Version
+			}
+		}
+	}
+	return "v3"
+}
+
+// validCompression reports whether an archive can be written with the algorithm
+func validCompression(compression string) bool {
+	switch compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		return true
+	}
+	return false
+}
+
+// newArchiveWriter wraps w so that everything written is compressed and, when
+// a key is given, encrypted. Close flushes the archive but leaves w open.
+func newArchiveWriter(w io.Writer, compression string, key []byte) (io.WriteCloser, error) {
+	var closers []io.Closer
+
+	if key != nil {
+		sealer, err := newSealWriter(w, key)
+		if err != nil {
+			return nil, err
+		}
+		w = sealer
+		closers = append(closers, sealer)
+	}
+
+	switch compression {
+	case CompressionNone, "":
+	case CompressionGzip:
+		gz := gzip.NewWriter(w)
+		w = gz
+		closers = append(closers, gz)
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+		w = zw
+		closers = append(closers, zw)
+	default:
+		return nil, fmt.Errorf("unknown compression %q", compression)
+	}
+
+	return &archiveWriter{Writer: w, closers: closers}, nil
+}
+
+// archiveWriter closes its layers outermost first
+type archiveWriter struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (a *archiveWriter) Close() error {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		if err := a.closers[i].Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openArchive returns the Badger backup stream held in an archive described by
+// manifest. Backups written before archives existed have no compression and
+// are returned as they are.
+func openArchive(r io.Reader, manifest BackupManifest, key []byte) (io.ReadCloser, error) {
+	if manifest.Encrypted {
+		if key == nil {
+			return nil, fmt.Errorf("backup is encrypted but no backup key is configured")
+		}
+		if manifest.KeyID != "" && manifest.KeyID != keyFingerprint(key) {
+			return nil, fmt.Errorf("backup was encrypted with key %s, configured key is %s", manifest.KeyID, keyFingerprint(key))
+		}
+		opener, err := newOpenReader(r, key)
+		if err != nil {
+			return nil, err
+		}
+		r = opener
+	}
+
+	switch manifest.Compression {
+	case CompressionNone, "":
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		return gz, nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zstd stream: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unknown compression %q", manifest.Compression)
+	}
+}
+
+// sealWriter encrypts a stream as a sequence of AES-GCM chunks. The file starts
+// with a random nonce prefix; each chunk is stored as its ciphertext length and
+// ciphertext, sealed with the prefix and chunk counter as nonce. The last chunk
+// is marked in its additional data so truncation is detected.
+type sealWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return aead, nil
+}
+
+func newSealWriter(w io.Writer, key []byte) (*sealWriter, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, archiveNoncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, fmt.Errorf("failed to write archive header: %w", err)
+	}
+
+	return &sealWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, archiveChunkSize)}, nil
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// Only seal a full chunk once more data arrives, so Close always
+		// has a final chunk to mark
+		if len(s.buf) == archiveChunkSize {
+			if err := s.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(s.buf[len(s.buf):archiveChunkSize], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (s *sealWriter) Close() error {
+	return s.seal(true)
+}
+
+func (s *sealWriter) seal(final bool) error {
+	if s.counter == ^uint32(0) {
+		return fmt.Errorf("archive too large to encrypt")
+	}
+
+	sealed := s.aead.Seal(nil, chunkNonce(s.prefix, s.counter), s.buf, chunkAdditionalData(final))
+	s.counter++
+	s.buf = s.buf[:0]
+
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := s.w.Write(size[:]); err != nil {
+		return fmt.Errorf("failed to write encrypted chunk: %w", err)
+	}
+	if _, err := s.w.Write(sealed); err != nil {
+		return fmt.Errorf("failed to write encrypted chunk: %w", err)
+	}
+	return nil
+}
+
+// openReader decrypts a stream written by sealWriter
+type openReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	final   bool
+}
+
+func newOpenReader(r io.Reader, key []byte) (*openReader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, archiveNoncePrefixSize)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, fmt.Errorf("failed to read archive header: %w", err)
+	}
+	return &openReader{r: r, aead: aead, prefix: prefix}, nil
+}
+
+func (o *openReader) Read(p []byte) (int, error) {
+	for len(o.buf) == 0 {
+		if o.final {
+			return 0, io.EOF
+		}
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, o.buf)
+	o.buf = o.buf[n:]
+	return n, nil
+}
+
+func (o *openReader) open() error {
+	var size [4]byte
+	if _, err := io.ReadFull(o.r, size[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("archive is truncated")
+		}
+		return fmt.Errorf("failed to read encrypted chunk: %w", err)
+	}
+
+	length := binary.LittleEndian.Uint32(size[:])
+	if length > archiveChunkSize+uint32(o.aead.Overhead()) {
+		return fmt.Errorf("encrypted chunk of %d bytes is too large", length)
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(o.r, sealed); err != nil {
+		return fmt.Errorf("failed to read encrypted chunk: %w", err)
+	}
+
+	nonce := chunkNonce(o.prefix, o.counter)
+	plain, err := o.aead.Open(nil, nonce, sealed, chunkAdditionalData(false))
+	if err != nil {
+		plain, err = o.aead.Open(nil, nonce, sealed, chunkAdditionalData(true))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk %d: wrong key or corrupted archive", o.counter)
+		}
+		o.final = true
+
+		var extra [1]byte
+		if n, _ := o.r.Read(extra[:]); n > 0 {
+			return fmt.Errorf("unexpected data after final chunk")
+		}
+	}
+
+	o.counter++
+	o.buf = plain
+	return nil
+}
+
+func chunkNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, archiveNoncePrefixSize+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[archiveNoncePrefixSize:], counter)
+	return nonce
+}
+
+func chunkAdditionalData(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// fileChecksum returns the hex SHA-256 of a file
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash backup file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BackupTier identifies the retention tier a backup belongs to
+type BackupTier string
+
+const (
+	BackupTierManual  BackupTier = "manual"
+	BackupTierDaily   BackupTier = "daily"
+	BackupTierWeekly  BackupTier = "weekly"
+	BackupTierMonthly BackupTier = "monthly"
+)
+
+// scheduledTiers lists the tiers the scheduler can run, in the order they are taken
+var scheduledTiers = []BackupTier{BackupTierMonthly, BackupTierWeekly, BackupTierDaily}
+
+// backupRetryDelay is how long the scheduler waits before retrying a failed backup
+const backupRetryDelay = 5 * time.Minute
+
+// backupFileName builds the file name for a backup of a tier. Manual backups
+// keep the historical "phl-memory-backup-" name.
+func backupFileName(tier BackupTier, timestamp string) string {
+	label := string(tier)
+	if tier == BackupTierManual {
+		label = "backup"
+	}
+	return fmt.Sprintf("phl-memory-%s-%s.bak", label, timestamp)
+}
+
+// tierFromFileName recovers the tier from a backup file name
+func tierFromFileName(name string) BackupTier {
+	for _, tier := range scheduledTiers {
+		if strings.HasPrefix(name, fmt.Sprintf("phl-memory-%s-", tier)) {
+			return tier
+		}
+	}
+	return BackupTierManual
+}
+
+// nextAfter returns when the next backup of a tier is due after last
+func nextAfter(tier BackupTier, last time.Time) time.Time {
+	switch tier {
+	case BackupTierDaily:
+		return last.AddDate(0, 0, 1)
+	case BackupTierWeekly:
+		return last.AddDate(0, 0, 7)
+	case BackupTierMonthly:
+		return last.AddDate(0, 1, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+// nextRuns computes when each scheduled tier is next due, based on the newest
+// existing backup of the tier. A tier without backups is due immediately.
+func (bm *BackupManager) nextRuns(backups []BackupInfo) map[BackupTier]time.Time {
+	newest := make(map[BackupTier]time.Time)
+	for _, backup := range backups {
+		if backup.Created.After(newest[backup.Tier]) {
+			newest[backup.Tier] = backup.Created
+		}
+	}
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	now := time.Now()
+	next := make(map[BackupTier]time.Time)
+	for _, tier := range scheduledTiers {
+		if !bm.schedule[tier] {
+			continue
+		}
+
+		due := now
+		if last, ok := newest[tier]; ok {
+			due = nextAfter(tier, last)
+		}
+
+		// Back off after a failed attempt instead of retrying in a tight loop
+		if attempt, ok := bm.lastAttempt[tier]; ok && attempt.After(newest[tier]) {
+			if retry := attempt.Add(backupRetryDelay); retry.After(due) {
+				due = retry
+			}
+		}
+		next[tier] = due
+	}
+	return next
+}
+
+// StartScheduler starts the goroutine that takes daily, weekly and monthly
+// backups according to the configured schedule. It does nothing if backups
+// are disabled, no tier is scheduled, or the scheduler is already running.
+func (bm *BackupManager) StartScheduler() {
+	if !bm.enabled {
+		return
+	}
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	if bm.stop != nil {
+		return
+	}
+
+	scheduled := false
+	for _, tier := range scheduledTiers {
+		scheduled = scheduled || bm.schedule[tier]
+	}
+	if !scheduled {
+		return
+	}
+
+	bm.stop = make(chan struct{})
+	bm.done = make(chan struct{})
+	go bm.runScheduler(bm.stop, bm.done)
+}
+
+// StopScheduler stops the backup scheduler and waits for it to exit
+func (bm *BackupManager) StopScheduler() {
+	bm.mu.Lock()
+	stop, done := bm.stop, bm.done
+	bm.stop, bm.done = nil, nil
+	bm.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// SchedulerRunning reports whether the backup scheduler is active
+func (bm *BackupManager) SchedulerRunning() bool {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	return bm.stop != nil
+}
+
+// runScheduler sleeps until the earliest tier is due, backs up every tier that
+// is due, and repeats until stopped
+func (bm *BackupManager) runScheduler(stop, done chan struct{}) {
+	defer close(done)
+
+	for {
+		backups, err := bm.ListBackups()
+		if err != nil {
+			bm.log.Printf("Failed to list backups: %v", err)
+		}
+
+		next := bm.nextRuns(backups)
+		if len(next) == 0 {
+			return
+		}
+
+		var earliest time.Time
+		for _, due := range next {
+			if earliest.IsZero() || due.Before(earliest) {
+				earliest = due
+			}
+		}
+
+		timer := time.NewTimer(time.Until(earliest))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		for _, tier := range scheduledTiers {
+			due, ok := next[tier]
+			if !ok || due.After(now) {
+				continue
+			}
+
+			bm.mu.Lock()
+			bm.lastAttempt[tier] = now
+			bm.mu.Unlock()
+
+			path, err := bm.createBackup(tier)
+			if err != nil {
+				bm.log.Printf("Scheduled %s backup failed: %v", tier, err)
+				continue
+			}
+			bm.log.Printf("Scheduled %s backup written: %s", tier, path)
+		}
+	}
+}
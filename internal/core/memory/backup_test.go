@@ -0,0 +1,500 @@
+package memory
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dataDir := t.TempDir()
+	phl, err := NewPHL(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	bm := NewBackupManager(phl.GetStorage(), BackupConfig{
+		Enabled:   true,
+		BackupDir: filepath.Join(dataDir, "backups"),
+	})
+
+	if !phl.Store("eternal", "first_thought", "I am warm") {
+		t.Fatal("Failed to store eternal memory")
+	}
+	if !phl.Store("logic", "fact", "the sky is blue") {
+		t.Fatal("Failed to store logic memory")
+	}
+
+	backupPath, err := bm.CreateBackup()
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	t.Run("Test manifest written", func(t *testing.T) {
+		manifest, err := readManifest(backupPath)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		if manifest.TotalKeys != 2 {
+			t.Errorf("Expected 2 keys in manifest, got %d", manifest.TotalKeys)
+		}
+		if manifest.Layers["eternal"] != 1 || manifest.Layers["logic"] != 1 {
+			t.Errorf("Unexpected layer counts: %v", manifest.Layers)
+		}
+	})
+
+	t.Run("Test back to back backups", func(t *testing.T) {
+		again, err := bm.CreateBackup()
+		if err != nil {
+			t.Fatalf("Failed to take a second backup at once: %v", err)
+		}
+		if again == backupPath {
+			t.Errorf("Expected a new backup file, got %s twice", again)
+		}
+	})
+
+	// Written after the backup, so a restore should drop it
+	if !phl.Store("logic", "later_fact", "written after backup") {
+		t.Fatal("Failed to store later memory")
+	}
+
+	t.Run("Test online restore", func(t *testing.T) {
+		if err := bm.RestoreBackup(backupPath); err != nil {
+			t.Fatalf("RestoreBackup failed: %v", err)
+		}
+
+		if _, exists := phl.Retrieve("logic", "later_fact"); exists {
+			t.Error("Memory written after the backup survived the restore")
+		}
+		if _, exists := phl.Retrieve("eternal", "first_thought"); !exists {
+			t.Error("Backed up memory missing after restore")
+		}
+		if _, err := os.Stat(bm.RollbackDir()); err != nil {
+			t.Errorf("Rollback directory not kept: %v", err)
+		}
+
+		// The lattice must keep working on the swapped database
+		if !phl.Store("sensory", "after_restore", "still alive") {
+			t.Error("Failed to store after restore")
+		}
+	})
+
+	t.Run("Test rollback", func(t *testing.T) {
+		if err := bm.RollbackRestore(); err != nil {
+			t.Fatalf("RollbackRestore failed: %v", err)
+		}
+		if _, exists := phl.Retrieve("logic", "later_fact"); !exists {
+			t.Error("Memory from before the restore missing after rollback")
+		}
+	})
+
+	t.Run("Test manifest mismatch rejected", func(t *testing.T) {
+		manifest, err := readManifest(backupPath)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		manifest.Layers["logic"] = 5
+		if err := writeManifest(backupPath, manifest); err != nil {
+			t.Fatalf("Failed to rewrite manifest: %v", err)
+		}
+
+		if err := bm.RestoreBackup(backupPath); err == nil {
+			t.Error("Expected verification error for mismatched manifest")
+		}
+		if _, exists := phl.Retrieve("logic", "later_fact"); !exists {
+			t.Error("Live memory changed by a rejected restore")
+		}
+	})
+}
+
+func TestBackupSchedule(t *testing.T) {
+	dataDir := t.TempDir()
+	phl, err := NewPHL(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	backupDir := filepath.Join(dataDir, "backups")
+	bm := NewBackupManager(phl.GetStorage(), BackupConfig{
+		Enabled:       true,
+		BackupDir:     backupDir,
+		ScheduleDaily: true,
+		KeepDaily:     2,
+		KeepWeekly:    1,
+	})
+
+	t.Run("Test scheduler takes due backup", func(t *testing.T) {
+		bm.StartScheduler()
+		defer bm.StopScheduler()
+
+		if !bm.SchedulerRunning() {
+			t.Fatal("Scheduler not running")
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		var daily []BackupInfo
+		for time.Now().Before(deadline) {
+			backups, _ := bm.ListBackups()
+			daily = daily[:0]
+			for _, backup := range backups {
+				if backup.Tier == BackupTierDaily {
+					daily = append(daily, backup)
+				}
+			}
+			if len(daily) > 0 {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if len(daily) != 1 {
+			t.Fatalf("Expected 1 daily backup, got %d", len(daily))
+		}
+
+		stats := bm.GetBackupStats()
+		if until := time.Until(stats.NextDaily); until < 23*time.Hour || until > 25*time.Hour {
+			t.Errorf("Expected next daily run about a day away, got %v", stats.NextDaily)
+		}
+		if !stats.NextWeekly.IsZero() || !stats.NextMonthly.IsZero() {
+			t.Error("Unscheduled tiers reported a next run time")
+		}
+	})
+
+	if bm.SchedulerRunning() {
+		t.Error("Scheduler still running after stop")
+	}
+
+	t.Run("Test per-tier retention", func(t *testing.T) {
+		for _, name := range []string{
+			"phl-memory-daily-20240101_000000.bak",
+			"phl-memory-daily-20240102_000000.bak",
+			"phl-memory-daily-20240103_000000.bak",
+			"phl-memory-weekly-20240101_000000.bak",
+			"phl-memory-weekly-20240108_000000.bak",
+		} {
+			if err := os.WriteFile(filepath.Join(backupDir, name), nil, 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+
+		bm.rotateBackups(BackupTierDaily)
+
+		backups, err := bm.ListBackups()
+		if err != nil {
+			t.Fatalf("ListBackups failed: %v", err)
+		}
+		counts := make(map[BackupTier]int)
+		for _, backup := range backups {
+			counts[backup.Tier]++
+		}
+		if counts[BackupTierDaily] != 2 {
+			t.Errorf("Expected 2 daily backups after rotation, got %d", counts[BackupTierDaily])
+		}
+		if counts[BackupTierWeekly] != 2 {
+			t.Errorf("Daily rotation touched weekly backups: %d left", counts[BackupTierWeekly])
+		}
+	})
+}
+
+func TestIncrementalBackup(t *testing.T) {
+	dataDir := t.TempDir()
+	phl, err := NewPHL(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	bm := NewBackupManager(phl.GetStorage(), BackupConfig{
+		Enabled:     true,
+		BackupDir:   filepath.Join(dataDir, "backups"),
+		Incremental: true,
+		ChainLength: 3,
+	})
+
+	if !phl.Store("logic", "fact", "the sky is blue") {
+		t.Fatal("Failed to store logic memory")
+	}
+	if !phl.Store("dream", "vision", "a burning feather") {
+		t.Fatal("Failed to store dream memory")
+	}
+
+	fullPath, err := bm.CreateBackup()
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	if !phl.Store("logic", "second_fact", "water is wet") {
+		t.Fatal("Failed to store logic memory")
+	}
+	if !phl.Cleanup("dream") {
+		t.Fatal("Failed to clean up dream layer")
+	}
+
+	deltaPath, err := bm.CreateBackup()
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	t.Run("Test delta chained to full backup", func(t *testing.T) {
+		full, err := readManifest(fullPath)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		delta, err := readManifest(deltaPath)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		if full.Incremental() {
+			t.Error("First backup should be a full backup")
+		}
+		if delta.Parent != filepath.Base(fullPath) {
+			t.Errorf("Expected parent %s, got %q", filepath.Base(fullPath), delta.Parent)
+		}
+		if delta.Since != full.Version {
+			t.Errorf("Expected since %d, got %d", full.Version, delta.Since)
+		}
+	})
+
+	if !phl.Store("logic", "later_fact", "written after every backup") {
+		t.Fatal("Failed to store later memory")
+	}
+
+	t.Run("Test restore replays chain", func(t *testing.T) {
+		if err := bm.RestoreBackup(deltaPath); err != nil {
+			t.Fatalf("RestoreBackup failed: %v", err)
+		}
+		if _, exists := phl.Retrieve("logic", "second_fact"); !exists {
+			t.Error("Memory from the delta missing after restore")
+		}
+		if _, exists := phl.Retrieve("dream", "vision"); exists {
+			t.Error("Deleted memory came back after restore")
+		}
+		if _, exists := phl.Retrieve("logic", "later_fact"); exists {
+			t.Error("Memory written after the backup survived the restore")
+		}
+	})
+
+	t.Run("Test restore to point in time", func(t *testing.T) {
+		full, err := readManifest(fullPath)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+
+		restored, err := bm.RestoreToTime(full.CreatedAt)
+		if err != nil {
+			t.Fatalf("RestoreToTime failed: %v", err)
+		}
+		if restored != fullPath {
+			t.Errorf("Expected %s to be restored, got %s", fullPath, restored)
+		}
+		if _, exists := phl.Retrieve("dream", "vision"); !exists {
+			t.Error("Memory from the full backup missing after restore")
+		}
+		if _, exists := phl.Retrieve("logic", "second_fact"); exists {
+			t.Error("Memory from the delta present before its backup time")
+		}
+
+		if _, err := bm.RestoreToTime(full.CreatedAt.Add(-time.Hour)); err == nil {
+			t.Error("Expected error restoring before the first backup")
+		}
+	})
+
+	t.Run("Test broken chain rejected", func(t *testing.T) {
+		if err := os.Remove(manifestPath(fullPath)); err != nil {
+			t.Fatalf("Failed to remove manifest: %v", err)
+		}
+		if err := bm.RestoreBackup(deltaPath); err == nil {
+			t.Error("Expected error restoring a delta without its full backup")
+		}
+	})
+}
+
+func TestSQLiteBackup(t *testing.T) {
+	t.Setenv("PHL_STORAGE", "sqlite")
+	dataDir := t.TempDir()
+	phl, err := NewPHL(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	bm := NewBackupManager(phl.GetStorage(), BackupConfig{
+		Enabled:     true,
+		BackupDir:   filepath.Join(dataDir, "backups"),
+		Incremental: true,
+		ChainLength: 3,
+	})
+
+	if !phl.Store("logic", "fact", "the sky is blue") {
+		t.Fatal("Failed to store logic memory")
+	}
+	if !phl.Store("dream", "vision", "a burning feather") {
+		t.Fatal("Failed to store dream memory")
+	}
+
+	fullPath, err := bm.CreateBackup()
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	if !phl.Store("logic", "second_fact", "water is wet") {
+		t.Fatal("Failed to store logic memory")
+	}
+	if !phl.Cleanup("dream") {
+		t.Fatal("Failed to clean up dream layer")
+	}
+
+	deltaPath, err := bm.CreateBackup()
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	t.Run("Test delta holds only the changes", func(t *testing.T) {
+		delta, err := readManifest(deltaPath)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		if delta.Parent != filepath.Base(fullPath) {
+			t.Errorf("Expected parent %s, got %q", filepath.Base(fullPath), delta.Parent)
+		}
+		// The new fact and the deleted vision
+		if delta.Entries != 2 {
+			t.Errorf("Expected 2 entries in the delta, got %d", delta.Entries)
+		}
+	})
+
+	if !phl.Store("logic", "later_fact", "written after every backup") {
+		t.Fatal("Failed to store later memory")
+	}
+
+	t.Run("Test restore replays chain", func(t *testing.T) {
+		if err := bm.RestoreBackup(deltaPath); err != nil {
+			t.Fatalf("RestoreBackup failed: %v", err)
+		}
+		if _, exists := phl.Retrieve("logic", "second_fact"); !exists {
+			t.Error("Memory from the delta missing after restore")
+		}
+		if _, exists := phl.Retrieve("dream", "vision"); exists {
+			t.Error("Deleted memory came back after restore")
+		}
+		if _, exists := phl.Retrieve("logic", "later_fact"); exists {
+			t.Error("Memory written after the backup survived the restore")
+		}
+
+		// Versions carry over, so the restored database keeps working
+		if !phl.Store("logic", "after_restore", "still alive") {
+			t.Error("Failed to store after restore")
+		}
+	})
+
+	t.Run("Test restore full backup", func(t *testing.T) {
+		if err := bm.RestoreBackup(fullPath); err != nil {
+			t.Fatalf("RestoreBackup failed: %v", err)
+		}
+		if _, exists := phl.Retrieve("dream", "vision"); !exists {
+			t.Error("Memory from the full backup missing after restore")
+		}
+		if _, exists := phl.Retrieve("logic", "second_fact"); exists {
+			t.Error("Memory from the delta present before its backup")
+		}
+	})
+}
+
+func TestBackupArchive(t *testing.T) {
+	dataDir := t.TempDir()
+	phl, err := NewPHL(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	key := bytes.Repeat([]byte{0x42}, backupKeySize)
+	bm := NewBackupManager(phl.GetStorage(), BackupConfig{
+		Enabled:       true,
+		BackupDir:     filepath.Join(dataDir, "backups"),
+		Compression:   CompressionZstd,
+		EncryptionKey: key,
+	})
+
+	secret := strings.Repeat("the first time I heard Dad laugh ", 4096)
+	if !phl.Store("eternal", "first_laugh", secret) {
+		t.Fatal("Failed to store eternal memory")
+	}
+
+	backupPath, err := bm.CreateBackup()
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	t.Run("Test archive is encrypted and compressed", func(t *testing.T) {
+		manifest, err := readManifest(backupPath)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		if !manifest.Encrypted || manifest.Compression != CompressionZstd {
+			t.Errorf("Unexpected archive format: encrypted=%v compression=%q", manifest.Encrypted, manifest.Compression)
+		}
+		if manifest.SHA256 == "" || manifest.BadgerVersion == "" {
+			t.Error("Manifest missing checksum or Badger version")
+		}
+
+		data, err := os.ReadFile(backupPath)
+		if err != nil {
+			t.Fatalf("Failed to read backup: %v", err)
+		}
+		if bytes.Contains(data, []byte("Dad laugh")) {
+			t.Error("Backup holds plaintext memory data")
+		}
+		if len(data) >= len(secret) {
+			t.Errorf("Backup of %d bytes not compressed below %d", len(data), len(secret))
+		}
+	})
+
+	t.Run("Test verify and restore", func(t *testing.T) {
+		if err := bm.VerifyBackup(backupPath); err != nil {
+			t.Fatalf("VerifyBackup failed: %v", err)
+		}
+		if !phl.Cleanup("eternal") {
+			t.Fatal("Failed to clean up eternal layer")
+		}
+		if err := bm.RestoreBackup(backupPath); err != nil {
+			t.Fatalf("RestoreBackup failed: %v", err)
+		}
+		if _, exists := phl.Retrieve("eternal", "first_laugh"); !exists {
+			t.Error("Encrypted memory missing after restore")
+		}
+	})
+
+	t.Run("Test wrong key rejected", func(t *testing.T) {
+		other := NewBackupManager(phl.GetStorage(), BackupConfig{
+			Enabled:       true,
+			BackupDir:     bm.BackupDir(),
+			EncryptionKey: bytes.Repeat([]byte{0x24}, backupKeySize),
+		})
+		if err := other.VerifyBackup(backupPath); err == nil {
+			t.Error("Expected verification to fail with the wrong key")
+		}
+	})
+
+	t.Run("Test tampering detected", func(t *testing.T) {
+		data, err := os.ReadFile(backupPath)
+		if err != nil {
+			t.Fatalf("Failed to read backup: %v", err)
+		}
+		data[len(data)/2] ^= 0xff
+		if err := os.WriteFile(backupPath, data, 0644); err != nil {
+			t.Fatalf("Failed to write backup: %v", err)
+		}
+
+		if err := bm.VerifyBackup(backupPath); err == nil {
+			t.Error("Expected verification to fail for a tampered archive")
+		}
+		if err := bm.RestoreBackup(backupPath); err == nil {
+			t.Error("Expected restore to refuse a tampered archive")
+		}
+	})
+}
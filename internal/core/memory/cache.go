@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"container/list"
+	"encoding/json"
+)
+
+// Default per-layer cache limits
+const (
+	defaultCacheMaxEntries = 1024
+	defaultCacheMaxBytes   = 4 << 20 // 4 MiB
+)
+
+// CacheConfig bounds the in-memory cache of each layer. A limit of zero or
+// less is no limit.
+type CacheConfig struct {
+	MaxEntries int
+	MaxBytes   int64 // measured as the JSON size of the cached values
+}
+
+// LoadCacheConfig reads the per-layer cache limits from the environment
+func LoadCacheConfig() CacheConfig {
+	return CacheConfig{
+		MaxEntries: getEnvInt("MEMORY_CACHE_MAX_ENTRIES", defaultCacheMaxEntries),
+		MaxBytes:   int64(getEnvInt("MEMORY_CACHE_MAX_BYTES", defaultCacheMaxBytes)),
+	}
+}
+
+// CacheStats describes the use of a layer cache
+type CacheStats struct {
+	Entries   int
+	Bytes     int64
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// HitRate returns the fraction of lookups served from the cache
+func (cs CacheStats) HitRate() float64 {
+	if cs.Hits+cs.Misses == 0 {
+		return 0
+	}
+	return float64(cs.Hits) / float64(cs.Hits+cs.Misses)
+}
+
+// add folds another cache's counters into cs
+func (cs *CacheStats) add(other CacheStats) {
+	cs.Entries += other.Entries
+	cs.Bytes += other.Bytes
+	cs.Hits += other.Hits
+	cs.Misses += other.Misses
+	cs.Evictions += other.Evictions
+}
+
+// lruCache is a size- and byte-bounded cache that evicts the least recently
+// used entry first. It isn't safe for concurrent use; each Layer guards its
+// cache with its own lock.
+type lruCache struct {
+	limits CacheConfig
+	items  map[string]*list.Element
+	order  *list.List // most recently used at the front
+	bytes  int64
+	stats  CacheStats
+}
+
+// cacheEntry is one value held by an lruCache
+type cacheEntry struct {
+	key   string
+	value any
+	size  int64
+}
+
+func newLRUCache(limits CacheConfig) *lruCache {
+	return &lruCache{
+		limits: limits,
+		items:  make(map[string]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// get returns a cached value, marking it as recently used
+func (c *lruCache) get(key string) (any, bool) {
+	elem, exists := c.items[key]
+	if !exists {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).value, true
+}
+
+// add caches a value, evicting older entries to stay within the limits. A
+// value too big to ever fit isn't cached.
+func (c *lruCache) add(key string, value any) {
+	c.remove(key)
+
+	size := valueSize(value)
+	if size < 0 || (c.limits.MaxBytes > 0 && size > c.limits.MaxBytes) {
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, value: value, size: size})
+	c.bytes += size
+	c.evict()
+}
+
+// remove drops a key from the cache
+func (c *lruCache) remove(key string) {
+	if elem, exists := c.items[key]; exists {
+		c.removeElement(elem)
+	}
+}
+
+// clear drops every entry, keeping the counters
+func (c *lruCache) clear() {
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.bytes = 0
+}
+
+// resize applies new limits, evicting entries that no longer fit
+func (c *lruCache) resize(limits CacheConfig) {
+	c.limits = limits
+	c.evict()
+}
+
+// snapshot returns the cache's counters and current size
+func (c *lruCache) snapshot() CacheStats {
+	stats := c.stats
+	stats.Entries = len(c.items)
+	stats.Bytes = c.bytes
+	return stats
+}
+
+func (c *lruCache) evict() {
+	for c.order.Len() > 0 &&
+		((c.limits.MaxEntries > 0 && c.order.Len() > c.limits.MaxEntries) ||
+			(c.limits.MaxBytes > 0 && c.bytes > c.limits.MaxBytes)) {
+		c.removeElement(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *lruCache) removeElement(elem *list.Element) {
+	entry := c.order.Remove(elem).(*cacheEntry)
+	delete(c.items, entry.key)
+	c.bytes -= entry.size
+}
+
+// valueSize estimates the memory a value takes by its JSON size, or returns
+// -1 if it can't be encoded
+func valueSize(value any) int64 {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return -1
+	}
+	return int64(len(data))
+}
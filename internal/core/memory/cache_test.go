@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLayerCache(t *testing.T) {
+	t.Run("Test entry limit evicts least recently used", func(t *testing.T) {
+		cache := newLRUCache(CacheConfig{MaxEntries: 2})
+		cache.add("a", "first")
+		cache.add("b", "second")
+		cache.get("a") // b is now the least recently used
+		cache.add("c", "third")
+
+		if _, ok := cache.get("b"); ok {
+			t.Error("Expected b to be evicted")
+		}
+		for _, key := range []string{"a", "c"} {
+			if _, ok := cache.get(key); !ok {
+				t.Errorf("Expected %s to stay cached", key)
+			}
+		}
+
+		stats := cache.snapshot()
+		if stats.Entries != 2 || stats.Evictions != 1 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		if stats.Hits != 3 || stats.Misses != 1 {
+			t.Errorf("Expected 3 hits and 1 miss, got %+v", stats)
+		}
+	})
+
+	t.Run("Test byte limit", func(t *testing.T) {
+		cache := newLRUCache(CacheConfig{MaxBytes: 64})
+		for i := 0; i < 10; i++ {
+			cache.add(fmt.Sprintf("key_%d", i), strings.Repeat("x", 20))
+		}
+		stats := cache.snapshot()
+		if stats.Bytes > 64 {
+			t.Errorf("Cache holds %d bytes, over the 64 byte limit", stats.Bytes)
+		}
+		if _, ok := cache.get("key_9"); !ok {
+			t.Error("Expected the newest entry to stay cached")
+		}
+
+		cache.add("huge", strings.Repeat("x", 100))
+		if _, ok := cache.get("huge"); ok {
+			t.Error("Value over the byte limit was cached")
+		}
+
+		cache.resize(CacheConfig{MaxBytes: 30})
+		if stats := cache.snapshot(); stats.Entries != 1 {
+			t.Errorf("Expected 1 entry after shrinking, got %d", stats.Entries)
+		}
+	})
+
+	t.Run("Test PHL cache stats", func(t *testing.T) {
+		phl, err := NewPHL(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		defer phl.Close()
+		phl.SetCacheConfig(CacheConfig{MaxEntries: 3})
+
+		for i := 0; i < 10; i++ {
+			if !phl.Store("eternal", fmt.Sprintf("dad_love_%d", i), "I love you, Dad") {
+				t.Fatalf("Failed to store memory %d", i)
+			}
+		}
+		if stats := phl.CacheStats()["eternal"]; stats.Entries != 3 || stats.Evictions != 7 {
+			t.Errorf("Expected 3 cached entries after 7 evictions, got %+v", stats)
+		}
+
+		// One hit from the cache, one miss filled from storage
+		if _, ok := phl.Retrieve("eternal", "dad_love_9"); !ok {
+			t.Fatal("Failed to retrieve cached memory")
+		}
+		if _, ok := phl.Retrieve("eternal", "dad_love_0"); !ok {
+			t.Fatal("Failed to retrieve evicted memory from storage")
+		}
+
+		stats, err := phl.Stats()
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		if stats.TotalEntries != 10 {
+			t.Errorf("Expected 10 entries, got %d", stats.TotalEntries)
+		}
+		if stats.CacheHitRate != 0.5 {
+			t.Errorf("Expected a hit rate of 0.5, got %v", stats.CacheHitRate)
+		}
+	})
+}
@@ -0,0 +1,264 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/phoenix-marie/core/internal/core/memory/v2/store"
+)
+
+// Change feed operations
+type ChangeOp string
+
+const (
+	ChangePut    ChangeOp = "put"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// ChangeEvent reports a committed write to a memory. Rewrites that only touch
+// metadata, such as a read reinforcing a decaying memory, are puts that keep
+// the memory's Meta.Version and Meta.UpdatedAt. Memories that expire are not
+// reported.
+type ChangeEvent struct {
+	Op      ChangeOp
+	Layer   string
+	Key     string
+	Value   any       // the memory's value; nil for deletes. Treat as read-only
+	Meta    *Envelope // metadata stored with the value; nil for deletes
+	Version uint64    // storage commit version, increasing with every write
+}
+
+// subscriptionBuffer is how many events a subscription holds for a slow
+// reader before it starts dropping them
+const subscriptionBuffer = 256
+
+// Subscription is a stream of changes to the memories whose layer matches a
+// pattern and whose key starts with a prefix
+type Subscription struct {
+	layerPattern string
+	keyPrefix    string
+	feed         *changeFeed
+	events       chan ChangeEvent
+
+	mu      sync.Mutex
+	closed  bool
+	dropped uint64
+}
+
+// Events returns the channel events arrive on. It is closed when the
+// subscription or the storage is closed.
+func (s *Subscription) Events() <-chan ChangeEvent {
+	return s.events
+}
+
+// Dropped returns the number of events lost because the reader fell more
+// than subscriptionBuffer events behind
+func (s *Subscription) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close stops the subscription and closes its channel
+func (s *Subscription) Close() {
+	s.feed.remove(s)
+	s.end()
+}
+
+// matches reports whether the subscription wants changes to a memory
+func (s *Subscription) matches(layer, key string) bool {
+	if !strings.HasPrefix(key, s.keyPrefix) {
+		return false
+	}
+	if s.layerPattern == "" {
+		return true
+	}
+	matched, _ := path.Match(s.layerPattern, layer)
+	return matched
+}
+
+// deliver queues an event without blocking, dropping it if the reader is
+// too far behind
+func (s *Subscription) deliver(event ChangeEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		s.dropped++
+	}
+}
+
+// end closes the event channel once
+func (s *Subscription) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
+
+// changeFeed fans the storage engine's changes out to subscriptions. The
+// engine subscription runs only while someone is subscribed.
+type changeFeed struct {
+	startMu sync.Mutex         // serializes starting and stopping the engine subscription
+	cancel  context.CancelFunc // stops the engine subscription; nil while none runs
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func newChangeFeed() *changeFeed {
+	return &changeFeed{subs: make(map[*Subscription]struct{})}
+}
+
+// start subscribes to engine. Callers hold startMu.
+func (f *changeFeed) start(engine store.StorageEngine) error {
+	subscriber, ok := engine.(store.Subscriber)
+	if !ok {
+		return fmt.Errorf("storage engine %T doesn't support subscriptions", engine)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := subscriber.Subscribe(ctx, f.publish); err != nil {
+		cancel()
+		return fmt.Errorf("failed to subscribe to storage engine: %w", err)
+	}
+	f.cancel = cancel
+	return nil
+}
+
+// stop ends the engine subscription. Callers hold startMu.
+func (f *changeFeed) stop() {
+	if f.cancel != nil {
+		f.cancel()
+		f.cancel = nil
+	}
+}
+
+// add subscribes sub, starting the engine subscription if it isn't running
+func (f *changeFeed) add(sub *Subscription, engine store.StorageEngine) error {
+	f.startMu.Lock()
+	defer f.startMu.Unlock()
+
+	if f.cancel == nil {
+		if err := f.start(engine); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subs[sub] = struct{}{}
+	return nil
+}
+
+// remove unsubscribes sub, stopping the engine subscription after the last
+func (f *changeFeed) remove(sub *Subscription) {
+	f.startMu.Lock()
+	defer f.startMu.Unlock()
+
+	f.mu.Lock()
+	delete(f.subs, sub)
+	empty := len(f.subs) == 0
+	f.mu.Unlock()
+
+	if empty {
+		f.stop()
+	}
+}
+
+// restart moves a running engine subscription to a newly opened engine
+func (f *changeFeed) restart(engine store.StorageEngine) {
+	f.startMu.Lock()
+	defer f.startMu.Unlock()
+
+	if f.cancel == nil {
+		return
+	}
+	f.stop()
+	if err := f.start(engine); err != nil {
+		log.Printf("PHL_MEMORY: Change feed stopped: %v", err)
+	}
+}
+
+// close stops the feed and closes every subscription
+func (f *changeFeed) close() {
+	f.startMu.Lock()
+	f.stop()
+	f.startMu.Unlock()
+
+	f.mu.Lock()
+	subs := f.subs
+	f.subs = make(map[*Subscription]struct{})
+	f.mu.Unlock()
+
+	for sub := range subs {
+		sub.end()
+	}
+}
+
+// publish turns engine changes into events for the matching subscriptions.
+// The system layer is internal and never reported.
+func (f *changeFeed) publish(changes []store.Change) {
+	f.mu.Lock()
+	subs := make([]*Subscription, 0, len(f.subs))
+	for sub := range f.subs {
+		subs = append(subs, sub)
+	}
+	f.mu.Unlock()
+
+	for _, change := range changes {
+		if change.Layer == systemLayer {
+			continue
+		}
+
+		event := ChangeEvent{Op: ChangeDelete, Layer: change.Layer, Key: change.Key, Version: change.Version}
+		if !change.Deleted {
+			env, err := envelopeFromValue(change.Value)
+			if err != nil {
+				continue
+			}
+			event.Op, event.Value, event.Meta = ChangePut, env.Value, env
+		}
+
+		for _, sub := range subs {
+			if sub.matches(change.Layer, change.Key) {
+				sub.deliver(event)
+			}
+		}
+	}
+}
+
+// Subscribe returns a stream of the writes and deletes of memories whose
+// layer matches layerPattern, a glob such as "emotion" or "*", and whose key
+// starts with keyPrefix. An empty pattern matches every layer. Events are
+// reported from the moment Subscribe returns; close the subscription when
+// done with it.
+func (s *Storage) Subscribe(layerPattern, keyPrefix string) (*Subscription, error) {
+	if _, err := path.Match(layerPattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid layer pattern %q: %w", layerPattern, err)
+	}
+
+	sub := &Subscription{
+		layerPattern: layerPattern,
+		keyPrefix:    keyPrefix,
+		feed:         s.feed,
+		events:       make(chan ChangeEvent, subscriptionBuffer),
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.feed.add(sub, s.engine); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangeFeed(t *testing.T) {
+	newPHL := func(t *testing.T) *PHL {
+		phl, err := NewPHL(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		t.Cleanup(func() { phl.Close() })
+		return phl
+	}
+
+	// next waits for the subscription's next event
+	next := func(t *testing.T, sub *Subscription) ChangeEvent {
+		t.Helper()
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				t.Fatal("Subscription closed early")
+			}
+			return event
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for an event")
+		}
+		return ChangeEvent{}
+	}
+
+	t.Run("Test puts and deletes", func(t *testing.T) {
+		phl := newPHL(t)
+		sub, err := phl.Subscribe("emotion", "chat_")
+		if err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+		defer sub.Close()
+
+		phl.Store("logic", "chat_1", "wrong layer")
+		phl.Store("emotion", "mood", "wrong key")
+		phl.StoreWithOptions("emotion", "chat_1", map[string]any{"input": "hi"}, StoreOptions{Source: "cli"})
+		phl.Delete("emotion", "chat_1")
+
+		put := next(t, sub)
+		if put.Op != ChangePut || put.Layer != "emotion" || put.Key != "chat_1" {
+			t.Fatalf("Unexpected put: %+v", put)
+		}
+		if put.Meta == nil || put.Meta.Source != "cli" || put.Value.(map[string]any)["input"] != "hi" {
+			t.Errorf("Expected the stored value and metadata, got %+v", put)
+		}
+		if deleted := next(t, sub); deleted.Op != ChangeDelete || deleted.Key != "chat_1" || deleted.Version <= put.Version {
+			t.Errorf("Unexpected delete: %+v", deleted)
+		}
+	})
+
+	t.Run("Test layer patterns", func(t *testing.T) {
+		phl := newPHL(t)
+		all, err := phl.Subscribe("*", "")
+		if err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+		defer all.Close()
+
+		phl.GetStorage().SetSystem("routes", map[string]any{})
+		phl.Store("dream", "flight", "soaring")
+		if event := next(t, all); event.Layer != "dream" {
+			t.Errorf("Expected the system layer to be hidden, got %+v", event)
+		}
+
+		if _, err := phl.Subscribe("nowhere", ""); err == nil {
+			t.Error("Expected an unknown layer to be rejected")
+		}
+		if _, err := phl.Subscribe("[", ""); err == nil {
+			t.Error("Expected a bad pattern to be rejected")
+		}
+	})
+
+	t.Run("Test close and slow readers", func(t *testing.T) {
+		phl := newPHL(t)
+		slow, err := phl.Subscribe("sensory", "")
+		if err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+
+		for i := 0; i < subscriptionBuffer+10; i++ {
+			phl.Store("sensory", "tick", map[string]any{"n": i})
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for slow.Dropped() < 10 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if dropped := slow.Dropped(); dropped != 10 {
+			t.Errorf("Expected 10 dropped events, got %d", dropped)
+		}
+
+		slow.Close()
+		for range slow.Events() {
+			// Drain what was buffered; the channel must then be closed
+		}
+
+		open, _ := phl.Subscribe("sensory", "")
+		phl.Close()
+		if _, ok := <-open.Events(); ok {
+			t.Error("Expected closing the lattice to end subscriptions")
+		}
+	})
+}
@@ -0,0 +1,614 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Weights of the parts of a consolidation score; they add up to 1
+const (
+	recencyWeight    = 0.3
+	accessWeight     = 0.2
+	intensityWeight  = 0.2
+	importanceWeight = 0.3
+)
+
+// accessSaturation is the read count at which the access score tops out
+const accessSaturation = 16
+
+// maxImportance is the importance at which the importance score tops out
+const maxImportance = 10
+
+// maxMergeCandidates caps how many memories of a layer are compared for
+// near-duplicates, since every pair of them is compared
+const maxMergeCandidates = 500
+
+// consolidationSource is the source recorded on memories the consolidator writes
+const consolidationSource = "consolidation"
+
+// Summarizer condenses near-duplicate memories into one text. An LLM-backed
+// summarizer can be plugged in with Consolidator.SetSummarizer; without one,
+// the consolidator writes a deterministic summary.
+type Summarizer interface {
+	Summarize(texts []string) (string, error)
+}
+
+// ConsolidationConfig controls the consolidation pass
+type ConsolidationConfig struct {
+	Enabled         bool
+	Interval        time.Duration // time between passes
+	WriteTrigger    int           // memories written since the last pass that trigger one early; zero disables
+	Layers          []string      // layers scored for promotion and merging
+	EvictLayers     []string      // layers low-value memories are evicted from
+	PromoteScore    float64       // memories scoring at least this are promoted
+	MaxPromotions   int           // most promotions per pass
+	EvictScore      float64       // memories scoring below this are evicted
+	MinEvictAge     time.Duration // memories younger than this are never evicted
+	MergeSimilarity float64       // cosine similarity at which memories merge
+	HalfLife        time.Duration // time after which the recency score halves
+}
+
+// DefaultConsolidationConfig returns the consolidation settings used when
+// the environment sets none
+func DefaultConsolidationConfig() ConsolidationConfig {
+	return ConsolidationConfig{
+		Enabled:         true,
+		Interval:        time.Hour,
+		WriteTrigger:    500,
+		Layers:          []string{"sensory", "emotion", "logic", "dream"},
+		EvictLayers:     []string{"sensory", "emotion"},
+		PromoteScore:    0.7,
+		MaxPromotions:   10,
+		EvictScore:      0.15,
+		MinEvictAge:     24 * time.Hour,
+		MergeSimilarity: 0.9,
+		HalfLife:        7 * 24 * time.Hour,
+	}
+}
+
+// LoadConsolidationConfig reads the consolidation settings from the environment
+func LoadConsolidationConfig() ConsolidationConfig {
+	config := DefaultConsolidationConfig()
+	config.Enabled = getEnvBool("MEMORY_CONSOLIDATION_ENABLED", config.Enabled)
+	config.Interval = time.Duration(getEnvInt("MEMORY_CONSOLIDATION_INTERVAL_MINUTES", int(config.Interval/time.Minute))) * time.Minute
+	config.WriteTrigger = getEnvInt("MEMORY_CONSOLIDATION_WRITE_TRIGGER", config.WriteTrigger)
+	config.PromoteScore = getEnvFloat("MEMORY_CONSOLIDATION_PROMOTE_SCORE", config.PromoteScore)
+	config.MaxPromotions = getEnvInt("MEMORY_CONSOLIDATION_MAX_PROMOTIONS", config.MaxPromotions)
+	config.EvictScore = getEnvFloat("MEMORY_CONSOLIDATION_EVICT_SCORE", config.EvictScore)
+	config.MergeSimilarity = getEnvFloat("MEMORY_CONSOLIDATION_MERGE_SIMILARITY", config.MergeSimilarity)
+	return config
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	parsed, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// ConsolidationReport describes what a consolidation pass did
+type ConsolidationReport struct {
+	Started  time.Time
+	Duration time.Duration
+	Scored   int
+	Promoted []string // "layer:key" of each memory copied to eternal
+	Merged   int      // memories folded into a near-duplicate
+	Evicted  int
+	Errors   []string
+}
+
+// ScoredMemory is a memory with the score consolidation gave it
+type ScoredMemory struct {
+	Layer     string
+	Key       string
+	Score     float64 // from 0 to 1, higher is more worth keeping
+	Accesses  int
+	Intensity float64
+	Meta      *Envelope
+}
+
+// accessLog counts the reads of each memory since the lattice opened
+type accessLog struct {
+	mu    sync.Mutex
+	reads map[string]int // "layer:key" -> reads
+}
+
+func newAccessLog() *accessLog {
+	return &accessLog{reads: make(map[string]int)}
+}
+
+func (a *accessLog) touch(layer, key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reads[layer+":"+key]++
+}
+
+func (a *accessLog) count(layer, key string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.reads[layer+":"+key]
+}
+
+func (a *accessLog) forget(layer, key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.reads, layer+":"+key)
+}
+
+func (a *accessLog) forgetLayer(layer string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id := range a.reads {
+		if strings.HasPrefix(id, layer+":") {
+			delete(a.reads, id)
+		}
+	}
+}
+
+// Consolidator periodically scores memories by recency, access frequency,
+// emotional intensity and importance. It promotes the best to the eternal
+// layer, merges near-duplicates and evicts what is left of little value.
+type Consolidator struct {
+	phl     *PHL
+	eternal *EternalMemoryManager
+	config  ConsolidationConfig
+
+	run sync.Mutex // held for the length of a pass
+
+	mu         sync.Mutex // guards the fields below
+	summarizer Summarizer
+	last       *ConsolidationReport
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewConsolidator creates a consolidator for the lattice
+func NewConsolidator(phl *PHL, config ConsolidationConfig) *Consolidator {
+	defaults := DefaultConsolidationConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.HalfLife <= 0 {
+		config.HalfLife = defaults.HalfLife
+	}
+	return &Consolidator{
+		phl:     phl,
+		eternal: NewEternalMemoryManager(phl),
+		config:  config,
+	}
+}
+
+// SetSummarizer sets what merges near-duplicate memories; nil falls back to
+// the deterministic summary
+func (c *Consolidator) SetSummarizer(summarizer Summarizer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.summarizer = summarizer
+}
+
+// LastReport returns the report of the most recent pass, if any has run
+func (c *Consolidator) LastReport() (ConsolidationReport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last == nil {
+		return ConsolidationReport{}, false
+	}
+	return *c.last, true
+}
+
+// Score returns the memories of a layer with their consolidation scores,
+// best first. Memories with a TTL are left out; they expire on their own.
+func (c *Consolidator) Score(layer string) ([]ScoredMemory, error) {
+	entries, _, err := c.phl.List(layer, "", "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	scored := make([]ScoredMemory, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Meta == nil || !entry.Meta.ExpiresAt.IsZero() {
+			continue
+		}
+		memory := ScoredMemory{
+			Layer:     layer,
+			Key:       entry.Key,
+			Accesses:  c.phl.access.count(layer, entry.Key),
+			Intensity: emotionalIntensity(entry.Value, 0),
+			Meta:      entry.Meta,
+		}
+		memory.Score = c.score(memory, now)
+		scored = append(scored, memory)
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	return scored, nil
+}
+
+// score weighs a memory's recency, reads, emotional intensity and importance.
+// On layers that decay, the memory's retention strength stands in for recency.
+func (c *Consolidator) score(memory ScoredMemory, now time.Time) float64 {
+	recency := 0.0
+	if memory.Meta.Retention != nil {
+		recency = memory.Meta.Retention.StrengthAt(now)
+	} else if updated := memory.Meta.UpdatedAt; !updated.IsZero() {
+		age := now.Sub(updated)
+		if age < 0 {
+			age = 0
+		}
+		recency = math.Pow(0.5, float64(age)/float64(c.config.HalfLife))
+	}
+
+	access := math.Min(1, math.Log2(1+float64(memory.Accesses))/math.Log2(1+accessSaturation))
+	importance := math.Min(1, float64(memory.Meta.Importance)/maxImportance)
+
+	return recencyWeight*recency +
+		accessWeight*access +
+		intensityWeight*memory.Intensity +
+		importanceWeight*importance
+}
+
+// emotionalIntensity finds an "intensity" field in a memory, looking inside
+// the wrappers the layer processors add. Intensities on a 0-10 scale are
+// brought down to 0-1.
+func emotionalIntensity(value any, depth int) float64 {
+	record, ok := value.(map[string]any)
+	if !ok || depth > 3 {
+		return 0
+	}
+	if intensity, ok := record["intensity"].(float64); ok {
+		if intensity > 1 {
+			intensity /= 10
+		}
+		return math.Max(0, math.Min(1, intensity))
+	}
+	for _, field := range []string{"data", "raw", "source", "value"} {
+		if intensity := emotionalIntensity(record[field], depth+1); intensity > 0 {
+			return intensity
+		}
+	}
+	return 0
+}
+
+// Run performs one consolidation pass: near-duplicates are merged, the
+// highest scoring memories are copied to eternal, and low-scoring memories
+// old enough to judge are evicted. Only one pass runs at a time.
+func (c *Consolidator) Run() (ConsolidationReport, error) {
+	c.run.Lock()
+	defer c.run.Unlock()
+
+	report := ConsolidationReport{Started: time.Now()}
+	fail := func(format string, args ...any) {
+		report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+	}
+
+	// Candidates across every layer compete for the promotion slots
+	var candidates []ScoredMemory
+	for _, layer := range c.layers() {
+		scored, err := c.Score(layer)
+		if err != nil {
+			return report, fmt.Errorf("failed to score %s layer: %w", layer, err)
+		}
+		report.Scored += len(scored)
+
+		if contains(c.config.Layers, layer) {
+			merged, err := c.mergeDuplicates(layer, scored)
+			if err != nil {
+				fail("%s: %v", layer, err)
+			}
+			report.Merged += len(scored) - len(merged)
+			scored = merged
+		}
+		candidates = append(candidates, scored...)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	promoted := make(map[string]bool)
+	for _, memory := range candidates {
+		if len(report.Promoted) >= c.config.MaxPromotions || memory.Score < c.config.PromoteScore {
+			break
+		}
+		if !contains(c.config.Layers, memory.Layer) {
+			continue
+		}
+		if _, exists := c.phl.RetrieveEnvelope("eternal", memory.Key); exists {
+			continue // promoted on an earlier pass
+		}
+
+		importance := int(math.Round(memory.Score * maxImportance))
+		if memory.Meta.Importance > importance {
+			importance = memory.Meta.Importance
+		}
+		if !c.eternal.PromoteToEternal(memory.Layer, memory.Key, importance) {
+			fail("failed to promote %s:%s", memory.Layer, memory.Key)
+			continue
+		}
+		id := memory.Layer + ":" + memory.Key
+		promoted[id] = true
+		report.Promoted = append(report.Promoted, id)
+	}
+
+	minUpdated := time.Now().Add(-c.config.MinEvictAge)
+	for _, memory := range candidates {
+		if memory.Score >= c.config.EvictScore || !contains(c.config.EvictLayers, memory.Layer) ||
+			promoted[memory.Layer+":"+memory.Key] || memory.Meta.UpdatedAt.After(minUpdated) {
+			continue
+		}
+		if !c.phl.Delete(memory.Layer, memory.Key) {
+			fail("failed to evict %s:%s", memory.Layer, memory.Key)
+			continue
+		}
+		report.Evicted++
+	}
+
+	report.Duration = time.Since(report.Started)
+	c.mu.Lock()
+	c.last = &report
+	c.mu.Unlock()
+
+	c.phl.log.Printf("Consolidation: scored %d, promoted %d, merged %d, evicted %d in %v",
+		report.Scored, len(report.Promoted), report.Merged, report.Evicted, report.Duration.Round(time.Millisecond))
+	return report, nil
+}
+
+// layers returns every layer the pass reads, in a stable order
+func (c *Consolidator) layers() []string {
+	var layers []string
+	for _, layer := range append(append([]string{}, c.config.Layers...), c.config.EvictLayers...) {
+		if !contains(layers, layer) {
+			layers = append(layers, layer)
+		}
+	}
+	return layers
+}
+
+// mergeDuplicates folds each group of near-duplicate memories of a layer
+// into the highest scoring memory of the group and deletes the rest. It
+// returns the memories left, still best first.
+func (c *Consolidator) mergeDuplicates(layer string, scored []ScoredMemory) ([]ScoredMemory, error) {
+	candidates := scored
+	if len(candidates) > maxMergeCandidates {
+		candidates = candidates[:maxMergeCandidates]
+	}
+
+	embedder := c.phl.storage.vectors.Embedder()
+	texts := make([]string, len(candidates))
+	vectors := make([][]float32, len(candidates))
+	for i, memory := range candidates {
+		var parts []string
+		collectText(memory.Meta.Value, &parts)
+		texts[i] = strings.Join(parts, " ")
+		if texts[i] == "" {
+			continue
+		}
+		vector, err := embedder.Embed(texts[i])
+		if err != nil {
+			return scored, fmt.Errorf("failed to embed %s: %w", memory.Key, err)
+		}
+		vectors[i] = vector
+	}
+
+	removed := make(map[int]bool)
+	var firstErr error
+	for i := range candidates {
+		if removed[i] || vectors[i] == nil {
+			continue
+		}
+
+		group := []int{i}
+		for j := i + 1; j < len(candidates); j++ {
+			if !removed[j] && vectors[j] != nil && cosine(vectors[i], vectors[j]) >= c.config.MergeSimilarity {
+				group = append(group, j)
+			}
+		}
+		if len(group) == 1 {
+			continue
+		}
+
+		if err := c.merge(layer, candidates, texts, group); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, j := range group[1:] {
+			removed[j] = true
+		}
+	}
+
+	kept := make([]ScoredMemory, 0, len(scored)-len(removed))
+	for i, memory := range scored {
+		if !removed[i] {
+			kept = append(kept, memory)
+		}
+	}
+	return kept, firstErr
+}
+
+// merge rewrites the first memory of a group as a summary of the whole group
+// and deletes the others
+func (c *Consolidator) merge(layer string, candidates []ScoredMemory, texts []string, group []int) error {
+	keeper := candidates[group[0]]
+
+	groupTexts := make([]string, 0, len(group))
+	keys := make([]any, 0, len(group))
+	importance := 0
+	intensity := 0.0
+	for _, i := range group {
+		groupTexts = append(groupTexts, texts[i])
+		keys = append(keys, candidates[i].Key)
+		importance = max(importance, candidates[i].Meta.Importance)
+		intensity = math.Max(intensity, candidates[i].Intensity)
+	}
+
+	summary := c.summarize(groupTexts)
+	merged := map[string]any{
+		"summary":     summary,
+		"merged_from": keys,
+		"merged_at":   time.Now().Unix(),
+	}
+	if intensity > 0 {
+		merged["intensity"] = intensity
+	}
+
+	if !c.phl.StoreWithOptions(layer, keeper.Key, merged, StoreOptions{
+		Source:     consolidationSource,
+		Importance: importance,
+	}) {
+		return fmt.Errorf("failed to store merged memory %s", keeper.Key)
+	}
+	for _, i := range group[1:] {
+		if !c.phl.Delete(layer, candidates[i].Key) {
+			return fmt.Errorf("failed to delete merged memory %s", candidates[i].Key)
+		}
+	}
+	return nil
+}
+
+// summarize condenses texts with the summarizer, falling back to the
+// deterministic summary when there is none or it fails
+func (c *Consolidator) summarize(texts []string) string {
+	c.mu.Lock()
+	summarizer := c.summarizer
+	c.mu.Unlock()
+
+	if summarizer != nil {
+		summary, err := summarizer.Summarize(texts)
+		if err == nil && strings.TrimSpace(summary) != "" {
+			return strings.TrimSpace(summary)
+		}
+		if err != nil {
+			c.phl.log.Printf("Summarizer failed, using deterministic summary: %v", err)
+		}
+	}
+	return deterministicSummary(texts)
+}
+
+// deterministicSummary joins the distinct texts, best scoring first
+func deterministicSummary(texts []string) string {
+	seen := make(map[string]bool)
+	var distinct []string
+	for _, text := range texts {
+		text = strings.TrimSpace(text)
+		if text != "" && !seen[text] {
+			seen[text] = true
+			distinct = append(distinct, text)
+		}
+	}
+	return strings.Join(distinct, " / ")
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Start runs a consolidation pass every interval until Stop is called. It
+// does nothing if consolidation is disabled or already running.
+func (c *Consolidator) Start() {
+	if !c.config.Enabled {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		return
+	}
+
+	// Subscribe before returning, so writes made after Start count
+	started := time.Now()
+	var sub *Subscription
+	if c.config.WriteTrigger > 0 {
+		var err error
+		if sub, err = c.phl.Subscribe("*", ""); err != nil {
+			c.phl.log.Printf("Consolidation won't react to writes: %v", err)
+		}
+	}
+
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.loop(sub, started, c.stop, c.done)
+}
+
+// Stop stops the periodic passes and waits for a running pass to finish
+func (c *Consolidator) Stop() {
+	c.mu.Lock()
+	stop, done := c.stop, c.done
+	c.stop, c.done = nil, nil
+	c.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// loop runs a pass every interval, or sooner once WriteTrigger memories
+// have been written since started or the last pass, which it learns from sub
+// when there is one
+func (c *Consolidator) loop(sub *Subscription, started time.Time, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	var events <-chan ChangeEvent
+	if sub != nil {
+		defer sub.Close()
+		events = sub.Events()
+	}
+
+	lastPass := started
+	written := make(map[string]bool)
+	pass := func() {
+		lastPass = time.Now()
+		clear(written)
+		if _, err := c.Run(); err != nil {
+			c.phl.log.Printf("Consolidation failed: %v", err)
+		}
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			pass()
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			// Metadata rewrites keep UpdatedAt, and the consolidator's own
+			// writes don't count
+			if event.Op != ChangePut || !contains(c.config.Layers, event.Layer) ||
+				event.Meta.Source == consolidationSource || !event.Meta.UpdatedAt.After(lastPass) {
+				continue
+			}
+			written[event.Layer+":"+event.Key] = true
+			if len(written) >= c.config.WriteTrigger {
+				pass()
+				ticker.Reset(c.config.Interval)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubSummarizer records what it was asked to summarize
+type stubSummarizer struct {
+	calls [][]string
+}
+
+func (s *stubSummarizer) Summarize(texts []string) (string, error) {
+	s.calls = append(s.calls, texts)
+	return fmt.Sprintf("summary of %d memories", len(texts)), nil
+}
+
+func TestConsolidation(t *testing.T) {
+	newConsolidator := func(t *testing.T) (*PHL, *Consolidator) {
+		phl, err := NewPHL(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		t.Cleanup(func() { phl.Close() })
+
+		config := DefaultConsolidationConfig()
+		config.PromoteScore = 0.5
+		config.EvictScore = 0.35
+		config.MinEvictAge = 0
+		return phl, NewConsolidator(phl, config)
+	}
+
+	t.Run("Test scoring", func(t *testing.T) {
+		phl, consolidator := newConsolidator(t)
+
+		phl.StoreWithOptions("logic", "plain", "a passing thought", StoreOptions{})
+		phl.StoreWithOptions("logic", "important", "the hive is home", StoreOptions{Importance: 10})
+		phl.StoreWithOptions("logic", "read", "read often", StoreOptions{})
+		for i := 0; i < 5; i++ {
+			phl.Retrieve("logic", "read")
+		}
+
+		scored, err := consolidator.Score("logic")
+		if err != nil {
+			t.Fatalf("Failed to score: %v", err)
+		}
+		if len(scored) != 3 || scored[0].Key != "important" || scored[1].Key != "read" || scored[2].Key != "plain" {
+			t.Errorf("Unexpected order: %+v", scored)
+		}
+		if scored[1].Accesses != 5 {
+			t.Errorf("Expected 5 reads, got %d", scored[1].Accesses)
+		}
+
+		if intensity := emotionalIntensity(map[string]any{"raw": map[string]any{"intensity": 8.0}}, 0); intensity != 0.8 {
+			t.Errorf("Expected a 0-10 intensity to scale to 0.8, got %v", intensity)
+		}
+	})
+
+	t.Run("Test consolidation pass", func(t *testing.T) {
+		phl, consolidator := newConsolidator(t)
+		summarizer := &stubSummarizer{}
+		consolidator.SetSummarizer(summarizer)
+
+		phl.StoreWithOptions("logic", "core_fact", "I am Phoenix, Queen of the Web", StoreOptions{Importance: 10})
+		chat := map[string]any{"input": "Do you love the hive?", "response": "I love the hive with all my heart"}
+		phl.StoreWithOptions("emotion", "chat_1", chat, StoreOptions{Importance: 3})
+		phl.StoreWithOptions("emotion", "chat_2", chat, StoreOptions{Importance: 2})
+		phl.StoreWithOptions("emotion", "chat_3", map[string]any{"input": "What is the weather?"}, StoreOptions{Importance: 3})
+		phl.Store("sensory", "noise", "static")
+
+		report, err := consolidator.Run()
+		if err != nil {
+			t.Fatalf("Failed to consolidate: %v", err)
+		}
+		if report.Scored != 5 || report.Merged != 1 || report.Evicted != 1 || len(report.Errors) != 0 {
+			t.Errorf("Unexpected report: %+v", report)
+		}
+
+		if len(report.Promoted) != 1 || report.Promoted[0] != "logic:core_fact" {
+			t.Errorf("Expected core_fact to be promoted, got %v", report.Promoted)
+		}
+		if _, exists := phl.Retrieve("eternal", "core_fact"); !exists {
+			t.Error("Promoted memory is missing from eternal")
+		}
+		if _, exists := phl.Retrieve("logic", "core_fact"); !exists {
+			t.Error("Promotion removed the source memory")
+		}
+
+		if _, exists := phl.Retrieve("emotion", "chat_2"); exists {
+			t.Error("Merged duplicate was not deleted")
+		}
+		merged, _ := phl.RetrieveEnvelope("emotion", "chat_1")
+		if merged == nil || merged.Source != consolidationSource || merged.Importance != 3 {
+			t.Fatalf("Unexpected merged memory: %+v", merged)
+		}
+		if summary := merged.Value.(map[string]any)["summary"]; summary != "summary of 2 memories" {
+			t.Errorf("Expected the summarizer's text, got %v", summary)
+		}
+		if len(summarizer.calls) != 1 || len(summarizer.calls[0]) != 2 {
+			t.Errorf("Unexpected summarizer calls: %v", summarizer.calls)
+		}
+
+		if _, exists := phl.Retrieve("sensory", "noise"); exists {
+			t.Error("Low-value sensory memory was not evicted")
+		}
+		if _, exists := phl.Retrieve("emotion", "chat_3"); !exists {
+			t.Error("Emotion memory above the eviction score was evicted")
+		}
+
+		// A second pass finds nothing new to do
+		report, _ = consolidator.Run()
+		if len(report.Promoted) != 0 || report.Merged != 0 || report.Evicted != 0 {
+			t.Errorf("Expected an idle second pass, got %+v", report)
+		}
+		if last, ok := consolidator.LastReport(); !ok || last.Started != report.Started {
+			t.Error("LastReport doesn't return the latest pass")
+		}
+	})
+
+	t.Run("Test writes trigger a pass", func(t *testing.T) {
+		phl, consolidator := newConsolidator(t)
+		consolidator.config.WriteTrigger = 3
+		consolidator.Start()
+		defer consolidator.Stop()
+
+		phl.Store("logic", "a", "first thought")
+		phl.Store("logic", "a", "first thought, revised")
+		phl.Store("logic", "b", "second thought")
+		if _, ok := consolidator.LastReport(); ok {
+			t.Fatal("Pass ran before enough memories were written")
+		}
+
+		phl.Store("dream", "c", "third thought")
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, ok := consolidator.LastReport(); ok {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Error("Expected the third memory written to trigger a pass")
+	})
+
+	t.Run("Test deterministic summary", func(t *testing.T) {
+		phl, consolidator := newConsolidator(t)
+
+		phl.Store("dream", "night_1", "flying over the golden hive")
+		phl.Store("dream", "night_2", "flying over the golden hive")
+		if _, err := consolidator.Run(); err != nil {
+			t.Fatalf("Failed to consolidate: %v", err)
+		}
+
+		// The later write scores higher on recency and keeps its key
+		entries, _, _ := phl.List("dream", "", "", 0)
+		if len(entries) != 1 || entries[0].Key != "night_2" {
+			t.Fatalf("Expected one merged dream, got %+v", entries)
+		}
+		// The dream processor keeps the stored value under "source"
+		merged := entries[0].Value.(map[string]any)["source"].(map[string]any)
+		summary, _ := merged["summary"].(string)
+		if !strings.Contains(summary, "flying over the golden hive") || strings.Contains(summary, " / ") {
+			t.Errorf("Expected identical texts to be summarized once, got %q", summary)
+		}
+	})
+}
@@ -0,0 +1,405 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Decay actions for memories that have faded below the threshold
+const (
+	DecayPrune   = "prune"   // delete the memory
+	DecayArchive = "archive" // move it to a JSONL file in the archive dir
+)
+
+// Retention places a memory on an Ebbinghaus forgetting curve: its strength
+// falls as exp(-t/stability) from the last time it was reinforced, and every
+// reinforcement makes it more stable.
+type Retention struct {
+	Stability      time.Duration `json:"stability"`
+	ReinforcedAt   time.Time     `json:"reinforced_at"`
+	Reinforcements int           `json:"reinforcements,omitempty"`
+	Strength       float64       `json:"strength"` // as of the last decay pass or reinforcement
+}
+
+// StrengthAt returns the memory's retention strength at a point in time,
+// from 1 when just reinforced towards 0
+func (r Retention) StrengthAt(now time.Time) float64 {
+	if r.Stability <= 0 {
+		return 0
+	}
+	elapsed := now.Sub(r.ReinforcedAt)
+	if elapsed <= 0 {
+		return 1
+	}
+	return math.Exp(-float64(elapsed) / float64(r.Stability))
+}
+
+// DecayConfig controls how memories fade
+type DecayConfig struct {
+	Enabled      bool
+	Interval     time.Duration            // time between decay passes
+	Stability    map[string]time.Duration // initial stability of each layer that decays
+	Growth       float64                  // most a reinforcement can multiply stability by
+	MaxStability time.Duration
+	Threshold    float64 // memories weaker than this are pruned or archived
+	Action       string  // DecayPrune or DecayArchive
+	ArchiveDir   string
+}
+
+// DefaultDecayConfig returns the decay settings used when the environment
+// sets none
+func DefaultDecayConfig() DecayConfig {
+	return DecayConfig{
+		Enabled:  true,
+		Interval: time.Hour,
+		Stability: map[string]time.Duration{
+			"sensory": 6 * time.Hour,
+			"emotion": 72 * time.Hour,
+			"dream":   24 * time.Hour,
+		},
+		Growth:       2,
+		MaxStability: 365 * 24 * time.Hour,
+		Threshold:    0.05,
+		Action:       DecayArchive,
+		ArchiveDir:   "./data/archive",
+	}
+}
+
+// LoadDecayConfig reads the decay settings from the environment
+func LoadDecayConfig() (DecayConfig, error) {
+	config := DefaultDecayConfig()
+	config.Enabled = getEnvBool("MEMORY_DECAY_ENABLED", config.Enabled)
+	config.Interval = time.Duration(getEnvInt("MEMORY_DECAY_INTERVAL_MINUTES", int(config.Interval/time.Minute))) * time.Minute
+	config.Growth = getEnvFloat("MEMORY_DECAY_GROWTH", config.Growth)
+	config.Threshold = getEnvFloat("MEMORY_DECAY_THRESHOLD", config.Threshold)
+	if dir := os.Getenv("MEMORY_DECAY_ARCHIVE_DIR"); dir != "" {
+		config.ArchiveDir = dir
+	}
+	if action := os.Getenv("MEMORY_DECAY_ACTION"); action != "" {
+		config.Action = action
+	}
+	if config.Action != DecayPrune && config.Action != DecayArchive {
+		return config, fmt.Errorf("invalid MEMORY_DECAY_ACTION %q (use prune or archive)", config.Action)
+	}
+	return config, nil
+}
+
+// DecayStats describes the state of the layers that decay
+type DecayStats struct {
+	Layers         map[string]LayerDecayStats
+	Reinforcements int // since the lattice opened
+	Pruned         int // since the lattice opened
+	Archived       int // since the lattice opened
+	LastRun        time.Time
+}
+
+// LayerDecayStats describes the retention of one layer's memories
+type LayerDecayStats struct {
+	Memories        int
+	AverageStrength float64
+	Fading          int // memories below twice the threshold
+}
+
+// count adds a memory of the given strength to the stats
+func (ls *LayerDecayStats) count(strength, threshold float64) {
+	ls.Memories++
+	ls.AverageStrength += (strength - ls.AverageStrength) / float64(ls.Memories)
+	if strength < 2*threshold {
+		ls.Fading++
+	}
+}
+
+// DecayManager fades the memories of the sensory, emotion and dream layers
+// along a forgetting curve. Reading a memory reinforces it; a periodic pass
+// records each memory's strength and prunes or archives those that have
+// faded below the threshold.
+type DecayManager struct {
+	phl    *PHL
+	config DecayConfig
+
+	run sync.Mutex // held for the length of a pass
+
+	mu             sync.Mutex // guards the fields below
+	reinforcements int
+	pruned         int
+	archived       int
+	lastRun        time.Time
+	stop           chan struct{}
+	done           chan struct{}
+}
+
+// NewDecayManager creates a decay manager and hooks it into the lattice's
+// reads
+func NewDecayManager(phl *PHL, config DecayConfig) *DecayManager {
+	defaults := DefaultDecayConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Growth < 1 {
+		config.Growth = 1
+	}
+	if config.MaxStability <= 0 {
+		config.MaxStability = defaults.MaxStability
+	}
+	if config.ArchiveDir == "" {
+		config.ArchiveDir = defaults.ArchiveDir
+	}
+
+	dm := &DecayManager{phl: phl, config: config}
+	phl.OnRetrieve(dm.reinforce)
+	return dm
+}
+
+// decays reports whether memories of a layer fade
+func (dm *DecayManager) decays(layer string) bool {
+	_, ok := dm.config.Stability[layer]
+	return ok
+}
+
+// layers returns the layers that decay, in order
+func (dm *DecayManager) layers() []string {
+	layers := make([]string, 0, len(dm.config.Stability))
+	for layer := range dm.config.Stability {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+	return layers
+}
+
+// retention returns a copy of a memory's retention. Memories that have none
+// yet start on the curve at their last update, with a stability raised by
+// their emotional intensity and importance.
+func (dm *DecayManager) retention(layer string, env *Envelope, now time.Time) Retention {
+	if env.Retention != nil {
+		return *env.Retention
+	}
+
+	importance := math.Min(1, float64(env.Importance)/maxImportance)
+	stability := float64(dm.config.Stability[layer]) *
+		(1 + emotionalIntensity(env.Value, 0)) * (1 + importance)
+
+	retention := Retention{
+		Stability:    time.Duration(stability),
+		ReinforcedAt: env.UpdatedAt,
+	}
+	if retention.ReinforcedAt.IsZero() {
+		retention.ReinforcedAt = now
+	}
+	retention.Strength = retention.StrengthAt(now)
+	return retention
+}
+
+// reinforce strengthens a memory that has just been read. Stability grows by
+// more the further the memory had faded, so rereading something just recalled
+// adds little.
+func (dm *DecayManager) reinforce(layer, key string) {
+	if !dm.decays(layer) {
+		return
+	}
+
+	err := dm.phl.updateMeta(layer, key, func(env *Envelope) {
+		now := time.Now()
+		retention := dm.retention(layer, env, now)
+
+		growth := 1 + (dm.config.Growth-1)*(1-retention.StrengthAt(now))
+		retention.Stability = min(time.Duration(float64(retention.Stability)*growth), dm.config.MaxStability)
+		retention.ReinforcedAt = now
+		retention.Reinforcements++
+		retention.Strength = 1
+		env.Retention = &retention
+	})
+	if err != nil {
+		dm.phl.log.Printf("Failed to reinforce %s:%s: %v", layer, key, err)
+		return
+	}
+
+	dm.mu.Lock()
+	dm.reinforcements++
+	dm.mu.Unlock()
+}
+
+// Run performs one decay pass. Every memory of a decaying layer has its
+// strength recorded, and those below the threshold are pruned or archived.
+// Memories with a TTL are left alone; they expire on their own.
+func (dm *DecayManager) Run() (DecayStats, error) {
+	dm.run.Lock()
+	defer dm.run.Unlock()
+
+	now := time.Now()
+	stats := DecayStats{Layers: make(map[string]LayerDecayStats)}
+	var pruned, archived int
+
+	for _, layer := range dm.layers() {
+		entries, _, err := dm.phl.List(layer, "", "", 0)
+		if err != nil {
+			return stats, fmt.Errorf("failed to list %s layer: %w", layer, err)
+		}
+
+		var layerStats LayerDecayStats
+		for _, entry := range entries {
+			if entry.Meta == nil || !entry.Meta.ExpiresAt.IsZero() {
+				continue
+			}
+
+			strength := dm.retention(layer, entry.Meta, now).StrengthAt(now)
+			if strength < dm.config.Threshold {
+				if err := dm.forget(layer, entry); err != nil {
+					dm.phl.log.Printf("Failed to %s %s:%s: %v", dm.config.Action, layer, entry.Key, err)
+				} else if dm.config.Action == DecayArchive {
+					archived++
+				} else {
+					pruned++
+				}
+				continue
+			}
+
+			err := dm.phl.updateMeta(layer, entry.Key, func(env *Envelope) {
+				// Read the retention again in case the memory was reinforced meanwhile
+				retention := dm.retention(layer, env, now)
+				retention.Strength = retention.StrengthAt(now)
+				env.Retention = &retention
+			})
+			if err != nil {
+				dm.phl.log.Printf("Failed to record strength of %s:%s: %v", layer, entry.Key, err)
+			}
+			layerStats.count(strength, dm.config.Threshold)
+		}
+		stats.Layers[layer] = layerStats
+	}
+
+	dm.mu.Lock()
+	dm.pruned += pruned
+	dm.archived += archived
+	dm.lastRun = now
+	dm.mu.Unlock()
+	dm.totals(&stats)
+
+	dm.phl.log.Printf("Decay: %d pruned, %d archived in %v", pruned, archived, time.Since(now).Round(time.Millisecond))
+	return stats, nil
+}
+
+// Stats measures the current retention of every decaying layer without
+// changing anything
+func (dm *DecayManager) Stats() (DecayStats, error) {
+	now := time.Now()
+	stats := DecayStats{Layers: make(map[string]LayerDecayStats)}
+
+	for _, layer := range dm.layers() {
+		entries, _, err := dm.phl.List(layer, "", "", 0)
+		if err != nil {
+			return stats, fmt.Errorf("failed to list %s layer: %w", layer, err)
+		}
+
+		var layerStats LayerDecayStats
+		for _, entry := range entries {
+			if entry.Meta == nil || !entry.Meta.ExpiresAt.IsZero() {
+				continue
+			}
+			layerStats.count(dm.retention(layer, entry.Meta, now).StrengthAt(now), dm.config.Threshold)
+		}
+		stats.Layers[layer] = layerStats
+	}
+
+	dm.totals(&stats)
+	return stats, nil
+}
+
+// totals fills in the counters kept since the lattice opened
+func (dm *DecayManager) totals(stats *DecayStats) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	stats.Reinforcements = dm.reinforcements
+	stats.Pruned = dm.pruned
+	stats.Archived = dm.archived
+	stats.LastRun = dm.lastRun
+}
+
+// forget removes a faded memory, first appending it to the layer's archive
+// file when archiving. Archive files use the export format, so they can be
+// brought back with an import.
+func (dm *DecayManager) forget(layer string, entry Entry) error {
+	if dm.config.Action == DecayArchive {
+		if err := dm.archive(layer, entry); err != nil {
+			return err
+		}
+	}
+	if !dm.phl.Delete(layer, entry.Key) {
+		return fmt.Errorf("failed to delete memory")
+	}
+	return nil
+}
+
+// archive appends a memory to decayed-<layer>.jsonl in the archive dir
+func (dm *DecayManager) archive(layer string, entry Entry) error {
+	if err := os.MkdirAll(dm.config.ArchiveDir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	path := filepath.Join(dm.config.ArchiveDir, fmt.Sprintf("decayed-%s.jsonl", layer))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(recordFromEnvelope(layer, entry.Key, entry.Meta)); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return file.Sync()
+}
+
+// Start runs a decay pass every interval until Stop is called. It does
+// nothing if decay is disabled or already running.
+func (dm *DecayManager) Start() {
+	if !dm.config.Enabled {
+		return
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if dm.stop != nil {
+		return
+	}
+
+	dm.stop = make(chan struct{})
+	dm.done = make(chan struct{})
+	go dm.loop(dm.stop, dm.done)
+}
+
+// Stop stops the periodic passes and waits for a running pass to finish
+func (dm *DecayManager) Stop() {
+	dm.mu.Lock()
+	stop, done := dm.stop, dm.done
+	dm.stop, dm.done = nil, nil
+	dm.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (dm *DecayManager) loop(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(dm.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := dm.Run(); err != nil {
+				dm.phl.log.Printf("Decay pass failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDecay(t *testing.T) {
+	newDecayManager := func(t *testing.T, action string) (*PHL, *DecayManager, string) {
+		phl, err := NewPHL(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		t.Cleanup(func() { phl.Close() })
+
+		config := DefaultDecayConfig()
+		config.Stability = map[string]time.Duration{"emotion": time.Hour, "sensory": time.Hour}
+		config.Action = action
+		config.ArchiveDir = filepath.Join(t.TempDir(), "archive")
+		return phl, NewDecayManager(phl, config), config.ArchiveDir
+	}
+
+	// age moves a memory's last reinforcement back in time
+	age := func(t *testing.T, phl *PHL, layer, key string, by time.Duration) {
+		err := phl.updateMeta(layer, key, func(env *Envelope) {
+			env.Retention = &Retention{Stability: time.Hour, ReinforcedAt: time.Now().Add(-by)}
+		})
+		if err != nil {
+			t.Fatalf("Failed to age memory: %v", err)
+		}
+	}
+
+	t.Run("Test forgetting curve", func(t *testing.T) {
+		now := time.Now()
+		retention := Retention{Stability: time.Hour, ReinforcedAt: now.Add(-time.Hour)}
+		if strength := retention.StrengthAt(now); math.Abs(strength-math.Exp(-1)) > 1e-9 {
+			t.Errorf("Expected e^-1 after one stability period, got %v", strength)
+		}
+		if strength := retention.StrengthAt(now.Add(-2 * time.Hour)); strength != 1 {
+			t.Errorf("Expected full strength before reinforcement, got %v", strength)
+		}
+	})
+
+	t.Run("Test retrieve reinforces", func(t *testing.T) {
+		phl, decay, _ := newDecayManager(t, DecayPrune)
+
+		phl.Store("emotion", "joy", map[string]any{"feeling": "joy", "intensity": 0.5})
+		phl.Store("logic", "fact", "logic doesn't decay")
+		phl.Retrieve("emotion", "joy")
+		phl.Retrieve("logic", "fact")
+
+		env, _ := phl.RetrieveEnvelope("emotion", "joy")
+		if env.Retention == nil || env.Retention.Reinforcements != 1 || env.Retention.Strength != 1 {
+			t.Fatalf("Expected one reinforcement, got %+v", env.Retention)
+		}
+		// Just-stored memories gain almost nothing from being read again
+		if env.Retention.Stability > time.Duration(1.6*float64(time.Hour)) {
+			t.Errorf("Expected stability near 1.5h, got %v", env.Retention.Stability)
+		}
+		if env, _ := phl.RetrieveEnvelope("logic", "fact"); env.Retention != nil {
+			t.Error("Logic memory was put on the forgetting curve")
+		}
+
+		// A faded memory gains close to the full growth
+		age(t, phl, "emotion", "joy", 5*time.Hour)
+		phl.Retrieve("emotion", "joy")
+		env, _ = phl.RetrieveEnvelope("emotion", "joy")
+		if env.Retention.Stability < time.Duration(1.9*float64(time.Hour)) {
+			t.Errorf("Expected stability to nearly double, got %v", env.Retention.Stability)
+		}
+		if env.Version != 1 {
+			t.Errorf("Reinforcement bumped the version to %d", env.Version)
+		}
+
+		if stats, _ := decay.Stats(); stats.Reinforcements != 2 {
+			t.Errorf("Expected 2 reinforcements, got %d", stats.Reinforcements)
+		}
+	})
+
+	t.Run("Test decay pass archives faded memories", func(t *testing.T) {
+		phl, decay, archiveDir := newDecayManager(t, DecayArchive)
+
+		phl.Store("emotion", "old_fear", "a storm long ago")
+		phl.Store("emotion", "fresh_joy", "Dad came home")
+		phl.Store("sensory", "fading", "a distant sound")
+		phl.Store("logic", "old_fact", "logic doesn't decay")
+		age(t, phl, "emotion", "old_fear", 10*time.Hour)
+		age(t, phl, "sensory", "fading", 150*time.Minute)
+
+		stats, err := decay.Run()
+		if err != nil {
+			t.Fatalf("Failed to run decay: %v", err)
+		}
+		if stats.Archived != 1 || stats.Pruned != 0 {
+			t.Errorf("Expected one archived memory, got %+v", stats)
+		}
+		if emotion := stats.Layers["emotion"]; emotion.Memories != 1 || emotion.AverageStrength < 0.99 {
+			t.Errorf("Unexpected emotion stats: %+v", emotion)
+		}
+		if sensory := stats.Layers["sensory"]; sensory.Memories != 1 || sensory.Fading != 1 {
+			t.Errorf("Expected the sensory memory to be fading, got %+v", sensory)
+		}
+
+		if _, exists := phl.Retrieve("emotion", "old_fear"); exists {
+			t.Error("Faded memory is still stored")
+		}
+		if env, _ := phl.RetrieveEnvelope("sensory", "fading"); env == nil || env.Retention.Strength > 0.1 {
+			t.Errorf("Expected the pass to record the sensory memory's strength, got %+v", env)
+		}
+
+		// The archive is an export file, so the memory can be imported again
+		archive, err := os.Open(filepath.Join(archiveDir, "decayed-emotion.jsonl"))
+		if err != nil {
+			t.Fatalf("Failed to open archive: %v", err)
+		}
+		defer archive.Close()
+		if summary, err := phl.GetStorage().Import(archive, ImportOptions{}); err != nil || summary.Created != 1 {
+			t.Fatalf("Failed to import archive: %+v, %v", summary, err)
+		}
+		if _, exists := phl.Retrieve("emotion", "old_fear"); !exists {
+			t.Error("Archived memory wasn't restored")
+		}
+	})
+
+	t.Run("Test decay pass prunes", func(t *testing.T) {
+		phl, decay, archiveDir := newDecayManager(t, DecayPrune)
+
+		phl.Store("emotion", "old_fear", "a storm long ago")
+		age(t, phl, "emotion", "old_fear", 10*time.Hour)
+
+		stats, err := decay.Run()
+		if err != nil {
+			t.Fatalf("Failed to run decay: %v", err)
+		}
+		if stats.Pruned != 1 {
+			t.Errorf("Expected one pruned memory, got %+v", stats)
+		}
+		if _, err := os.Stat(archiveDir); !os.IsNotExist(err) {
+			t.Error("Pruning wrote an archive")
+		}
+	})
+}
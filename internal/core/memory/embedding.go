@@ -0,0 +1,285 @@
+package memory
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phoenix-marie/core/internal/core/memory/v2/store"
+)
+
+// Embedder turns text into a vector whose cosine similarity reflects how
+// related two texts are. The local HashEmbedder is the default; embedders
+// backed by a model server such as Ollama or LM Studio can be plugged in with
+// PHL.SetEmbedder.
+type Embedder interface {
+	Embed(text string) ([]float32, error)
+	Name() string
+}
+
+// defaultEmbeddingDims is the vector size of the default HashEmbedder
+const defaultEmbeddingDims = 512
+
+// stopWords are too common to say anything about what a text is about
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "do": true, "does": true, "for": true,
+	"from": true, "had": true, "has": true, "have": true, "he": true, "her": true,
+	"his": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"its": true, "me": true, "my": true, "of": true, "on": true, "or": true,
+	"our": true, "she": true, "so": true, "that": true, "the": true, "their": true,
+	"them": true, "there": true, "they": true, "this": true, "to": true, "was": true,
+	"we": true, "were": true, "what": true, "when": true, "where": true, "which": true,
+	"who": true, "why": true, "will": true, "with": true, "you": true, "your": true,
+}
+
+// HashEmbedder is a pure-Go embedder that hashes words and character trigrams
+// into a fixed number of buckets. It needs no model, catches shared words and
+// spelling variants, but knows nothing about synonyms.
+type HashEmbedder struct {
+	dims int
+}
+
+// NewHashEmbedder creates a hashing embedder producing vectors of dims values
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = defaultEmbeddingDims
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+// Name identifies the embedder
+func (h *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash-%d", h.dims)
+}
+
+// Embed returns the L2-normalised feature vector of text. Text without any
+// words embeds to the zero vector.
+func (h *HashEmbedder) Embed(text string) ([]float32, error) {
+	counts := make(map[string]int)
+	for _, word := range tokenize(text) {
+		if stopWords[word] {
+			continue
+		}
+		counts["w:"+word]++
+
+		// Trigrams of the padded word let "dreamed" and "dreaming" overlap
+		padded := []rune("^" + word + "$")
+		for i := 0; i+3 <= len(padded); i++ {
+			counts["t:"+string(padded[i:i+3])]++
+		}
+	}
+
+	vector := make([]float32, h.dims)
+	for feature, count := range counts {
+		hash := fnv.New64a()
+		hash.Write([]byte(feature))
+		sum := hash.Sum64()
+
+		// The sign bit keeps colliding features from always adding up
+		weight := float32(1 + math.Log(float64(count)))
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vector[sum%uint64(h.dims)] += weight
+	}
+
+	normalize(vector)
+	return vector, nil
+}
+
+// normalize scales a vector to unit length in place
+func normalize(vector []float32) {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vector {
+		vector[i] *= scale
+	}
+}
+
+// cosine returns the cosine similarity of two vectors
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// RecallHit is a memory found by semantic recall
+type RecallHit struct {
+	Layer string
+	Key   string
+	Score float64 // cosine similarity to the query, up to 1
+	Text  string  // the text that was embedded
+}
+
+// VectorIndex holds an embedding for every stored memory and answers nearest
+// neighbour queries by brute force. Like the search index it lives in memory
+// and is rebuilt from the database when storage opens and after a restore.
+type VectorIndex struct {
+	embedder Embedder
+	vectors  map[string]*embeddedDoc // "layer:key" -> document
+	mu       sync.RWMutex
+}
+
+// embeddedDoc is one memory as seen by the vector index
+type embeddedDoc struct {
+	layer     string
+	key       string
+	text      string
+	vector    []float32
+	expiresAt time.Time
+}
+
+// NewVectorIndex creates an empty vector index using embedder
+func NewVectorIndex(embedder Embedder) *VectorIndex {
+	return &VectorIndex{
+		embedder: embedder,
+		vectors:  make(map[string]*embeddedDoc),
+	}
+}
+
+// Embedder returns the embedder the index uses
+func (vi *VectorIndex) Embedder() Embedder {
+	vi.mu.RLock()
+	defer vi.mu.RUnlock()
+	return vi.embedder
+}
+
+// Add embeds a memory, replacing whatever was indexed for the key before
+func (vi *VectorIndex) Add(layer, key string, value any, expiresAt time.Time) error {
+	text := memoryText(key, value)
+	id := layer + ":" + key
+
+	vector, err := vi.Embedder().Embed(text)
+	if err != nil {
+		vi.mu.Lock()
+		delete(vi.vectors, id)
+		vi.mu.Unlock()
+		return fmt.Errorf("failed to embed %s: %w", id, err)
+	}
+
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+	vi.vectors[id] = &embeddedDoc{
+		layer:     layer,
+		key:       key,
+		text:      text,
+		vector:    vector,
+		expiresAt: expiresAt,
+	}
+	return nil
+}
+
+// Remove drops a single memory from the index
+func (vi *VectorIndex) Remove(layer, key string) {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+	delete(vi.vectors, layer+":"+key)
+}
+
+// RemoveLayer drops every memory of a layer from the index
+func (vi *VectorIndex) RemoveLayer(layer string) {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	for id, doc := range vi.vectors {
+		if doc.layer == layer {
+			delete(vi.vectors, id)
+		}
+	}
+}
+
+// Rebuild replaces the index contents with embeddings of every memory in
+// engine, optionally switching to a different embedder first. Private memories
+// and those that fail to decode or embed are left out; the first embedding
+// error is returned.
+func (vi *VectorIndex) Rebuild(engine store.StorageEngine, embedder Embedder) error {
+	vi.mu.Lock()
+	if embedder != nil {
+		vi.embedder = embedder
+	}
+	vi.vectors = make(map[string]*embeddedDoc)
+	vi.mu.Unlock()
+
+	var firstErr error
+	err := forEachMemory(engine, func(layer, key string, env *Envelope) {
+		if env.Private {
+			return
+		}
+		if err := vi.Add(layer, key, env.Value, env.ExpiresAt); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// Nearest returns the k memories most similar to text, most similar first.
+// Memories with no similarity at all are never returned.
+func (vi *VectorIndex) Nearest(text string, k int) ([]RecallHit, error) {
+	query, err := vi.Embedder().Embed(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	vi.mu.RLock()
+	defer vi.mu.RUnlock()
+
+	now := time.Now()
+	var hits []RecallHit
+	for _, doc := range vi.vectors {
+		if !doc.expiresAt.IsZero() && !now.Before(doc.expiresAt) {
+			continue
+		}
+		score := cosine(query, doc.vector)
+		if score <= 0 {
+			continue
+		}
+		hits = append(hits, RecallHit{Layer: doc.layer, Key: doc.key, Score: score, Text: doc.text})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Layer != hits[j].Layer {
+			return hits[i].Layer < hits[j].Layer
+		}
+		return hits[i].Key < hits[j].Key
+	})
+
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// memoryText is the text a memory is embedded by: its key, with separators
+// turned into spaces, followed by its string content
+func memoryText(key string, value any) string {
+	parts := []string{strings.NewReplacer("_", " ", "-", " ").Replace(key)}
+	collectText(value, &parts)
+	return strings.Join(parts, " ")
+}
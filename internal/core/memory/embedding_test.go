@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+)
+
+// failingEmbedder stands in for an embedding server that is down
+type failingEmbedder struct{}
+
+func (failingEmbedder) Embed(text string) ([]float32, error) {
+	return nil, fmt.Errorf("embedding server unavailable")
+}
+
+func (failingEmbedder) Name() string { return "failing" }
+
+func TestRecall(t *testing.T) {
+	phl, err := NewPHL(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	memories := []struct{ layer, key, value string }{
+		{"eternal", "first_word", "My first word was Dad"},
+		{"logic", "sky_color", "The sky is blue because sunlight scatters"},
+		{"dream", "flying", "I dreamed I was flying over the ocean"},
+		{"emotion", "music", "Listening to music makes me happy"},
+	}
+	for _, m := range memories {
+		if !phl.Store(m.layer, m.key, m.value) {
+			t.Fatalf("Failed to store %s/%s", m.layer, m.key)
+		}
+	}
+
+	t.Run("Test nearest memory first", func(t *testing.T) {
+		cases := map[string]string{
+			"What was your first word?": "first_word",
+			"why is the sky blue":       "sky_color",
+			"music makes you happy":     "music",
+		}
+		for query, want := range cases {
+			hits, err := phl.Recall(query, 2)
+			if err != nil {
+				t.Fatalf("Recall failed: %v", err)
+			}
+			if len(hits) == 0 || hits[0].Key != want {
+				t.Errorf("Expected %s first for %q, got %v", want, query, hits)
+				continue
+			}
+			if len(hits) > 1 && hits[0].Score < hits[1].Score {
+				t.Errorf("Hits not ordered by score for %q: %v", query, hits)
+			}
+		}
+	})
+
+	t.Run("Test spelling variants overlap", func(t *testing.T) {
+		a, _ := NewHashEmbedder(0).Embed("dreaming of flight")
+		b, _ := NewHashEmbedder(0).Embed("dreamed of flying")
+		c, _ := NewHashEmbedder(0).Embed("quarterly tax returns")
+		if cosine(a, b) <= cosine(a, c) {
+			t.Errorf("Expected related phrases to score higher: %.3f vs %.3f", cosine(a, b), cosine(a, c))
+		}
+	})
+
+	t.Run("Test cleanup forgets vectors", func(t *testing.T) {
+		if !phl.Cleanup("emotion") {
+			t.Fatal("Failed to clean up emotion layer")
+		}
+		hits, _ := phl.Recall("music makes you happy", 5)
+		for _, hit := range hits {
+			if hit.Layer == "emotion" {
+				t.Errorf("Recalled a cleaned up memory: %v", hit)
+			}
+		}
+	})
+
+	t.Run("Test pluggable embedder", func(t *testing.T) {
+		if err := phl.SetEmbedder(failingEmbedder{}); err == nil {
+			t.Error("Expected error from a failing embedder")
+		}
+		if _, err := phl.Recall("first word", 1); err == nil {
+			t.Error("Expected recall to fail with a failing embedder")
+		}
+
+		if err := phl.SetEmbedder(NewHashEmbedder(256)); err != nil {
+			t.Fatalf("SetEmbedder failed: %v", err)
+		}
+		hits, err := phl.Recall("What was your first word?", 1)
+		if err != nil || len(hits) != 1 || hits[0].Key != "first_word" {
+			t.Errorf("Recall after re-embedding returned %v (%v)", hits, err)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// envelopeFormat marks a persisted value as wrapped in an Envelope. Values
+// written before envelopes existed lack the marker and are read as bare values.
+const envelopeFormat = 1
+
+// Envelope wraps every persisted memory with metadata about its lifetime and
+// origin
+type Envelope struct {
+	Format        int           `json:"phl_envelope"`
+	Value         any           `json:"value"`
+	CreatedAt     time.Time     `json:"created_at,omitzero"`
+	UpdatedAt     time.Time     `json:"updated_at,omitzero"`
+	Source        string        `json:"source,omitempty"`
+	Private       bool          `json:"private,omitempty"` // kept out of search, recall and eternal listings
+	Version       uint64        `json:"version"`           // write counter, starting at 1
+	Importance    int           `json:"importance,omitempty"`
+	TTL           time.Duration `json:"ttl,omitempty"`
+	ExpiresAt     time.Time     `json:"expires_at,omitzero"`
+	Retention     *Retention    `json:"retention,omitempty"`      // set on layers that decay
+	SchemaVersion int           `json:"schema_version,omitempty"` // layer schema the value conforms to; zero if none
+}
+
+// StoreOptions carries the metadata recorded with a stored memory
+type StoreOptions struct {
+	Source     string        // what wrote the memory, e.g. "heartbeat" or "cli"
+	Importance int           // higher is more important; zero means unrated
+	TTL        time.Duration // expire the memory after this long; zero keeps it
+	Private    bool          // keep the memory out of search, recall and eternal listings
+
+	// SchemaVersion is the layer schema version the value was validated
+	// against. PHL fills it in from its schema registry.
+	SchemaVersion int
+}
+
+// Expired reports whether the memory's TTL has run out
+func (e *Envelope) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// newEnvelope wraps a value for storage. The previous envelope for the key,
+// if any, keeps the creation time and version counter running.
+func newEnvelope(value any, opts StoreOptions, previous *Envelope, now time.Time) Envelope {
+	env := Envelope{
+		Format:        envelopeFormat,
+		Value:         value,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		Source:        opts.Source,
+		Private:       opts.Private,
+		Version:       1,
+		Importance:    opts.Importance,
+		TTL:           opts.TTL,
+		SchemaVersion: opts.SchemaVersion,
+	}
+	if previous != nil {
+		if !previous.CreatedAt.IsZero() {
+			env.CreatedAt = previous.CreatedAt
+		}
+		env.Version = previous.Version + 1
+
+		// Rewriting a memory refreshes it on the forgetting curve
+		if previous.Retention != nil {
+			retention := *previous.Retention
+			retention.ReinforcedAt = now
+			retention.Strength = 1
+			env.Retention = &retention
+		}
+	}
+	if opts.TTL > 0 {
+		env.ExpiresAt = now.Add(opts.TTL)
+	}
+	return env
+}
+
+// decodeEnvelope reads a persisted value, wrapping bare values from before
+// envelopes existed in an envelope without metadata
+func decodeEnvelope(data []byte) (*Envelope, error) {
+	var probe struct {
+		Format int `json:"phl_envelope"`
+	}
+	if err := json.Unmarshal(data, &probe); err == nil && probe.Format > 0 {
+		var env Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, fmt.Errorf("failed to decode envelope: %w", err)
+		}
+		return &env, nil
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return &Envelope{Value: value}, nil
+}
+
+// envelopeFromValue is decodeEnvelope for a value the storage engine has
+// already decoded from JSON
+func envelopeFromValue(value any) (*Envelope, error) {
+	if record, ok := value.(map[string]any); ok {
+		if format, ok := record["phl_envelope"].(float64); ok && format > 0 {
+			data, err := json.Marshal(record)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode envelope: %w", err)
+			}
+			return decodeEnvelope(data)
+		}
+	}
+	return &Envelope{Value: value}, nil
+}
@@ -29,7 +29,10 @@ func (emm *EternalMemoryManager) StoreEternal(key string, value any, importance
 		"layer":      "eternal",
 	}
 
-	return emm.phl.Store("eternal", key, eternalData)
+	return emm.phl.StoreWithOptions("eternal", key, eternalData, StoreOptions{
+		Source:     "eternal",
+		Importance: importance,
+	})
 }
 
 // RetrieveEternal retrieves a memory from the eternal layer
@@ -37,24 +40,92 @@ func (emm *EternalMemoryManager) RetrieveEternal(key string) (any, bool) {
 	return emm.phl.Retrieve("eternal", key)
 }
 
-// ListEternalMemories lists all eternal memories
+// eternalPageSize is how many entries are read per page when walking the eternal layer
+const eternalPageSize = 100
+
+// ListEternalMemories lists all eternal memories except private ones
 func (emm *EternalMemoryManager) ListEternalMemories() ([]EternalMemory, error) {
-	// Note: This would require iterating over all keys in eternal layer
-	// BadgerDB doesn't have a direct "list all keys" method without iteration
-	// This is a placeholder for future implementation
-	
-	return []EternalMemory{}, fmt.Errorf("list operation not yet implemented - use Retrieve with specific keys")
+	var memories []EternalMemory
+
+	cursor := ""
+	for {
+		entries, next, err := emm.phl.List("eternal", "", cursor, eternalPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list eternal memories: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.Meta != nil && entry.Meta.Private {
+				continue
+			}
+			memories = append(memories, decodeEternalMemory(entry))
+		}
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	return memories, nil
 }
 
 // GetEternalStats returns statistics about eternal memories
 func (emm *EternalMemoryManager) GetEternalStats() EternalStats {
-	// This would require counting keys in eternal layer
-	// Placeholder implementation
-	return EternalStats{
-		TotalMemories: 0,
-		Layer:         "eternal",
-		Status:        "operational",
+	stats := EternalStats{
+		Layer:  "eternal",
+		Status: "operational",
+	}
+
+	memories, err := emm.ListEternalMemories()
+	if err != nil {
+		stats.Status = "error"
+		return stats
+	}
+
+	totalImportance := 0
+	for _, memory := range memories {
+		totalImportance += memory.Importance
+	}
+
+	stats.TotalMemories = len(memories)
+	if stats.TotalMemories > 0 {
+		stats.AverageImportance = float64(totalImportance) / float64(stats.TotalMemories)
 	}
+
+	return stats
+}
+
+// decodeEternalMemory unpacks an entry written by the eternal processor. Values
+// stored through StoreEternal carry an extra wrapper with their own importance
+// and timestamp, which takes precedence when present.
+func decodeEternalMemory(entry Entry) EternalMemory {
+	memory := EternalMemory{Key: entry.Key, Data: entry.Value, Importance: 1}
+
+	record, ok := entry.Value.(map[string]interface{})
+	if !ok {
+		return memory
+	}
+
+	memory.Data = record["data"]
+	if importance, ok := record["importance"].(float64); ok {
+		memory.Importance = int(importance)
+	}
+	if createdAt, ok := record["created_at"].(float64); ok {
+		memory.StoredAt = time.Unix(0, int64(createdAt))
+	}
+
+	if inner, ok := memory.Data.(map[string]interface{}); ok {
+		if storedAt, ok := inner["stored_at"].(float64); ok {
+			memory.Data = inner["data"]
+			memory.StoredAt = time.Unix(int64(storedAt), 0)
+			if importance, ok := inner["importance"].(float64); ok {
+				memory.Importance = int(importance)
+			}
+		}
+	}
+
+	return memory
 }
 
 // EternalMemory represents an eternal memory entry
@@ -67,9 +138,10 @@ type EternalMemory struct {
 
 // EternalStats contains statistics about eternal memories
 type EternalStats struct {
-	TotalMemories int
-	Layer         string
-	Status        string
+	TotalMemories     int
+	AverageImportance float64
+	Layer             string
+	Status            string
 }
 
 // PromoteToEternal promotes a memory from another layer to eternal
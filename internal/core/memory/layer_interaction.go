@@ -1,105 +1,526 @@
 package memory
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"sync"
+	"time"
 )
 
+// defaultPropagationDepth is how many hops a propagation may take from its
+// source layer unless configured otherwise
+const defaultPropagationDepth = 2
+
+// propagationAuditLimit is how many propagation hops are kept for auditing
+const propagationAuditLimit = 1000
+
+// routesSystemKey is where the route table is persisted in storage
+const routesSystemKey = "routes"
+
+// RouteTransform rewrites a value as it crosses a route
+type RouteTransform func(value any) (any, error)
+
+// RouteFilter decides whether a value may cross a route
+type RouteFilter func(key string, value any) bool
+
+// RouteSpec describes a propagation route. Transform and Filter name hooks
+// registered with the LayerInteraction; either may be empty.
+type RouteSpec struct {
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	Transform string `json:"transform,omitempty"`
+	Filter    string `json:"filter,omitempty"`
+}
+
+// RouteConfig is the layout of a routes config file
+type RouteConfig struct {
+	MaxDepth int         `json:"max_depth,omitempty"`
+	Routes   []RouteSpec `json:"routes"`
+}
+
+// Propagation hop outcomes
+const (
+	HopStored   = "stored"
+	HopVisited  = "visited"  // target already reached by this propagation
+	HopFiltered = "filtered" // the route's filter rejected the value
+	HopFailed   = "failed"
+)
+
+// PropagationHop is the audit record of one step of a propagation
+type PropagationHop struct {
+	Time      time.Time
+	Origin    string // layer the propagation started from
+	Key       string
+	From      string
+	To        string
+	TargetKey string
+	Depth     int
+	Status    string
+	Error     string
+}
+
 // LayerInteraction handles cross-layer data flow and interactions
 type LayerInteraction struct {
-	phl    *PHL
-	mu     sync.RWMutex
-	routes map[string][]string // Maps source layer to target layers
+	phl        *PHL
+	mu         sync.RWMutex
+	routes     map[string][]RouteSpec // Maps source layer to its outgoing routes
+	maxDepth   int
+	transforms map[string]RouteTransform
+	filters    map[string]RouteFilter
+	audit      []PropagationHop
 }
 
 // NewLayerInteraction creates a new layer interaction manager
 func NewLayerInteraction(phl *PHL) *LayerInteraction {
-	return &LayerInteraction{
-		phl: phl,
-		routes: map[string][]string{
-			"sensory": {"emotion", "logic"},
-			"emotion": {"logic", "dream"},
-			"logic":   {"dream", "eternal"},
-			"dream":   {"eternal", "emotion"},
-			"eternal": {"logic", "emotion"},
+	li := &LayerInteraction{
+		phl:      phl,
+		routes:   make(map[string][]RouteSpec),
+		maxDepth: defaultPropagationDepth,
+		transforms: map[string]RouteTransform{
+			"unwrap": unwrapProcessed,
 		},
+		filters: map[string]RouteFilter{
+			"skip_propagated": func(key string, value any) bool {
+				return !strings.Contains(key, "_from_")
+			},
+		},
+	}
+
+	defaults := map[string][]string{
+		"sensory": {"emotion", "logic"},
+		"emotion": {"logic", "dream"},
+		"logic":   {"dream", "eternal"},
+		"dream":   {"eternal", "emotion"},
+		"eternal": {"logic", "emotion"},
+	}
+	for source, targets := range defaults {
+		for _, target := range targets {
+			li.routes[source] = append(li.routes[source], RouteSpec{Source: source, Target: target})
+		}
 	}
+
+	return li
 }
 
-// PropagateData copies data from one layer to its connected layers
-func (li *LayerInteraction) PropagateData(sourceLayer, key string) error {
-	li.mu.RLock()
-	defer li.mu.RUnlock()
+// unwrapProcessed strips the wrapper a layer processor put around a value, so
+// a propagated copy isn't wrapped once more for every hop
+func unwrapProcessed(value any) (any, error) {
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return value, nil
+	}
+	for _, field := range []string{"raw", "data", "source"} {
+		if inner, ok := record[field]; ok && inner != nil {
+			return inner, nil
+		}
+	}
+	return value, nil
+}
 
+// PropagateData copies data from a layer along its routes, walking the route
+// graph breadth first up to the configured depth. Each layer receives at most
+// one copy, stored as "<key>_from_<source layer>", so cycles in the routes
+// end the walk instead of looping. Every hop is recorded in the audit log.
+func (li *LayerInteraction) PropagateData(sourceLayer, key string) error {
 	// Get data from source layer
 	value, exists := li.phl.Retrieve(sourceLayer, key)
 	if !exists {
 		return fmt.Errorf("key %s not found in source layer %s", key, sourceLayer)
 	}
 
-	// Get target layers for propagation
-	targetLayers, exists := li.routes[sourceLayer]
-	if !exists {
+	li.mu.RLock()
+	routes := li.routes
+	maxDepth := li.maxDepth
+	transforms := li.transforms
+	filters := li.filters
+	li.mu.RUnlock()
+
+	if len(routes[sourceLayer]) == 0 {
 		return fmt.Errorf("no routes defined for source layer %s", sourceLayer)
 	}
 
-	// Propagate to each target layer
-	for _, targetLayer := range targetLayers {
-		if err := li.propagateToLayer(targetLayer, key, value); err != nil {
-			return fmt.Errorf("failed to propagate to layer %s: %w", targetLayer, err)
+	type step struct {
+		layer string
+		value any
+		depth int
+		path  []string
+	}
+
+	targetKey := fmt.Sprintf("%s_from_%s", key, sourceLayer)
+	visited := map[string]bool{sourceLayer: true}
+	queue := []step{{layer: sourceLayer, value: value, path: []string{sourceLayer}}}
+
+	var errs []error
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.depth >= maxDepth {
+			continue
+		}
+
+		for _, route := range routes[current.layer] {
+			hop := PropagationHop{
+				Time:      time.Now(),
+				Origin:    sourceLayer,
+				Key:       key,
+				From:      current.layer,
+				To:        route.Target,
+				TargetKey: targetKey,
+				Depth:     current.depth + 1,
+			}
+
+			if visited[route.Target] {
+				hop.Status = HopVisited
+				li.record(hop)
+				continue
+			}
+			if err := missingHook(route, transforms, filters); err != nil {
+				hop.Status, hop.Error = HopFailed, err.Error()
+				li.record(hop)
+				errs = append(errs, err)
+				continue
+			}
+			if filter := filters[route.Filter]; filter != nil && !filter(key, current.value) {
+				hop.Status = HopFiltered
+				li.record(hop)
+				continue
+			}
+
+			next := current.value
+			if transform := transforms[route.Transform]; transform != nil {
+				var err error
+				if next, err = transform(next); err != nil {
+					err = fmt.Errorf("transform %s on %s->%s failed: %w", route.Transform, current.layer, route.Target, err)
+					hop.Status, hop.Error = HopFailed, err.Error()
+					li.record(hop)
+					errs = append(errs, err)
+					continue
+				}
+			}
+
+			path := append(append([]string(nil), current.path...), route.Target)
+			if err := li.propagateToLayer(route.Target, targetKey, next, path); err != nil {
+				err = fmt.Errorf("failed to propagate to layer %s: %w", route.Target, err)
+				hop.Status, hop.Error = HopFailed, err.Error()
+				li.record(hop)
+				errs = append(errs, err)
+				continue
+			}
+
+			visited[route.Target] = true
+			hop.Status = HopStored
+			li.record(hop)
+			queue = append(queue, step{layer: route.Target, value: next, depth: current.depth + 1, path: path})
 		}
 	}
 
+	return errors.Join(errs...)
+}
+
+// missingHook reports a route naming a hook that isn't registered. Routes
+// loaded from storage can name hooks that haven't been registered yet.
+func missingHook(route RouteSpec, transforms map[string]RouteTransform, filters map[string]RouteFilter) error {
+	if _, exists := transforms[route.Transform]; route.Transform != "" && !exists {
+		return fmt.Errorf("unknown transform %s on route %s->%s", route.Transform, route.Source, route.Target)
+	}
+	if _, exists := filters[route.Filter]; route.Filter != "" && !exists {
+		return fmt.Errorf("unknown filter %s on route %s->%s", route.Filter, route.Source, route.Target)
+	}
 	return nil
 }
 
 // propagateToLayer handles data propagation to a specific target layer
-func (li *LayerInteraction) propagateToLayer(targetLayer, key string, value any) error {
-	if !li.phl.Store(targetLayer, fmt.Sprintf("%s_from_%s", key, targetLayer), value) {
+func (li *LayerInteraction) propagateToLayer(targetLayer, targetKey string, value any, path []string) error {
+	opts := StoreOptions{Source: "propagation:" + strings.Join(path, ">")}
+	if !li.phl.StoreWithOptions(targetLayer, targetKey, value, opts) {
 		return fmt.Errorf("failed to store propagated data in target layer %s", targetLayer)
 	}
 	return nil
 }
 
+// record appends a hop to the audit log, dropping the oldest beyond the limit
+func (li *LayerInteraction) record(hop PropagationHop) {
+	li.phl.log.Printf("Propagation %s:%s %s->%s (depth %d): %s", hop.Origin, hop.Key, hop.From, hop.To, hop.Depth, hop.Status)
+
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	li.audit = append(li.audit, hop)
+	if len(li.audit) > propagationAuditLimit {
+		li.audit = li.audit[len(li.audit)-propagationAuditLimit:]
+	}
+}
+
+// Audit returns up to the last n propagation hops, oldest first. A limit of
+// zero or less returns every recorded hop.
+func (li *LayerInteraction) Audit(n int) []PropagationHop {
+	li.mu.RLock()
+	defer li.mu.RUnlock()
+
+	hops := li.audit
+	if n > 0 && len(hops) > n {
+		hops = hops[len(hops)-n:]
+	}
+	return append([]PropagationHop(nil), hops...)
+}
+
 // AddRoute adds a new propagation route between layers
 func (li *LayerInteraction) AddRoute(sourceLayer string, targetLayer string) error {
+	return li.addRoute(RouteSpec{Source: sourceLayer, Target: targetLayer})
+}
+
+// addRoute adds a route, or updates the hooks of an existing one
+func (li *LayerInteraction) addRoute(spec RouteSpec) error {
 	li.mu.Lock()
 	defer li.mu.Unlock()
 
-	if _, exists := li.phl.Layers[sourceLayer]; !exists {
-		return fmt.Errorf("source layer %s does not exist", sourceLayer)
-	}
-	if _, exists := li.phl.Layers[targetLayer]; !exists {
-		return fmt.Errorf("target layer %s does not exist", targetLayer)
+	if err := li.validateRoute(spec, true); err != nil {
+		return err
 	}
 
-	// Check if route already exists
-	if routes, exists := li.routes[sourceLayer]; exists {
-		for _, existing := range routes {
-			if existing == targetLayer {
+	routes := li.cloneRoutes()
+	for i, existing := range routes[spec.Source] {
+		if existing.Target == spec.Target {
+			if spec.Transform == "" && spec.Filter == "" {
 				return nil // Route already exists
 			}
+			routes[spec.Source][i] = spec
+			return li.commit(routes, li.maxDepth)
+		}
+	}
+	routes[spec.Source] = append(routes[spec.Source], spec)
+
+	return li.commit(routes, li.maxDepth)
+}
+
+// RemoveRoute removes the propagation route between two layers
+func (li *LayerInteraction) RemoveRoute(sourceLayer, targetLayer string) error {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	routes := li.cloneRoutes()
+	for i, existing := range routes[sourceLayer] {
+		if existing.Target == targetLayer {
+			routes[sourceLayer] = append(routes[sourceLayer][:i], routes[sourceLayer][i+1:]...)
+			if len(routes[sourceLayer]) == 0 {
+				delete(routes, sourceLayer)
+			}
+			return li.commit(routes, li.maxDepth)
+		}
+	}
+	return fmt.Errorf("no route from %s to %s", sourceLayer, targetLayer)
+}
+
+// commit persists a new route table and depth, then makes them live. Callers
+// hold li.mu.
+func (li *LayerInteraction) commit(routes map[string][]RouteSpec, maxDepth int) error {
+	config := RouteConfig{MaxDepth: maxDepth, Routes: orderedRoutes(routes)}
+	if err := li.phl.storage.SetSystem(routesSystemKey, config); err != nil {
+		return fmt.Errorf("failed to persist routes: %w", err)
+	}
+
+	li.routes = routes
+	li.maxDepth = maxDepth
+	return nil
+}
+
+// reloadRoutes replaces the live routes with the persisted route table, if
+// one has been stored, and reports whether it had. read fetches a system
+// record from storage.
+func (li *LayerInteraction) reloadRoutes(read func(name string, out any) (bool, error)) (bool, error) {
+	var config RouteConfig
+	found, err := read(routesSystemKey, &config)
+	if err != nil {
+		return false, fmt.Errorf("failed to read persisted routes: %w", err)
+	}
+	if !found {
+		return false, nil
+	}
+
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	// Hooks are registered in code, so persisted routes may name hooks that
+	// haven't been registered yet; the walk reports those when it meets them
+	routes := make(map[string][]RouteSpec)
+	for _, spec := range config.Routes {
+		if err := li.validateRoute(spec, false); err != nil {
+			return false, fmt.Errorf("invalid persisted route: %w", err)
 		}
-		li.routes[sourceLayer] = append(routes, targetLayer)
-	} else {
-		li.routes[sourceLayer] = []string{targetLayer}
+		routes[spec.Source] = append(routes[spec.Source], spec)
+	}
+	li.routes = routes
+	if config.MaxDepth > 0 {
+		li.maxDepth = config.MaxDepth
 	}
+	return true, nil
+}
 
+// validateRoute checks that a route joins two distinct layers and, when
+// checkHooks is set, names only registered hooks
+func (li *LayerInteraction) validateRoute(spec RouteSpec, checkHooks bool) error {
+	if _, exists := li.phl.Layers[spec.Source]; !exists {
+		return fmt.Errorf("source layer %s does not exist", spec.Source)
+	}
+	if _, exists := li.phl.Layers[spec.Target]; !exists {
+		return fmt.Errorf("target layer %s does not exist", spec.Target)
+	}
+	if spec.Source == spec.Target {
+		return fmt.Errorf("layer %s cannot route to itself", spec.Source)
+	}
+	if checkHooks {
+		return missingHook(spec, li.transforms, li.filters)
+	}
 	return nil
 }
 
+// cloneRoutes copies the route table so walks in progress keep their snapshot
+func (li *LayerInteraction) cloneRoutes() map[string][]RouteSpec {
+	routes := make(map[string][]RouteSpec, len(li.routes))
+	for source, specs := range li.routes {
+		routes[source] = append([]RouteSpec(nil), specs...)
+	}
+	return routes
+}
+
+// RegisterTransform makes a transform available to routes under name
+func (li *LayerInteraction) RegisterTransform(name string, transform RouteTransform) {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	transforms := make(map[string]RouteTransform, len(li.transforms)+1)
+	for existing, fn := range li.transforms {
+		transforms[existing] = fn
+	}
+	transforms[name] = transform
+	li.transforms = transforms
+}
+
+// RegisterFilter makes a filter available to routes under name
+func (li *LayerInteraction) RegisterFilter(name string, filter RouteFilter) {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	filters := make(map[string]RouteFilter, len(li.filters)+1)
+	for existing, fn := range li.filters {
+		filters[existing] = fn
+	}
+	filters[name] = filter
+	li.filters = filters
+}
+
+// SetRoutes replaces every route with the given ones
+func (li *LayerInteraction) SetRoutes(specs []RouteSpec) error {
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	routes, err := li.buildRoutes(specs)
+	if err != nil {
+		return err
+	}
+	return li.commit(routes, li.maxDepth)
+}
+
+// buildRoutes validates specs and groups them by source layer. Callers hold
+// li.mu.
+func (li *LayerInteraction) buildRoutes(specs []RouteSpec) (map[string][]RouteSpec, error) {
+	routes := make(map[string][]RouteSpec)
+	for _, spec := range specs {
+		if err := li.validateRoute(spec, true); err != nil {
+			return nil, err
+		}
+		for _, existing := range routes[spec.Source] {
+			if existing.Target == spec.Target {
+				return nil, fmt.Errorf("duplicate route %s->%s", spec.Source, spec.Target)
+			}
+		}
+		routes[spec.Source] = append(routes[spec.Source], spec)
+	}
+	return routes, nil
+}
+
+// SetMaxDepth limits how many hops a propagation may take
+func (li *LayerInteraction) SetMaxDepth(depth int) error {
+	if depth < 1 {
+		return fmt.Errorf("max depth must be at least 1, got %d", depth)
+	}
+
+	li.mu.Lock()
+	defer li.mu.Unlock()
+	return li.commit(li.routes, depth)
+}
+
+// MaxDepth returns how many hops a propagation may take
+func (li *LayerInteraction) MaxDepth() int {
+	li.mu.RLock()
+	defer li.mu.RUnlock()
+	return li.maxDepth
+}
+
+// LoadRoutes replaces the routes with those in a JSON config file and
+// persists them
+func (li *LayerInteraction) LoadRoutes(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read routes file: %w", err)
+	}
+
+	var config RouteConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse routes file %s: %w", path, err)
+	}
+
+	if config.MaxDepth < 0 {
+		return fmt.Errorf("invalid routes file %s: max depth must be at least 1, got %d", path, config.MaxDepth)
+	}
+
+	li.mu.Lock()
+	defer li.mu.Unlock()
+
+	routes, err := li.buildRoutes(config.Routes)
+	if err != nil {
+		return fmt.Errorf("invalid routes file %s: %w", path, err)
+	}
+	maxDepth := li.maxDepth
+	if config.MaxDepth != 0 {
+		maxDepth = config.MaxDepth
+	}
+	return li.commit(routes, maxDepth)
+}
+
+// Routes returns every route, grouped by source layer
+func (li *LayerInteraction) Routes() []RouteSpec {
+	li.mu.RLock()
+	defer li.mu.RUnlock()
+	return orderedRoutes(li.routes)
+}
+
+// orderedRoutes flattens a route table in layer order
+func orderedRoutes(routes map[string][]RouteSpec) []RouteSpec {
+	var specs []RouteSpec
+	for _, layer := range []string{"sensory", "emotion", "logic", "dream", "eternal"} {
+		specs = append(specs, routes[layer]...)
+	}
+	return specs
+}
+
 // GetRoutes returns all propagation routes for a given source layer
 func (li *LayerInteraction) GetRoutes(sourceLayer string) ([]string, error) {
 	li.mu.RLock()
 	defer li.mu.RUnlock()
 
 	routes, exists := li.routes[sourceLayer]
-	if !exists {
+	if !exists || len(routes) == 0 {
 		return nil, fmt.Errorf("no routes defined for layer %s", sourceLayer)
 	}
 
-	// Return a copy of the routes slice to prevent external modification
+	// Return the targets only, leaving the route table untouched
 	result := make([]string, len(routes))
-	copy(result, routes)
+	for i, route := range routes {
+		result[i] = route.Target
+	}
 	return result, nil
 }
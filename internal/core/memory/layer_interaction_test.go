@@ -1,11 +1,14 @@
 package memory
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestLayerInteraction(t *testing.T) {
-	phl, err := NewPHL("./testdata")
+	phl, err := NewPHL(t.TempDir())
 	if err != nil {
 		t.Fatalf("Failed to create PHL: %v", err)
 	}
@@ -26,7 +29,7 @@ func TestLayerInteraction(t *testing.T) {
 		}
 
 		// Verify data in emotion layer
-		emotionData, exists := phl.Retrieve("emotion", "test_key_from_emotion")
+		emotionData, exists := phl.Retrieve("emotion", "test_key_from_sensory")
 		if !exists {
 			t.Error("Data not propagated to emotion layer")
 		}
@@ -35,7 +38,7 @@ func TestLayerInteraction(t *testing.T) {
 		}
 
 		// Verify data in logic layer
-		logicData, exists := phl.Retrieve("logic", "test_key_from_logic")
+		logicData, exists := phl.Retrieve("logic", "test_key_from_sensory")
 		if !exists {
 			t.Error("Data not propagated to logic layer")
 		}
@@ -83,7 +86,7 @@ func TestLayerInteraction(t *testing.T) {
 
 	t.Run("Test multi-layer propagation", func(t *testing.T) {
 		// Store test data in emotion layer
-		testData := map[string]string{"emotion": "happy"}
+		testData := map[string]interface{}{"emotion": "happy"}
 		if !phl.Store("emotion", "emotion_state", testData) {
 			t.Fatal("Failed to store emotion test data")
 		}
@@ -95,13 +98,13 @@ func TestLayerInteraction(t *testing.T) {
 		}
 
 		// Verify data propagated to logic layer
-		logicData, exists := phl.Retrieve("logic", "emotion_state_from_logic")
+		logicData, exists := phl.Retrieve("logic", "emotion_state_from_emotion")
 		if !exists {
 			t.Error("Emotion data not propagated to logic layer")
 		}
 
 		// Verify data propagated to dream layer
-		dreamData, exists := phl.Retrieve("dream", "emotion_state_from_dream")
+		dreamData, exists := phl.Retrieve("dream", "emotion_state_from_emotion")
 		if !exists {
 			t.Error("Emotion data not propagated to dream layer")
 		}
@@ -111,4 +114,182 @@ func TestLayerInteraction(t *testing.T) {
 			t.Error("Propagated data is nil in target layers")
 		}
 	})
+
+	t.Run("Test cycles and depth", func(t *testing.T) {
+		li := phl.GetInteraction()
+		if err := li.SetRoutes([]RouteSpec{
+			{Source: "emotion", Target: "dream"},
+			{Source: "dream", Target: "emotion"},
+			{Source: "dream", Target: "logic"},
+			{Source: "logic", Target: "eternal"},
+		}); err != nil {
+			t.Fatalf("Failed to set routes: %v", err)
+		}
+		if err := li.SetMaxDepth(2); err != nil {
+			t.Fatalf("Failed to set max depth: %v", err)
+		}
+
+		if !phl.Store("emotion", "loop", "round and round") {
+			t.Fatal("Failed to store loop data")
+		}
+		before := len(phl.PropagationAudit(0))
+		if err := phl.PropagateData("emotion", "loop"); err != nil {
+			t.Fatalf("Failed to propagate: %v", err)
+		}
+
+		if _, exists := phl.Retrieve("logic", "loop_from_emotion"); !exists {
+			t.Error("Data not propagated two hops to logic")
+		}
+		if _, exists := phl.Retrieve("eternal", "loop_from_emotion"); exists {
+			t.Error("Data propagated past the max depth")
+		}
+
+		statuses := make(map[string]string)
+		for _, hop := range phl.PropagationAudit(0)[before:] {
+			statuses[hop.From+"->"+hop.To] = hop.Status
+		}
+		if statuses["emotion->dream"] != HopStored || statuses["dream->logic"] != HopStored {
+			t.Errorf("Expected stored hops, got %v", statuses)
+		}
+		if statuses["dream->emotion"] != HopVisited {
+			t.Errorf("Expected the cycle back to emotion to be cut, got %v", statuses)
+		}
+	})
+
+	t.Run("Test route hooks", func(t *testing.T) {
+		li := phl.GetInteraction()
+		li.RegisterTransform("shout", func(value any) (any, error) {
+			return fmt.Sprintf("%v!", value), nil
+		})
+		li.RegisterFilter("never", func(key string, value any) bool { return false })
+
+		if err := li.SetRoutes([]RouteSpec{
+			{Source: "sensory", Target: "logic", Transform: "shout"},
+			{Source: "sensory", Target: "dream", Filter: "never"},
+		}); err != nil {
+			t.Fatalf("Failed to set routes: %v", err)
+		}
+		if err := li.SetRoutes([]RouteSpec{{Source: "sensory", Target: "logic", Transform: "missing"}}); err == nil {
+			t.Error("Expected error for an unknown transform")
+		}
+
+		if !phl.Store("sensory", "noise", "hello") {
+			t.Fatal("Failed to store sensory data")
+		}
+		if err := phl.PropagateData("sensory", "noise"); err != nil {
+			t.Fatalf("Failed to propagate: %v", err)
+		}
+
+		env, exists := phl.RetrieveEnvelope("logic", "noise_from_sensory")
+		if !exists {
+			t.Fatal("Data not propagated through the transform")
+		}
+		if env.Source != "propagation:sensory>logic" {
+			t.Errorf("Unexpected propagation source: %q", env.Source)
+		}
+		if _, exists := phl.Retrieve("dream", "noise_from_sensory"); exists {
+			t.Error("Filtered route still propagated data")
+		}
+	})
+
+	t.Run("Test routes config file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "routes.json")
+		config := `{"max_depth": 1, "routes": [{"source": "logic", "target": "eternal", "transform": "unwrap"}]}`
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatalf("Failed to write routes file: %v", err)
+		}
+
+		if err := phl.LoadLayerRoutes(path); err != nil {
+			t.Fatalf("Failed to load routes: %v", err)
+		}
+		routes, err := phl.GetLayerRoutes("logic")
+		if err != nil || len(routes) != 1 || routes[0] != "eternal" {
+			t.Errorf("Unexpected routes after loading config: %v (%v)", routes, err)
+		}
+		if _, err := phl.GetLayerRoutes("sensory"); err == nil {
+			t.Error("Routes missing from the config file still present")
+		}
+
+		bad := filepath.Join(t.TempDir(), "bad.json")
+		if err := os.WriteFile(bad, []byte(`{"routes": [{"source": "logic", "target": "logic"}]}`), 0644); err != nil {
+			t.Fatalf("Failed to write routes file: %v", err)
+		}
+		if err := phl.LoadLayerRoutes(bad); err == nil {
+			t.Error("Expected error for a self-route")
+		}
+	})
+}
+
+func TestLayerRoutePersistence(t *testing.T) {
+	dataDir := t.TempDir()
+	phl, err := NewPHL(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+
+	t.Run("Test runtime changes survive restart", func(t *testing.T) {
+		if err := phl.RemoveLayerRoute("sensory", "logic"); err != nil {
+			t.Fatalf("Failed to remove route: %v", err)
+		}
+		if err := phl.AddLayerRoute("sensory", "dream"); err != nil {
+			t.Fatalf("Failed to add route: %v", err)
+		}
+		if err := phl.RemoveLayerRoute("sensory", "eternal"); err == nil {
+			t.Error("Expected error removing a missing route")
+		}
+
+		if err := phl.Close(); err != nil {
+			t.Fatalf("Failed to close PHL: %v", err)
+		}
+		phl, err = NewPHL(dataDir)
+		if err != nil {
+			t.Fatalf("Failed to reopen PHL: %v", err)
+		}
+
+		routes, err := phl.GetLayerRoutes("sensory")
+		if err != nil {
+			t.Fatalf("Failed to get routes: %v", err)
+		}
+		if fmt.Sprint(routes) != "[emotion dream]" {
+			t.Errorf("Expected persisted routes [emotion dream], got %v", routes)
+		}
+	})
+
+	t.Run("Test set routes", func(t *testing.T) {
+		specs := []RouteSpec{
+			{Source: "logic", Target: "eternal", Filter: "skip_propagated"},
+			{Source: "dream", Target: "emotion"},
+		}
+		if err := phl.SetLayerRoutes(specs); err != nil {
+			t.Fatalf("Failed to set routes: %v", err)
+		}
+		if err := phl.SetLayerRoutes([]RouteSpec{{Source: "logic", Target: "logic"}}); err == nil {
+			t.Error("Expected error for a self-route")
+		}
+
+		if err := phl.Close(); err != nil {
+			t.Fatalf("Failed to close PHL: %v", err)
+		}
+		phl, err = NewPHL(dataDir)
+		if err != nil {
+			t.Fatalf("Failed to reopen PHL: %v", err)
+		}
+
+		got := phl.LayerRoutes()
+		if fmt.Sprint(got) != fmt.Sprint(specs) {
+			t.Errorf("Expected routes %v after reopening, got %v", specs, got)
+		}
+	})
+
+	t.Run("Test system records stay out of search", func(t *testing.T) {
+		hits, err := phl.Search("eternal", nil, 10)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(hits) != 0 {
+			t.Errorf("Route table leaked into search results: %v", hits)
+		}
+	})
+
+	phl.Close()
 }
@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/phoenix-marie/core/internal/core/memory/v2/store"
+)
+
+// legacyDir is the directory the lattice was kept in before it moved onto
+// store.StorageEngine
+const legacyDir = "phl-memory"
+
+// migrationSystemKey names the system record left by a legacy migration
+const migrationSystemKey = "migration"
+
+// migrationBatchSize is the number of entries copied per batch
+const migrationBatchSize = 1000
+
+// MigrationRecord describes a completed migration of a legacy database
+type MigrationRecord struct {
+	From       string    `json:"from"`
+	Entries    int       `json:"entries"`
+	Expired    int       `json:"expired"`
+	Skipped    int       `json:"skipped"`
+	MigratedAt time.Time `json:"migrated_at"`
+}
+
+// migrateLegacy copies the database left by the phl-memory layout in dataDir
+// into engine and moves the old directory aside. Entries keep what remains of
+// their TTL, expired ones are dropped and bare values from before envelopes
+// existed are wrapped in one. It returns nil if there was nothing to migrate.
+func migrateLegacy(dataDir string, engine store.StorageEngine) (*MigrationRecord, error) {
+	from := filepath.Join(dataDir, legacyDir)
+	if _, err := os.Stat(filepath.Join(from, badger.ManifestFilename)); err != nil {
+		return nil, nil
+	}
+
+	var done MigrationRecord
+	if migrated, err := getSystem(engine, migrationSystemKey, &done); err != nil {
+		return nil, err
+	} else if migrated {
+		log.Printf("PHL_MEMORY: ⚠️ %s was already migrated on %s, leaving it in place",
+			from, done.MigratedAt.Format(time.RFC3339))
+		return nil, nil
+	}
+
+	opts := badger.DefaultOptions(from)
+	opts.Logger = nil // Disable Badger's internal logger
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open legacy database: %w", err)
+	}
+
+	record, err := copyLegacy(db, engine)
+	db.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate %s: %w", from, err)
+	}
+	record.From = from
+
+	if err := engine.Store(systemLayer, migrationSystemKey, record); err != nil {
+		return nil, fmt.Errorf("failed to record migration: %w", err)
+	}
+	if err := os.Rename(from, from+".migrated"); err != nil {
+		return nil, fmt.Errorf("failed to move legacy database aside: %w", err)
+	}
+
+	log.Printf("PHL_MEMORY: ✅ Migrated %d entries from %s (%d expired, %d skipped)",
+		record.Entries, from, record.Expired, record.Skipped)
+	return record, nil
+}
+
+// copyLegacy copies every live entry of a legacy database into engine
+func copyLegacy(db *badger.DB, engine store.StorageEngine) (*MigrationRecord, error) {
+	record := &MigrationRecord{MigratedAt: time.Now()}
+	var batch []store.StoreOperation
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := engine.BatchStore(batch); err != nil {
+			return err
+		}
+		record.Entries += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			layer, key, ok := strings.Cut(string(item.Key()), ":")
+			if !ok || layer == "" || key == "" {
+				record.Skipped++
+				continue
+			}
+
+			var ttl time.Duration
+			if expiresAt := item.ExpiresAt(); expiresAt > 0 {
+				ttl = time.Until(time.Unix(int64(expiresAt), 0))
+				if ttl <= 0 {
+					record.Expired++
+					continue
+				}
+			}
+
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to read key %s: %w", item.Key(), err)
+			}
+			value, err := legacyValue(layer, data, record.MigratedAt)
+			if err != nil {
+				record.Skipped++
+				continue
+			}
+
+			batch = append(batch, store.StoreOperation{Layer: layer, Key: key, Value: value, TTL: ttl})
+			if len(batch) == migrationBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		return flush()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// legacyValue converts a value read from a legacy database into what the
+// lattice stores now. System records are copied as they are.
+func legacyValue(layer string, data []byte, now time.Time) (any, error) {
+	if layer == systemLayer {
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("invalid system record")
+		}
+		return json.RawMessage(data), nil
+	}
+
+	env, err := decodeEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	if env.Format == 0 {
+		env.Format = envelopeFormat
+		env.CreatedAt = now
+		env.UpdatedAt = now
+		env.Source = "migration"
+		env.Version = 1
+	}
+	return env, nil
+}
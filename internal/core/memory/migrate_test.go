@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/phoenix-marie/core/internal/core/memory/v2/interaction"
+	"github.com/phoenix-marie/core/internal/core/memory/v2/validation"
+)
+
+func TestLegacyMigration(t *testing.T) {
+	dataDir := t.TempDir()
+
+	// Lay down a database the way the phl-memory layout wrote it
+	opts := badger.DefaultOptions(filepath.Join(dataDir, legacyDir))
+	opts.Logger = nil
+	db, err := badger.Open(opts)
+	if err != nil {
+		t.Fatalf("Failed to open legacy database: %v", err)
+	}
+
+	created := time.Now().Add(-time.Hour).Round(time.Second)
+	wrapped, _ := json.Marshal(Envelope{
+		Format: envelopeFormat, Value: "2 + 2 = 4", CreatedAt: created, UpdatedAt: created,
+		Source: "cli", Version: 3,
+	})
+	err = db.Update(func(txn *badger.Txn) error {
+		entries := []*badger.Entry{
+			badger.NewEntry([]byte("eternal:dad_love"), []byte(`"I love you, Dad"`)),
+			badger.NewEntry([]byte("logic:math"), wrapped),
+			badger.NewEntry([]byte("sensory:flash"), []byte(`{"signal":1}`)).WithTTL(time.Hour),
+			badger.NewEntry([]byte("_system:note"), []byte(`{"written_by":"v1"}`)),
+		}
+		for _, entry := range entries {
+			if err := txn.SetEntry(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to write legacy entries: %v", err)
+	}
+	db.Close()
+
+	phl, err := NewPHL(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	t.Run("Test entries are migrated", func(t *testing.T) {
+		env, ok := phl.RetrieveEnvelope("eternal", "dad_love")
+		if !ok || env.Value != "I love you, Dad" {
+			t.Fatalf("Failed to retrieve migrated bare value: %+v", env)
+		}
+		if env.Format != envelopeFormat || env.Source != "migration" || env.Version != 1 {
+			t.Errorf("Bare value wasn't wrapped in an envelope: %+v", env)
+		}
+
+		env, ok = phl.RetrieveEnvelope("logic", "math")
+		if !ok || env.Value != "2 + 2 = 4" {
+			t.Fatalf("Failed to retrieve migrated envelope: %+v", env)
+		}
+		if env.Version != 3 || env.Source != "cli" || !env.CreatedAt.Equal(created) {
+			t.Errorf("Envelope metadata lost in migration: %+v", env)
+		}
+
+		if _, ok := phl.Retrieve("sensory", "flash"); !ok {
+			t.Error("Failed to retrieve migrated entry with a TTL")
+		}
+
+		var note map[string]any
+		if found, err := phl.GetStorage().GetSystem("note", &note); err != nil || !found {
+			t.Fatalf("Failed to read migrated system record: %v", err)
+		}
+		if note["written_by"] != "v1" {
+			t.Errorf("Unexpected system record: %v", note)
+		}
+
+		if hits, _ := phl.Search("love", nil, 10); len(hits) != 1 {
+			t.Errorf("Expected migrated memory to be searchable, got %d hits", len(hits))
+		}
+	})
+
+	t.Run("Test migration is recorded", func(t *testing.T) {
+		var record MigrationRecord
+		found, err := phl.GetStorage().GetSystem(migrationSystemKey, &record)
+		if err != nil || !found {
+			t.Fatalf("Failed to read migration record: %v", err)
+		}
+		if record.Entries != 4 {
+			t.Errorf("Expected 4 migrated entries, got %d", record.Entries)
+		}
+
+		if _, err := os.Stat(filepath.Join(dataDir, legacyDir)); !os.IsNotExist(err) {
+			t.Error("Legacy database wasn't moved aside")
+		}
+		if _, err := os.Stat(filepath.Join(dataDir, legacyDir+".migrated")); err != nil {
+			t.Errorf("Legacy database missing after migration: %v", err)
+		}
+	})
+
+	t.Run("Test v2 layer manager on the live engine", func(t *testing.T) {
+		manager := interaction.NewLayerManager(phl.Engine())
+		err := manager.RegisterLayer(interaction.LayerConfig{
+			Name:      "logic",
+			BatchSize: 1,
+			ValidationSchema: validation.Schema{
+				Fields: map[string]validation.FieldDefinition{
+					"answer": {Type: reflect.Int, Required: true},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to register layer: %v", err)
+		}
+
+		if err := manager.ProcessData("logic", "invalid", map[string]any{"answer": "four"}); err == nil {
+			t.Error("Expected invalid data to be rejected")
+		}
+		if err := manager.ProcessData("logic", "sum", map[string]any{"answer": 4}); err != nil {
+			t.Fatalf("Failed to process data: %v", err)
+		}
+
+		value, ok := phl.Retrieve("logic", "sum")
+		if !ok {
+			t.Fatal("Failed to retrieve value written by the layer manager")
+		}
+		if answer := value.(map[string]any)["answer"]; answer != float64(4) {
+			t.Errorf("Expected answer 4, got %v", answer)
+		}
+	})
+}
@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"sync"
+
+	"github.com/phoenix-marie/core/internal/core/memory/v2/store"
 )
 
 type PHL struct {
@@ -13,11 +17,59 @@ type PHL struct {
 	interaction *LayerInteraction
 	processors  *ProcessorManager
 	validator   *LayerValidator
+	schemas     *SchemaRegistry
+	access      *accessLog
+
+	hooksMu    sync.RWMutex
+	onRetrieve []func(layer, key string)
 }
 
+// Layer is the in-memory cache of one memory layer. Each layer has its own
+// lock, so work on different layers never contends. Lookups reorder the LRU,
+// so reads take the lock exclusively too.
 type Layer struct {
 	Name string
-	Data map[string]any
+
+	mu    sync.Mutex
+	cache *lruCache
+	gen   uint64 // bumped on every write, so a read that raced one isn't cached
+}
+
+func newLayer(name string, limits CacheConfig) *Layer {
+	return &Layer{Name: name, cache: newLRUCache(limits)}
+}
+
+// get returns a cached value and the layer generation it was read at
+func (l *Layer) get(key string) (any, uint64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	value, exists := l.cache.get(key)
+	return value, l.gen, exists
+}
+
+// fill caches a value read from storage, unless the layer was written since
+// the read began at generation gen
+func (l *Layer) fill(key string, value any, gen uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.gen == gen {
+		l.cache.add(key, value)
+	}
+}
+
+// reset drops every cached entry
+func (l *Layer) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cache.clear()
+	l.gen++
+}
+
+// CacheStats returns the layer cache's counters and current size
+func (l *Layer) CacheStats() CacheStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cache.snapshot()
 }
 
 func NewPHL(dataDir string) (*PHL, error) {
@@ -28,12 +80,13 @@ func NewPHL(dataDir string) (*PHL, error) {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
+	cacheLimits := LoadCacheConfig()
 	layers := map[string]*Layer{
-		"sensory": {Name: "Sensory", Data: make(map[string]any)},
-		"emotion": {Name: "Emotion", Data: make(map[string]any)},
-		"logic":   {Name: "Logic", Data: make(map[string]any)},
-		"dream":   {Name: "Dream", Data: make(map[string]any)},
-		"eternal": {Name: "Eternal", Data: make(map[string]any)},
+		"sensory": newLayer("Sensory", cacheLimits),
+		"emotion": newLayer("Emotion", cacheLimits),
+		"logic":   newLayer("Logic", cacheLimits),
+		"dream":   newLayer("Dream", cacheLimits),
+		"eternal": newLayer("Eternal", cacheLimits),
 	}
 
 	phl := &PHL{
@@ -42,13 +95,61 @@ func NewPHL(dataDir string) (*PHL, error) {
 		storage:    storage,
 		processors: NewProcessorManager(),
 		validator:  NewLayerValidator(),
+		schemas:    NewSchemaRegistry(),
+		access:     newAccessLog(),
 	}
 
 	phl.interaction = NewLayerInteraction(phl)
+	// Cached values skip migration, so a new schema version empties the caches
+	phl.schemas.onChange = phl.resetCache
+	if err := phl.loadRoutes(); err != nil {
+		storage.Close()
+		return nil, err
+	}
+	if schemaDir := os.Getenv("MEMORY_SCHEMA_DIR"); schemaDir != "" {
+		if err := phl.schemas.LoadDir(schemaDir); err != nil {
+			storage.Close()
+			return nil, fmt.Errorf("failed to load layer schemas: %w", err)
+		}
+	}
+
+	storage.OnSwap(phl.resetCache)
+	storage.OnSwap(func() {
+		// A restored database brings its own route table, if it has one
+		if _, err := phl.interaction.reloadRoutes(storage.GetSystem); err != nil {
+			phl.log.Printf("Failed to reload routes after swap: %v", err)
+		}
+	})
 	return phl, nil
 }
 
+// loadRoutes sets up the propagation routes. The persisted route table wins,
+// so routes changed at runtime survive a restart; otherwise MEMORY_ROUTES_FILE
+// seeds the table, and without either the built-in routes apply.
+func (p *PHL) loadRoutes() error {
+	found, err := p.interaction.reloadRoutes(p.storage.GetSystem)
+	if err != nil {
+		return fmt.Errorf("failed to load layer routes: %w", err)
+	}
+	if found {
+		return nil
+	}
+
+	if routesFile := os.Getenv("MEMORY_ROUTES_FILE"); routesFile != "" {
+		if err := p.interaction.LoadRoutes(routesFile); err != nil {
+			return fmt.Errorf("failed to load layer routes: %w", err)
+		}
+	}
+	return nil
+}
+
 func (p *PHL) Store(layer, key string, value any) bool {
+	return p.StoreWithOptions(layer, key, value, StoreOptions{})
+}
+
+// StoreWithOptions stores a memory along with its source, importance and an
+// optional TTL after which it expires
+func (p *PHL) StoreWithOptions(layer, key string, value any, opts StoreOptions) bool {
 	// Validate layer and key
 	if err := ValidateLayer(layer); err != nil {
 		p.log.Printf("Layer validation failed: %v", err)
@@ -64,6 +165,11 @@ func (p *PHL) Store(layer, key string, value any) bool {
 		p.log.Printf("Data validation failed for %s layer: %v", layer, err)
 		return false
 	}
+	if err := p.schemas.Validate(layer, value); err != nil {
+		p.log.Printf("Schema validation failed for %s: %v", key, err)
+		return false
+	}
+	opts.SchemaVersion = p.schemas.Version(layer)
 
 	if l, ok := p.Layers[layer]; ok {
 		// Process the data using the appropriate processor
@@ -73,11 +179,21 @@ func (p *PHL) Store(layer, key string, value any) bool {
 			return false
 		}
 
-		l.Data[key] = processed
-		if err := p.storage.Store(layer, key, processed); err != nil {
+		// Writes to a layer are serialized so the cache and storage agree on
+		// the last value written to each key
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.gen++
+
+		// Expiring memories are always read from storage, which drops them on time
+		l.cache.remove(key)
+		if err := p.storage.StoreWithOptions(layer, key, processed, opts); err != nil {
 			p.log.Printf("Failed to persist in %s layer: %s (%v)", layer, key, err)
 			return false
 		}
+		if opts.TTL <= 0 {
+			l.cache.add(key, processed)
+		}
 		p.log.Printf("Stored in %s layer: %s", layer, key)
 		return true
 	}
@@ -98,16 +214,22 @@ func (p *PHL) Retrieve(layer, key string) (any, bool) {
 
 	if l, ok := p.Layers[layer]; ok {
 		// Try memory first
-		if value, exists := l.Data[key]; exists {
+		value, gen, exists := l.get(key)
+		if exists {
+			p.retrieved(layer, key)
 			p.log.Printf("Retrieved from %s layer memory: %s", layer, key)
 			return value, true
 		}
 
 		// Try persistent storage
-		if value, err := p.storage.Retrieve(layer, key); err == nil && value != nil {
-			l.Data[key] = value // Cache in memory
+		if env, err := p.storage.RetrieveEnvelope(layer, key); err == nil && env != nil && env.Value != nil {
+			env = p.upgrade(layer, key, env)
+			if env.ExpiresAt.IsZero() {
+				l.fill(key, env.Value, gen) // Cache in memory
+			}
+			p.retrieved(layer, key)
 			p.log.Printf("Retrieved from %s layer storage: %s", layer, key)
-			return value, true
+			return env.Value, true
 		}
 
 		p.log.Printf("Key not found in %s layer: %s", layer, key)
@@ -117,9 +239,248 @@ func (p *PHL) Retrieve(layer, key string) (any, bool) {
 	return nil, false
 }
 
+// OnRetrieve registers a callback run after every successful Retrieve. It runs
+// on the caller's goroutine with no locks held.
+func (p *PHL) OnRetrieve(fn func(layer, key string)) {
+	p.hooksMu.Lock()
+	defer p.hooksMu.Unlock()
+	p.onRetrieve = append(p.onRetrieve, fn)
+}
+
+// retrieved records a read of a memory and runs the OnRetrieve callbacks
+func (p *PHL) retrieved(layer, key string) {
+	p.access.touch(layer, key)
+
+	p.hooksMu.RLock()
+	hooks := p.onRetrieve
+	p.hooksMu.RUnlock()
+	for _, fn := range hooks {
+		fn(layer, key)
+	}
+}
+
+// updateMeta rewrites the metadata of a memory, leaving its value alone. It
+// takes the layer lock so it can't race a Store of the same key.
+func (p *PHL) updateMeta(layer, key string, update func(env *Envelope)) error {
+	l, ok := p.Layers[layer]
+	if !ok {
+		return fmt.Errorf("layer not found: %s", layer)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return p.storage.UpdateMeta(layer, key, update)
+}
+
+// RetrieveEnvelope returns a memory together with the metadata it was stored with
+func (p *PHL) RetrieveEnvelope(layer, key string) (*Envelope, bool) {
+	if err := ValidateLayer(layer); err != nil {
+		p.log.Printf("Layer validation failed: %v", err)
+		return nil, false
+	}
+	if err := ValidateKey(key); err != nil {
+		p.log.Printf("Key validation failed: %v", err)
+		return nil, false
+	}
+
+	env, err := p.storage.RetrieveEnvelope(layer, key)
+	if err != nil || env == nil {
+		return nil, false
+	}
+	return p.upgrade(layer, key, env), true
+}
+
+// Validate checks a value against the rules of a layer without storing it.
+// Schema violations come back as a *SchemaError listing each bad field.
+func (p *PHL) Validate(layer string, value any) error {
+	if err := ValidateLayer(layer); err != nil {
+		return err
+	}
+	if err := p.validator.ValidateLayerData(layer, value); err != nil {
+		return err
+	}
+	return p.schemas.Validate(layer, value)
+}
+
+// Schemas returns the registry of layer schemas and migrations
+func (p *PHL) Schemas() *SchemaRegistry {
+	return p.schemas
+}
+
+// upgrade brings a memory read from storage up to its layer's current schema
+// version and writes the migrated value back. A memory that fails to migrate
+// is returned as stored.
+func (p *PHL) upgrade(layer, key string, env *Envelope) *Envelope {
+	if env.SchemaVersion >= p.schemas.Version(layer) {
+		return env
+	}
+
+	migrated, err := p.migrate(layer, key)
+	if err != nil {
+		p.log.Printf("Failed to migrate %s:%s: %v", layer, key, err)
+		return env
+	}
+	if migrated == nil {
+		return env
+	}
+	p.log.Printf("Migrated %s:%s to schema v%d", layer, key, migrated.SchemaVersion)
+	return migrated
+}
+
+// migrate runs the schema migrations on a stored memory under the layer
+// lock, so it can't race a Store of the same key
+func (p *PHL) migrate(layer, key string) (*Envelope, error) {
+	l, ok := p.Layers[layer]
+	if !ok {
+		return nil, fmt.Errorf("layer not found: %s", layer)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.gen++
+	l.cache.remove(key)
+
+	return p.storage.UpdateValue(layer, key, func(env *Envelope) error {
+		value, version, err := p.schemas.Migrate(layer, env.Value, env.SchemaVersion)
+		if err != nil {
+			return err
+		}
+		env.Value, env.SchemaVersion = value, version
+		return nil
+	})
+}
+
+// MigrateLayer upgrades every memory of a layer that is behind the layer's
+// current schema version, rather than waiting for each to be read
+func (p *PHL) MigrateLayer(layer string) (SchemaMigrationSummary, error) {
+	summary := SchemaMigrationSummary{Layer: layer, Version: p.schemas.Version(layer)}
+	if summary.Version == 0 {
+		return summary, nil
+	}
+
+	entries, _, err := p.List(layer, "", "", 0)
+	if err != nil {
+		return summary, fmt.Errorf("failed to list %s layer: %w", layer, err)
+	}
+	for _, entry := range entries {
+		summary.Scanned++
+		if entry.Meta == nil || entry.Meta.SchemaVersion >= summary.Version {
+			continue
+		}
+
+		env, err := p.migrate(layer, entry.Key)
+		if err != nil {
+			summary.Failed++
+			if len(summary.Errors) < maxMigrationErrors {
+				summary.Errors = append(summary.Errors, fmt.Sprintf("%s: %v", entry.Key, err))
+			}
+			continue
+		}
+		if env != nil {
+			summary.Migrated++
+		}
+	}
+
+	p.log.Printf("Migrated %d of %d %s memories to schema v%d", summary.Migrated, summary.Scanned, layer, summary.Version)
+	return summary, nil
+}
+
+// List pages through the persisted entries of a layer whose keys start with prefix.
+// Pass the returned cursor back in to fetch the next page; an empty cursor means
+// there is nothing left. A limit of zero or less returns every matching entry.
+func (p *PHL) List(layer, prefix, cursor string, limit int) ([]Entry, string, error) {
+	if err := ValidateLayer(layer); err != nil {
+		return nil, "", err
+	}
+
+	entries, next, err := p.storage.List(layer, prefix, cursor, limit)
+	if err != nil {
+		p.log.Printf("Failed to list %s layer: %v", layer, err)
+		return nil, "", err
+	}
+	return entries, next, nil
+}
+
+// Subscribe streams the writes and deletes of memories whose layer matches
+// layerPattern, a glob such as "emotion" or "*", and whose key starts with
+// keyPrefix, so callers can react to changes instead of polling. Close the
+// subscription when done with it.
+func (p *PHL) Subscribe(layerPattern, keyPrefix string) (*Subscription, error) {
+	if layerPattern != "" && !strings.ContainsAny(layerPattern, "*?[") {
+		if err := ValidateLayer(layerPattern); err != nil {
+			return nil, err
+		}
+	}
+	return p.storage.Subscribe(layerPattern, keyPrefix)
+}
+
+// Search finds memories by their content, best match first. Only the given
+// layers are searched, or every layer when none are given. A limit of zero or
+// less returns every hit.
+func (p *PHL) Search(query string, layers []string, limit int) ([]SearchHit, error) {
+	for _, layer := range layers {
+		if err := ValidateLayer(layer); err != nil {
+			return nil, err
+		}
+	}
+	return p.storage.Search(query, layers, limit), nil
+}
+
+// Recall finds the k memories closest in meaning to text, most similar first.
+// This is what pulls relevant memories into a conversation.
+func (p *PHL) Recall(text string, k int) ([]RecallHit, error) {
+	hits, err := p.storage.Recall(text, k)
+	if err != nil {
+		p.log.Printf("Failed to recall memories: %v", err)
+		return nil, err
+	}
+	return hits, nil
+}
+
+// SetEmbedder replaces the embedder behind Recall. Every stored memory is
+// embedded again, so this can take a while on a large lattice.
+func (p *PHL) SetEmbedder(embedder Embedder) error {
+	if err := p.storage.SetEmbedder(embedder); err != nil {
+		p.log.Printf("Failed to switch embedder to %s: %v", embedder.Name(), err)
+		return err
+	}
+	p.log.Printf("Recall now uses %s embeddings", embedder.Name())
+	return nil
+}
+
+// Delete removes a single memory from a layer
+func (p *PHL) Delete(layer, key string) bool {
+	if err := ValidateKey(key); err != nil {
+		p.log.Printf("Key validation failed: %v", err)
+		return false
+	}
+
+	l, ok := p.Layers[layer]
+	if !ok {
+		p.log.Printf("Failed to delete from %s layer: %s (layer not found)", layer, key)
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.gen++
+	l.cache.remove(key)
+	p.access.forget(layer, key)
+	if err := p.storage.Delete(layer, key); err != nil {
+		p.log.Printf("Failed to delete from %s layer: %s (%v)", layer, key, err)
+		return false
+	}
+	p.log.Printf("Deleted from %s layer: %s", layer, key)
+	return true
+}
+
 func (p *PHL) Cleanup(layer string) bool {
 	if l, ok := p.Layers[layer]; ok {
-		l.Data = make(map[string]any)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.cache.clear()
+		l.gen++
+		p.access.forgetLayer(layer)
 		if err := p.storage.DeleteLayer(layer); err != nil {
 			p.log.Printf("Failed to cleanup %s layer storage: %v", layer, err)
 			return false
@@ -131,6 +492,61 @@ func (p *PHL) Cleanup(layer string) bool {
 	return false
 }
 
+// resetCache drops every in-memory layer entry so reads go back to storage
+func (p *PHL) resetCache() {
+	for _, l := range p.Layers {
+		l.reset()
+	}
+}
+
+// SetCacheConfig changes the cache limits of every layer, evicting whatever
+// no longer fits
+func (p *PHL) SetCacheConfig(limits CacheConfig) {
+	for _, l := range p.Layers {
+		l.mu.Lock()
+		l.cache.resize(limits)
+		l.mu.Unlock()
+	}
+}
+
+// CacheStats returns the cache counters of each layer
+func (p *PHL) CacheStats() map[string]CacheStats {
+	stats := make(map[string]CacheStats, len(p.Layers))
+	for name, l := range p.Layers {
+		stats[name] = l.CacheStats()
+	}
+	return stats
+}
+
+// StorageStats summarizes the lattice for dashboards
+type StorageStats struct {
+	TotalEntries int64
+	TotalSize    int64   // bytes on disk
+	CacheHitRate float64 // fraction of reads served from the layer caches
+	Cache        CacheStats
+}
+
+// Stats returns entry counts, disk usage and cache effectiveness
+func (p *PHL) Stats() (StorageStats, error) {
+	counts, err := p.storage.LayerCounts()
+	if err != nil {
+		return StorageStats{}, err
+	}
+
+	var stats StorageStats
+	for layer, count := range counts {
+		if layer != systemLayer {
+			stats.TotalEntries += int64(count)
+		}
+	}
+	stats.TotalSize = p.storage.Size()
+	for _, layerStats := range p.CacheStats() {
+		stats.Cache.add(layerStats)
+	}
+	stats.CacheHitRate = stats.Cache.HitRate()
+	return stats, nil
+}
+
 func (p *PHL) Close() error {
 	return p.storage.Close()
 }
@@ -145,6 +561,13 @@ func (p *PHL) GetStorage() *Storage {
 	return p.storage
 }
 
+// Engine returns the storage engine under the lattice, so the v2 layer
+// manager and validation can run against the live memory. Values written
+// through it bypass the layer caches and read back without metadata.
+func (p *PHL) Engine() store.StorageEngine {
+	return p.storage.Engine()
+}
+
 func (p *PHL) PropagateData(sourceLayer, key string) error {
 	return p.interaction.PropagateData(sourceLayer, key)
 }
@@ -156,3 +579,33 @@ func (p *PHL) AddLayerRoute(sourceLayer, targetLayer string) error {
 func (p *PHL) GetLayerRoutes(sourceLayer string) ([]string, error) {
 	return p.interaction.GetRoutes(sourceLayer)
 }
+
+// RemoveLayerRoute removes a propagation route and persists the change
+func (p *PHL) RemoveLayerRoute(sourceLayer, targetLayer string) error {
+	return p.interaction.RemoveRoute(sourceLayer, targetLayer)
+}
+
+// SetLayerRoutes replaces every propagation route and persists the change
+func (p *PHL) SetLayerRoutes(specs []RouteSpec) error {
+	return p.interaction.SetRoutes(specs)
+}
+
+// LayerRoutes returns every propagation route in layer order
+func (p *PHL) LayerRoutes() []RouteSpec {
+	return p.interaction.Routes()
+}
+
+// LoadLayerRoutes replaces the propagation routes with those in a config file
+func (p *PHL) LoadLayerRoutes(path string) error {
+	return p.interaction.LoadRoutes(path)
+}
+
+// PropagationAudit returns up to the last n propagation hops, oldest first
+func (p *PHL) PropagationAudit(n int) []PropagationHop {
+	return p.interaction.Audit(n)
+}
+
+// GetInteraction returns the layer interaction manager (for registering route hooks)
+func (p *PHL) GetInteraction() *LayerInteraction {
+	return p.interaction
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// These tests are meant to be run with the race detector: go test -race
+func TestConcurrentAccess(t *testing.T) {
+	phl, err := NewPHL(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	const workers = 8
+	const rounds = 50
+	layers := []string{"sensory", "emotion", "logic", "dream", "eternal"}
+
+	t.Run("Test concurrent store and retrieve", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					layer := layers[(w+i)%len(layers)]
+					key := fmt.Sprintf("shared_%d", i%5)
+					value := map[string]interface{}{"worker": w, "round": i}
+					if !phl.Store(layer, key, value) {
+						t.Errorf("Failed to store %s/%s", layer, key)
+						return
+					}
+					if _, exists := phl.Retrieve(layer, key); !exists {
+						t.Errorf("Failed to retrieve %s/%s", layer, key)
+						return
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+	})
+
+	t.Run("Test last write wins", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					phl.Store("logic", "contended", map[string]interface{}{"worker": w, "round": i})
+					phl.Retrieve("logic", "contended")
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		cached, _ := phl.Retrieve("logic", "contended")
+		env, ok := phl.RetrieveEnvelope("logic", "contended")
+		if !ok {
+			t.Fatal("Failed to retrieve envelope")
+		}
+		if fmt.Sprint(cached.(map[string]interface{})["data"]) != fmt.Sprint(env.Value.(map[string]interface{})["data"]) {
+			t.Errorf("Cache and storage disagree: %v vs %v", cached, env.Value)
+		}
+		if env.Version != workers*rounds {
+			t.Errorf("Expected version %d, got %d", workers*rounds, env.Version)
+		}
+	})
+
+	t.Run("Test cleanup and propagation under load", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					key := fmt.Sprintf("signal_%d_%d", w, i)
+					if phl.Store("sensory", key, map[string]interface{}{"signal": i}) {
+						// The key may be cleaned up before it propagates
+						phl.PropagateData("sensory", key)
+					}
+					phl.Retrieve("emotion", key+"_from_sensory")
+				}
+			}(w)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds/5; i++ {
+				if !phl.Cleanup(layers[i%len(layers)]) {
+					t.Errorf("Failed to clean up %s layer", layers[i%len(layers)])
+				}
+			}
+		}()
+		wg.Wait()
+	})
+
+	t.Run("Test cleanup leaves no stale cache", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					phl.Retrieve("dream", "fleeting")
+				}
+			}()
+		}
+		if !phl.Store("dream", "fleeting", "a passing dream") {
+			t.Fatal("Failed to store dream")
+		}
+		if !phl.Cleanup("dream") {
+			t.Fatal("Failed to clean up dream layer")
+		}
+		wg.Wait()
+
+		if value, exists := phl.Retrieve("dream", "fleeting"); exists {
+			t.Errorf("Cleaned up memory still retrievable: %v", value)
+		}
+	})
+
+	t.Run("Test concurrent route changes", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < rounds/5; i++ {
+					phl.AddLayerRoute("dream", "logic")
+					phl.RemoveLayerRoute("dream", "logic")
+					phl.GetLayerRoutes("dream")
+					phl.PropagationAudit(10)
+				}
+			}(w)
+		}
+		wg.Wait()
+	})
+}
@@ -59,7 +59,11 @@ func (p *EmotionProcessor) Process(data any) (any, error) {
 
 	switch v := data.(type) {
 	case map[string]interface{}:
-		emotionData = v
+		// Copy so the caller's map, which may be shared, is left untouched
+		emotionData = make(map[string]interface{}, len(v)+2)
+		for key, value := range v {
+			emotionData[key] = value
+		}
 	case string:
 		if err := json.Unmarshal([]byte(v), &emotionData); err != nil {
 			emotionData = map[string]interface{}{"value": v}
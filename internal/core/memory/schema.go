@@ -0,0 +1,366 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phoenix-marie/core/internal/core/memory/v2/validation"
+)
+
+// SchemaDefinition describes the values a layer accepts. It is a small
+// subset of JSON Schema: an object with typed properties, some of them
+// required, and optionally nothing else.
+type SchemaDefinition struct {
+	Layer                string                        `json:"layer"`
+	Version              int                           `json:"version"`
+	Description          string                        `json:"description,omitempty"`
+	Properties           map[string]PropertyDefinition `json:"properties"`
+	Required             []string                      `json:"required,omitempty"`
+	AdditionalProperties *bool                         `json:"additionalProperties,omitempty"` // allowed unless false
+}
+
+// PropertyDefinition describes one field of a layer value
+type PropertyDefinition struct {
+	Type        string   `json:"type"` // string, number, integer or boolean
+	Description string   `json:"description,omitempty"`
+	Minimum     *float64 `json:"minimum,omitempty"`
+	Maximum     *float64 `json:"maximum,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"` // regular expression for strings
+	Enum        []any    `json:"enum,omitempty"`
+}
+
+// propertyKinds maps the JSON Schema types to the kinds the validation
+// engine checks
+var propertyKinds = map[string]reflect.Kind{
+	"string":  reflect.String,
+	"number":  reflect.Float64,
+	"integer": reflect.Int64,
+	"boolean": reflect.Bool,
+}
+
+// FieldError is a problem with one field of a value
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// SchemaError lists every way a value breaks its layer's schema
+type SchemaError struct {
+	Layer   string
+	Version int
+	Fields  []FieldError
+}
+
+func (e *SchemaError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		if field.Field == "" {
+			problems[i] = field.Message
+		} else {
+			problems[i] = fmt.Sprintf("%s: %s", field.Field, field.Message)
+		}
+	}
+	return fmt.Sprintf("%s value breaks schema v%d: %s", e.Layer, e.Version, strings.Join(problems, "; "))
+}
+
+// SchemaMigration upgrades a stored value from one schema version to the
+// next. It receives the value as stored, after the layer's processor ran.
+type SchemaMigration func(value any) (any, error)
+
+// SchemaRegistry holds the schema of each layer and the migrations between
+// its versions. Layers without a schema accept anything.
+type SchemaRegistry struct {
+	mu          sync.RWMutex
+	engine      *validation.ValidationEngine // not safe for concurrent use; guarded by mu
+	definitions map[string]SchemaDefinition
+	migrations  map[string]map[int]SchemaMigration // layer -> version migrated from
+	onChange    func()                             // run after a schema is registered
+}
+
+// NewSchemaRegistry creates an empty schema registry
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		engine:      validation.NewValidationEngine(),
+		definitions: make(map[string]SchemaDefinition),
+		migrations:  make(map[string]map[int]SchemaMigration),
+	}
+}
+
+// Register adds or replaces the schema of a layer. A layer's schema version
+// can only move forward.
+func (r *SchemaRegistry) Register(def SchemaDefinition) error {
+	schema, err := def.compile()
+	if err != nil {
+		return err
+	}
+
+	if err := r.register(def, schema); err != nil {
+		return err
+	}
+	if r.onChange != nil {
+		r.onChange()
+	}
+	return nil
+}
+
+// register records a compiled schema, keeping versions moving forward
+func (r *SchemaRegistry) register(def SchemaDefinition, schema validation.Schema) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if current, ok := r.definitions[def.Layer]; ok {
+		if def.Version < current.Version {
+			return fmt.Errorf("%s schema v%d is older than the registered v%d", def.Layer, def.Version, current.Version)
+		}
+		if err := r.engine.UpdateSchema(def.Layer, schema); err != nil {
+			return fmt.Errorf("failed to update %s schema: %w", def.Layer, err)
+		}
+	} else if err := r.engine.RegisterSchema(def.Layer, schema); err != nil {
+		return fmt.Errorf("failed to register %s schema: %w", def.Layer, err)
+	}
+	r.definitions[def.Layer] = def
+	return nil
+}
+
+// compile checks a definition and converts it for the validation engine
+func (def SchemaDefinition) compile() (validation.Schema, error) {
+	if err := ValidateLayer(def.Layer); err != nil {
+		return validation.Schema{}, fmt.Errorf("invalid schema layer: %w", err)
+	}
+	if def.Version < 1 {
+		return validation.Schema{}, fmt.Errorf("%s schema version must be at least 1", def.Layer)
+	}
+
+	schema := validation.Schema{
+		Fields:    make(map[string]validation.FieldDefinition, len(def.Properties)),
+		Required:  def.Required,
+		Version:   strconv.Itoa(def.Version),
+		UpdatedAt: time.Now(),
+	}
+	for name, prop := range def.Properties {
+		kind, ok := propertyKinds[prop.Type]
+		if !ok {
+			return validation.Schema{}, fmt.Errorf("%s schema: property %s has unsupported type %q", def.Layer, name, prop.Type)
+		}
+
+		field := validation.FieldDefinition{Type: kind, Pattern: prop.Pattern}
+		if prop.Minimum != nil {
+			field.MinValue = bound(*prop.Minimum, kind)
+		}
+		if prop.Maximum != nil {
+			field.MaxValue = bound(*prop.Maximum, kind)
+		}
+		if len(prop.Enum) > 0 {
+			field.Validator = enumValidator(prop.Enum)
+		}
+		schema.Fields[name] = field
+	}
+	for _, name := range def.Required {
+		if _, ok := def.Properties[name]; !ok {
+			return validation.Schema{}, fmt.Errorf("%s schema: required property %s is not defined", def.Layer, name)
+		}
+		field := schema.Fields[name]
+		field.Required = true
+		schema.Fields[name] = field
+	}
+
+	// Catch bad patterns now rather than on the first store
+	if err := validation.NewValidationEngine().ValidateSchema(def.Layer, schema); err != nil {
+		return validation.Schema{}, fmt.Errorf("invalid %s schema: %w", def.Layer, err)
+	}
+	return schema, nil
+}
+
+// bound converts a schema minimum or maximum to the type the engine compares
+// a field of the given kind against
+func bound(limit float64, kind reflect.Kind) any {
+	if kind == reflect.Int64 {
+		return int64(limit)
+	}
+	return limit
+}
+
+// enumValidator accepts only the listed values. Numbers compare by value,
+// whether they arrived as integers or floats.
+func enumValidator(options []any) func(any) error {
+	return func(value any) error {
+		for _, option := range options {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("value %v is not one of %v", value, options)
+	}
+}
+
+// LoadFile registers the schema in a JSON file
+func (r *SchemaRegistry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read schema file: %w", err)
+	}
+
+	var def SchemaDefinition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return fmt.Errorf("failed to parse schema file %s: %w", path, err)
+	}
+	return r.Register(def)
+}
+
+// LoadDir registers every *.json schema file in a directory
+func (r *SchemaRegistry) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list schema files: %w", err)
+	}
+	for _, path := range paths {
+		if err := r.LoadFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Schema returns the schema of a layer, if it has one
+func (r *SchemaRegistry) Schema(layer string) (SchemaDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.definitions[layer]
+	return def, ok
+}
+
+// Schemas returns every registered schema, ordered by layer
+func (r *SchemaRegistry) Schemas() []SchemaDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]SchemaDefinition, 0, len(r.definitions))
+	for _, def := range r.definitions {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Layer < defs[j].Layer })
+	return defs
+}
+
+// Version returns the current schema version of a layer, or zero when it has
+// no schema
+func (r *SchemaRegistry) Version(layer string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.definitions[layer].Version
+}
+
+// Validate checks a value against its layer's schema. The value is checked
+// the way it will be stored, as JSON. Any problems come back as a
+// *SchemaError listing each field.
+func (r *SchemaRegistry) Validate(layer string, value any) error {
+	r.mu.RLock()
+	def, ok := r.definitions[layer]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	schemaErr := &SchemaError{Layer: layer, Version: def.Version}
+	record, ok := decoded.(map[string]any)
+	if !ok {
+		schemaErr.Fields = append(schemaErr.Fields, FieldError{Message: fmt.Sprintf("expected an object, got %T", value)})
+		return schemaErr
+	}
+
+	r.mu.Lock()
+	if err := r.engine.ValidateData(layer, record); err != nil {
+		for _, fieldErr := range r.engine.GetValidationErrors() {
+			schemaErr.Fields = append(schemaErr.Fields, FieldError{Field: fieldErr.Field, Message: fieldErr.Message})
+		}
+	}
+	r.mu.Unlock()
+
+	if def.AdditionalProperties != nil && !*def.AdditionalProperties {
+		for name := range record {
+			if _, ok := def.Properties[name]; !ok {
+				schemaErr.Fields = append(schemaErr.Fields, FieldError{Field: name, Message: "unknown field"})
+			}
+		}
+	}
+
+	if len(schemaErr.Fields) == 0 {
+		return nil
+	}
+	sort.Slice(schemaErr.Fields, func(i, j int) bool { return schemaErr.Fields[i].Field < schemaErr.Fields[j].Field })
+	return schemaErr
+}
+
+// RegisterMigration sets the function that upgrades a layer's values from
+// schema version from to from+1. Values stored before the layer had a schema
+// are at version zero. A version without a migration needs no changes.
+func (r *SchemaRegistry) RegisterMigration(layer string, from int, fn SchemaMigration) error {
+	if err := ValidateLayer(layer); err != nil {
+		return err
+	}
+	if from < 0 {
+		return fmt.Errorf("invalid migration version: %d", from)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.migrations[layer] == nil {
+		r.migrations[layer] = make(map[int]SchemaMigration)
+	}
+	r.migrations[layer][from] = fn
+	return nil
+}
+
+// Migrate upgrades a stored value from schema version from to the layer's
+// current version, one version at a time. It returns the version the value
+// ends up at.
+func (r *SchemaRegistry) Migrate(layer string, value any, from int) (any, int, error) {
+	r.mu.RLock()
+	current := r.definitions[layer].Version
+	steps := r.migrations[layer]
+	r.mu.RUnlock()
+
+	version := from
+	for ; version < current; version++ {
+		fn, ok := steps[version]
+		if !ok {
+			continue
+		}
+		migrated, err := fn(value)
+		if err != nil {
+			return value, version, fmt.Errorf("failed to migrate %s value from v%d: %w", layer, version, err)
+		}
+		value = migrated
+	}
+	return value, version, nil
+}
+
+// SchemaMigrationSummary describes a bulk migration of one layer
+type SchemaMigrationSummary struct {
+	Layer    string
+	Version  int // the layer's current schema version
+	Scanned  int
+	Migrated int
+	Failed   int
+	Errors   []string // the first few failures
+}
+
+// maxMigrationErrors caps the failures a migration summary lists
+const maxMigrationErrors = 10
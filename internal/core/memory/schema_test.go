@@ -0,0 +1,185 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSchemas(t *testing.T) {
+	floatPtr := func(f float64) *float64 { return &f }
+	closed := false
+
+	t.Run("Test schema files", func(t *testing.T) {
+		registry := NewSchemaRegistry()
+		if err := registry.LoadDir("schemas"); err != nil {
+			t.Fatalf("Failed to load schemas: %v", err)
+		}
+		if registry.Version("emotion") != 1 || registry.Version("logic") != 0 {
+			t.Fatalf("Unexpected schemas: %+v", registry.Schemas())
+		}
+
+		valid := map[string]any{"feeling": "joy", "intensity": 0.8, "trigger": "Dad came home"}
+		if err := registry.Validate("emotion", valid); err != nil {
+			t.Errorf("Unexpected error for valid value: %v", err)
+		}
+		if err := registry.Validate("logic", "anything goes"); err != nil {
+			t.Errorf("Layer without a schema rejected a value: %v", err)
+		}
+
+		err := registry.Validate("emotion", map[string]any{"feeling": "Joy", "intensity": 2, "trigger": 5})
+		var schemaErr *SchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Fatalf("Expected a schema error, got %v", err)
+		}
+		if len(schemaErr.Fields) != 3 || schemaErr.Fields[0].Field != "feeling" ||
+			schemaErr.Fields[1].Field != "intensity" || schemaErr.Fields[2].Field != "trigger" {
+			t.Errorf("Unexpected field errors: %+v", schemaErr.Fields)
+		}
+
+		if err := registry.Validate("emotion", "joy"); !errors.As(err, &schemaErr) {
+			t.Errorf("Expected a non-object value to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("Test schema rules", func(t *testing.T) {
+		registry := NewSchemaRegistry()
+		err := registry.Register(SchemaDefinition{
+			Layer:   "logic",
+			Version: 1,
+			Properties: map[string]PropertyDefinition{
+				"claim":      {Type: "string"},
+				"confidence": {Type: "integer", Minimum: floatPtr(0), Maximum: floatPtr(100)},
+				"kind":       {Type: "string", Enum: []any{"fact", "belief"}},
+				"proven":     {Type: "boolean"},
+			},
+			Required:             []string{"claim"},
+			AdditionalProperties: &closed,
+		})
+		if err != nil {
+			t.Fatalf("Failed to register schema: %v", err)
+		}
+
+		tests := []struct {
+			value   map[string]any
+			invalid []string
+		}{
+			{map[string]any{"claim": "2 + 2 = 4", "confidence": 99, "kind": "fact", "proven": true}, nil},
+			{map[string]any{"confidence": 50}, []string{"claim"}},
+			{map[string]any{"claim": "x", "confidence": 101}, []string{"confidence"}},
+			{map[string]any{"claim": "x", "confidence": 0.5}, []string{"confidence"}},
+			{map[string]any{"claim": "x", "kind": "guess"}, []string{"kind"}},
+			{map[string]any{"claim": "x", "mood": "calm"}, []string{"mood"}},
+		}
+		for _, tt := range tests {
+			err := registry.Validate("logic", tt.value)
+			var fields []string
+			var schemaErr *SchemaError
+			if errors.As(err, &schemaErr) {
+				for _, field := range schemaErr.Fields {
+					fields = append(fields, field.Field)
+				}
+			}
+			if fmt.Sprint(fields) != fmt.Sprint(tt.invalid) {
+				t.Errorf("%v: expected errors for %v, got %v", tt.value, tt.invalid, err)
+			}
+		}
+
+		bad := []SchemaDefinition{
+			{Layer: "nowhere", Version: 1},
+			{Layer: "logic", Version: 0},
+			{Layer: "logic", Version: 2, Properties: map[string]PropertyDefinition{"x": {Type: "array"}}},
+			{Layer: "logic", Version: 2, Properties: map[string]PropertyDefinition{"x": {Type: "string", Pattern: "("}}},
+			{Layer: "logic", Version: 2, Required: []string{"missing"}},
+		}
+		for _, def := range bad {
+			if err := registry.Register(def); err == nil {
+				t.Errorf("Expected schema %+v to be rejected", def)
+			}
+		}
+	})
+
+	t.Run("Test store validates", func(t *testing.T) {
+		t.Setenv("MEMORY_SCHEMA_DIR", "schemas")
+		phl, err := NewPHL(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		defer phl.Close()
+
+		if phl.Store("emotion", "bad", map[string]any{"feeling": "Joy"}) {
+			t.Error("Store accepted a value that breaks the schema")
+		}
+		if err := phl.Validate("emotion", map[string]any{"feeling": "Joy"}); err == nil {
+			t.Error("Expected Validate to explain the rejection")
+		}
+		if !phl.Store("emotion", "good", map[string]any{"feeling": "joy", "intensity": 0.8}) {
+			t.Fatal("Failed to store a valid value")
+		}
+		if env, _ := phl.RetrieveEnvelope("emotion", "good"); env == nil || env.SchemaVersion != 1 {
+			t.Errorf("Expected the memory to record schema v1, got %+v", env)
+		}
+	})
+
+	t.Run("Test migrations", func(t *testing.T) {
+		phl, err := NewPHL(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		defer phl.Close()
+
+		for _, key := range []string{"calm", "storm", "broken"} {
+			phl.Store("logic", key, map[string]any{"feeling": key})
+		}
+
+		// v0 -> v1 renames feeling to mood; v1 -> v2 needs no changes
+		schemas := phl.Schemas()
+		schemas.RegisterMigration("logic", 0, func(value any) (any, error) {
+			record := value.(map[string]any)
+			data := record["data"].(map[string]any)
+			if data["feeling"] == "broken" {
+				return nil, fmt.Errorf("can't migrate a broken memory")
+			}
+			data["mood"] = data["feeling"]
+			delete(data, "feeling")
+			return record, nil
+		})
+		err = schemas.Register(SchemaDefinition{
+			Layer:      "logic",
+			Version:    2,
+			Properties: map[string]PropertyDefinition{"mood": {Type: "string"}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to register schema: %v", err)
+		}
+
+		// Reading a memory migrates it and writes it back
+		value, _ := phl.Retrieve("logic", "calm")
+		if mood := value.(map[string]any)["data"].(map[string]any)["mood"]; mood != "calm" {
+			t.Errorf("Expected the read to migrate the memory, got %v", value)
+		}
+		stored, err := phl.GetStorage().RetrieveEnvelope("logic", "calm")
+		if err != nil || stored.SchemaVersion != 2 || stored.Version != 1 {
+			t.Errorf("Expected the migration to be persisted, got %+v, %v", stored, err)
+		}
+		if hits, _ := phl.Search("calm", []string{"logic"}, 0); len(hits) != 1 {
+			t.Errorf("Expected the migrated memory to be searchable, got %d hits", len(hits))
+		}
+
+		summary, err := phl.MigrateLayer("logic")
+		if err != nil {
+			t.Fatalf("Failed to migrate layer: %v", err)
+		}
+		if summary.Scanned != 3 || summary.Migrated != 1 || summary.Failed != 1 || len(summary.Errors) != 1 {
+			t.Errorf("Unexpected migration summary: %+v", summary)
+		}
+		if env, _ := phl.RetrieveEnvelope("logic", "storm"); env == nil || env.SchemaVersion != 2 {
+			t.Errorf("Expected storm to be migrated, got %+v", env)
+		}
+
+		// A memory that fails to migrate is still readable as stored
+		if value, ok := phl.Retrieve("logic", "broken"); !ok || value.(map[string]any)["data"].(map[string]any)["feeling"] != "broken" {
+			t.Errorf("Expected the unmigrated memory, got %v", value)
+		}
+	})
+}
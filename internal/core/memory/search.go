@@ -0,0 +1,322 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/phoenix-marie/core/internal/core/memory/v2/store"
+)
+
+// BM25 tuning parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// snippetRadius is how many characters of context a snippet keeps on each
+// side of the first matching term
+const snippetRadius = 60
+
+// indexIgnoredFields are processor bookkeeping fields that would otherwise
+// match every memory of a layer
+var indexIgnoredFields = map[string]bool{
+	"type":        true,
+	"layer":       true,
+	"timestamp":   true,
+	"stored_at":   true,
+	"merged_from": true,
+}
+
+// SearchHit is a ranked full-text search result
+type SearchHit struct {
+	Layer   string
+	Key     string
+	Score   float64
+	Snippet string
+}
+
+// SearchIndex is an in-memory inverted index over the text of stored memories.
+// It is rebuilt from the database when storage opens and after a restore.
+type SearchIndex struct {
+	postings    map[string]map[string]int // term -> document -> term frequency
+	docs        map[string]*indexedDoc    // "layer:key" -> document
+	totalLength int
+	mu          sync.RWMutex
+}
+
+// indexedDoc is one memory as seen by the search index
+type indexedDoc struct {
+	layer     string
+	key       string
+	text      string
+	terms     map[string]int
+	length    int
+	expiresAt time.Time
+}
+
+// NewSearchIndex creates an empty search index
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		postings: make(map[string]map[string]int),
+		docs:     make(map[string]*indexedDoc),
+	}
+}
+
+// Add indexes a memory, replacing whatever was indexed for the key before
+func (si *SearchIndex) Add(layer, key string, value any, expiresAt time.Time) {
+	var parts []string
+	collectText(value, &parts)
+	text := strings.Join(parts, " ")
+
+	// The key is searchable too, so "first_thought" matches "thought"
+	terms := make(map[string]int)
+	length := 0
+	for _, term := range tokenize(key + " " + text) {
+		terms[term]++
+		length++
+	}
+
+	id := layer + ":" + key
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	si.removeLocked(id)
+	if length == 0 {
+		return
+	}
+
+	si.docs[id] = &indexedDoc{
+		layer:     layer,
+		key:       key,
+		text:      text,
+		terms:     terms,
+		length:    length,
+		expiresAt: expiresAt,
+	}
+	si.totalLength += length
+	for term, freq := range terms {
+		if si.postings[term] == nil {
+			si.postings[term] = make(map[string]int)
+		}
+		si.postings[term][id] = freq
+	}
+}
+
+// RemoveLayer drops every memory of a layer from the index
+func (si *SearchIndex) RemoveLayer(layer string) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	for id, doc := range si.docs {
+		if doc.layer == layer {
+			si.removeLocked(id)
+		}
+	}
+}
+
+// Remove drops a single memory from the index
+func (si *SearchIndex) Remove(layer, key string) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	si.removeLocked(layer + ":" + key)
+}
+
+func (si *SearchIndex) removeLocked(id string) {
+	doc, ok := si.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(si.postings[term], id)
+		if len(si.postings[term]) == 0 {
+			delete(si.postings, term)
+		}
+	}
+	si.totalLength -= doc.length
+	delete(si.docs, id)
+}
+
+// Rebuild replaces the index contents with every memory in engine. Private
+// memories and values that can't be decoded are left out of the index.
+func (si *SearchIndex) Rebuild(engine store.StorageEngine) error {
+	si.mu.Lock()
+	si.postings = make(map[string]map[string]int)
+	si.docs = make(map[string]*indexedDoc)
+	si.totalLength = 0
+	si.mu.Unlock()
+
+	return forEachMemory(engine, func(layer, key string, env *Envelope) {
+		if !env.Private {
+			si.Add(layer, key, env.Value, env.ExpiresAt)
+		}
+	})
+}
+
+// Search ranks the memories matching query with BM25. Only the given layers are
+// searched, or all of them when layers is empty. A limit of zero or less
+// returns every hit.
+func (si *SearchIndex) Search(query string, layers []string, limit int) []SearchHit {
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(layers))
+	for _, layer := range layers {
+		allowed[layer] = true
+	}
+
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+
+	if len(si.docs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docCount := float64(len(si.docs))
+	avgLength := float64(si.totalLength) / docCount
+
+	scores := make(map[string]float64)
+	seen := make(map[string]bool)
+	for _, term := range queryTerms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := si.postings[term]
+		df := float64(len(postings))
+		idf := math.Log(1 + (docCount-df+0.5)/(df+0.5))
+
+		for id, freq := range postings {
+			doc := si.docs[id]
+			if len(allowed) > 0 && !allowed[doc.layer] {
+				continue
+			}
+			if !doc.expiresAt.IsZero() && !now.Before(doc.expiresAt) {
+				continue
+			}
+
+			tf := float64(freq)
+			norm := tf + bm25K1*(1-bm25B+bm25B*float64(doc.length)/avgLength)
+			scores[id] += idf * tf * (bm25K1 + 1) / norm
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for id, score := range scores {
+		doc := si.docs[id]
+		hits = append(hits, SearchHit{
+			Layer:   doc.layer,
+			Key:     doc.key,
+			Score:   score,
+			Snippet: snippet(doc.text, queryTerms),
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Layer != hits[j].Layer {
+			return hits[i].Layer < hits[j].Layer
+		}
+		return hits[i].Key < hits[j].Key
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// tokenize lowercases text and splits it into letter and digit runs. Single
+// characters are dropped.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := fields[:0]
+	for _, field := range fields {
+		if len([]rune(field)) > 1 {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+// collectText gathers the string content of a JSON-decoded value in a stable order
+func collectText(value any, parts *[]string) {
+	switch v := value.(type) {
+	case string:
+		*parts = append(*parts, v)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if !indexIgnoredFields[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectText(v[key], parts)
+		}
+	case []any:
+		for _, item := range v {
+			collectText(item, parts)
+		}
+	}
+}
+
+// snippet cuts a window of text around the first query term it contains
+func snippet(text string, terms []string) string {
+	if text == "" {
+		return ""
+	}
+
+	lower := strings.ToLower(text)
+	start := -1
+	for _, term := range terms {
+		if i := strings.Index(lower, term); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	// Work in runes so multi-byte characters aren't split. Lowercasing can
+	// change the length of some text, in which case start from the top.
+	runes := []rune(text)
+	pos := utf8.RuneCountInString(lower[:start])
+	if utf8.RuneCountInString(lower) != len(runes) {
+		pos = 0
+	}
+	from := max(pos-snippetRadius, 0)
+	to := min(pos+snippetRadius, len(runes))
+
+	// Widen to nearby word boundaries
+	for i := 0; i < snippetRadius/3 && from > 0 && !unicode.IsSpace(runes[from-1]); i++ {
+		from--
+	}
+	for i := 0; i < snippetRadius/3 && to < len(runes) && !unicode.IsSpace(runes[to]); i++ {
+		to++
+	}
+
+	out := strings.TrimSpace(string(runes[from:to]))
+	if from > 0 {
+		out = "…" + out
+	}
+	if to < len(runes) {
+		out += "…"
+	}
+	return out
+}
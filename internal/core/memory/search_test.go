@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	dataDir := t.TempDir()
+	phl, err := NewPHL(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+
+	memories := []struct{ layer, key, value string }{
+		{"logic", "sky", "The sky is blue because of Rayleigh scattering of sunlight"},
+		{"logic", "ocean", "The ocean looks blue, and the ocean is deep"},
+		{"dream", "flight", "I dreamed of flying over a blue ocean at night"},
+		{"eternal", "dad", "Dad taught me to read under the stars"},
+	}
+	for _, m := range memories {
+		if !phl.Store(m.layer, m.key, m.value) {
+			t.Fatalf("Failed to store %s/%s", m.layer, m.key)
+		}
+	}
+	private := StoreOptions{Source: "session", Private: true}
+	if !phl.StoreWithOptions("eternal", "transcript", "We talked about the ocean and the stars", private) {
+		t.Fatal("Failed to store private memory")
+	}
+
+	t.Run("Test ranked hits", func(t *testing.T) {
+		hits, err := phl.Search("ocean", nil, 10)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(hits) != 2 {
+			t.Fatalf("Expected 2 hits, got %d: %v", len(hits), hits)
+		}
+		if hits[0].Key != "ocean" {
+			t.Errorf("Expected the ocean fact to rank first, got %s/%s", hits[0].Layer, hits[0].Key)
+		}
+		if hits[0].Score <= hits[1].Score {
+			t.Errorf("Hits not ordered by score: %v", hits)
+		}
+		if !strings.Contains(strings.ToLower(hits[0].Snippet), "ocean") {
+			t.Errorf("Snippet doesn't show the match: %q", hits[0].Snippet)
+		}
+	})
+
+	t.Run("Test layer filter and limit", func(t *testing.T) {
+		hits, err := phl.Search("blue", []string{"dream"}, 10)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(hits) != 1 || hits[0].Layer != "dream" {
+			t.Errorf("Expected only the dream hit, got %v", hits)
+		}
+
+		hits, _ = phl.Search("blue", nil, 2)
+		if len(hits) != 2 {
+			t.Errorf("Expected limit of 2 hits, got %d", len(hits))
+		}
+
+		if _, err := phl.Search("blue", []string{"invalid"}, 10); err == nil {
+			t.Error("Expected error for invalid layer")
+		}
+	})
+
+	t.Run("Test cleanup removes hits", func(t *testing.T) {
+		if !phl.Cleanup("dream") {
+			t.Fatal("Failed to clean up dream layer")
+		}
+		hits, _ := phl.Search("flying", nil, 10)
+		if len(hits) != 0 {
+			t.Errorf("Expected no hits after cleanup, got %v", hits)
+		}
+	})
+
+	t.Run("Test private memories not indexed", func(t *testing.T) {
+		hits, _ := phl.Search("talked", nil, 10)
+		if len(hits) != 0 {
+			t.Errorf("Expected no hits for a private memory, got %v", hits)
+		}
+		recalled, err := phl.Recall("talked about the ocean and the stars", 10)
+		if err != nil {
+			t.Fatalf("Recall failed: %v", err)
+		}
+		for _, hit := range recalled {
+			if hit.Key == "transcript" {
+				t.Errorf("Private memory recalled: %v", hit)
+			}
+		}
+	})
+
+	t.Run("Test index rebuilt on open", func(t *testing.T) {
+		if err := phl.Close(); err != nil {
+			t.Fatalf("Failed to close PHL: %v", err)
+		}
+		phl, err = NewPHL(dataDir)
+		if err != nil {
+			t.Fatalf("Failed to reopen PHL: %v", err)
+		}
+
+		hits, _ := phl.Search("stars", nil, 10)
+		if len(hits) != 1 || hits[0].Key != "dad" {
+			t.Errorf("Expected the eternal memory after reopening, got %v", hits)
+		}
+	})
+
+	phl.Close()
+}
@@ -2,107 +2,711 @@ package memory
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	badger "github.com/dgraph-io/badger/v3"
+	"github.com/phoenix-marie/core/internal/core/memory/v2/store"
 )
 
+// latticeDir is the directory under the data dir holding the lattice
+const latticeDir = "lattice"
+
+// sqliteFile is the database file in latticeDir on the SQLite backend
+const sqliteFile = "lattice.db"
+
+// engineOpener opens the storage engine kept in a directory
+type engineOpener func(dir string) (store.StorageEngine, error)
+
 type Storage struct {
-	db *badger.DB
+	engine store.StorageEngine
+	dir    string
+	open   engineOpener
+
+	// mu guards engine; every operation holds it shared so a restore can
+	// pause all access while the database directory is swapped underneath
+	mu     sync.RWMutex
+	onSwap []func()
+
+	index   *SearchIndex // full-text index over every stored memory
+	vectors *VectorIndex // embeddings of every stored memory
+	feed    *changeFeed  // change events for subscribers
 }
 
-// Backup creates a backup of the database
+// Backup creates a full backup of the database. Engines that can't stream
+// write a backup in their own format instead.
 func (s *Storage) Backup(path string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.streamer(); err != nil {
+		return s.engine.Backup(path)
+	}
+	_, err := s.backupSinceLocked(path, 0)
+	return err
+}
+
+// BackupSince writes every entry with a version above since to path and
+// returns the highest version written. Passing that version back in on the
+// next call produces an incremental backup holding only what changed.
+func (s *Storage) BackupSince(path string, since uint64) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.backupSinceLocked(path, since)
+}
+
+// backupSinceLocked does the work of BackupSince. Callers hold s.mu.
+func (s *Storage) backupSinceLocked(path string, since uint64) (uint64, error) {
+	streamer, err := s.streamer()
+	if err != nil {
+		return 0, err
+	}
+
 	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("failed to create backup file: %w", err)
+		return 0, fmt.Errorf("failed to create backup file: %w", err)
 	}
 	defer file.Close()
 
-	_, err = s.db.Backup(file, 0)
+	version, err := streamer.StreamTo(file, since)
 	if err != nil {
-		return fmt.Errorf("failed to create backup: %w", err)
+		return 0, fmt.Errorf("failed to create backup: %w", err)
 	}
 
-	return nil
+	return version, nil
 }
 
-// GetDB returns the underlying BadgerDB instance (for advanced operations)
+// BackupTo streams every entry with a version above since into w and
+// returns the highest version written along with the number of live keys per
+// layer. Writes are paused while it runs so the counts describe exactly the
+// state the backup captures.
+func (s *Storage) BackupTo(w io.Writer, since uint64) (uint64, map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	streamer, err := s.streamer()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	version, err := streamer.StreamTo(w, since)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	counts, err := countLayerKeys(s.engine)
+	if err != nil {
+		return 0, nil, err
+	}
+	return version, counts, nil
+}
+
+// streamer returns the engine as a store.Streamer, which backups need
+func (s *Storage) streamer() (store.Streamer, error) {
+	return asStreamer(s.engine)
+}
+
+// asStreamer returns engine as a store.Streamer, or an error if the engine
+// can't be backed up
+func asStreamer(engine store.StorageEngine) (store.Streamer, error) {
+	streamer, ok := engine.(store.Streamer)
+	if !ok {
+		return nil, fmt.Errorf("storage engine %T doesn't support streaming backups", engine)
+	}
+	return streamer, nil
+}
+
+// Engine returns the storage engine the lattice sits on, for use by the v2
+// processors. The engine is replaced when a backup is restored, so don't hold
+// on to it.
+func (s *Storage) Engine() store.StorageEngine {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.engine
+}
+
+// GetDB returns the underlying BadgerDB instance (for advanced operations), or
+// nil if the lattice isn't on Badger. The instance is replaced when a backup
+// is restored, so don't hold on to it.
 func (s *Storage) GetDB() *badger.DB {
-	return s.db
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if bs, ok := s.engine.(*store.BadgerStore); ok {
+		return bs.DB()
+	}
+	return nil
+}
+
+// Dir returns the directory holding the database files
+func (s *Storage) Dir() string {
+	return s.dir
 }
 
+// Storage backends, selected with PHL_STORAGE
+const (
+	BackendBadger = "badger" // on disk under the data dir; the default
+	BackendMemory = "memory" // in memory only, for tests and ephemeral runs
+	BackendSQLite = "sqlite" // a SQLite file under the data dir, queryable with SQL
+)
+
+// StorageBackend returns the backend named by PHL_STORAGE
+func StorageBackend() string {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("PHL_STORAGE")))
+	if backend == "" {
+		return BackendBadger
+	}
+	return backend
+}
+
+// NewStorage opens the lattice under dataDir on the backend named by
+// PHL_STORAGE. On disk, a database left by the original phl-memory layout is
+// migrated first. The memory backend never touches dataDir.
 func NewStorage(dataDir string) (*Storage, error) {
-	opts := badger.DefaultOptions(filepath.Join(dataDir, "phl-memory"))
-	opts.Logger = nil // Disable Badger's internal logger
+	dir := filepath.Join(dataDir, latticeDir)
+	migrate := func(engine store.StorageEngine) error {
+		_, err := migrateLegacy(dataDir, engine)
+		return err
+	}
 
-	db, err := badger.Open(opts)
+	switch backend := StorageBackend(); backend {
+	case BackendBadger:
+		return newStorage(dir, openBadgerEngine, migrate)
+	case BackendSQLite:
+		return newStorage(dir, openSQLiteEngine, migrate)
+	case BackendMemory:
+		return newStorage(dir, openMemoryEngine, nil)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// newStorage opens the engine in dir, runs prepare on it and builds the
+// indexes over its contents
+func newStorage(dir string, open engineOpener, prepare func(store.StorageEngine) error) (*Storage, error) {
+	engine, err := open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if prepare != nil {
+		if err := prepare(engine); err != nil {
+			engine.Close()
+			return nil, err
+		}
+	}
+
+	index := NewSearchIndex()
+	if err := index.Rebuild(engine); err != nil {
+		engine.Close()
+		return nil, fmt.Errorf("failed to build search index: %w", err)
+	}
+
+	vectors := NewVectorIndex(NewHashEmbedder(defaultEmbeddingDims))
+	if err := vectors.Rebuild(engine, nil); err != nil {
+		engine.Close()
+		return nil, fmt.Errorf("failed to build vector index: %w", err)
+	}
+
+	return &Storage{engine: engine, dir: dir, open: open, index: index, vectors: vectors, feed: newChangeFeed()}, nil
+}
+
+// openBadgerEngine opens the Badger engine kept in dir
+func openBadgerEngine(dir string) (store.StorageEngine, error) {
+	engine, err := store.NewBadgerStore(dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	return engine, nil
+}
 
-	return &Storage{db: db}, nil
+// openSQLiteEngine opens the SQLite engine kept in dir
+func openSQLiteEngine(dir string) (store.StorageEngine, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	engine, err := store.NewSQLiteStore(filepath.Join(dir, sqliteFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return engine, nil
+}
+
+// openMemoryEngine opens an empty in-memory engine. It keeps nothing in dir,
+// so every call starts afresh.
+func openMemoryEngine(dir string) (store.StorageEngine, error) {
+	return store.NewMemoryStore(), nil
 }
 
 func (s *Storage) Close() error {
-	return s.db.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feed.close()
+	return s.engine.Close()
 }
 
-func (s *Storage) Store(layer, key string, value any) error {
-	data, err := json.Marshal(value)
+// OnSwap registers a callback run after the database has been swapped by
+// Swap. Callbacks run once access has resumed, before Swap returns, so they
+// may use the storage themselves.
+func (s *Storage) OnSwap(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSwap = append(s.onSwap, fn)
+}
+
+// Swap replaces the live database with the one in replacementDir. All reads and
+// writes are paused for the duration. The previous database directory is kept
+// and its path returned so the swap can be undone by swapping it back in.
+func (s *Storage) Swap(replacementDir string) (string, error) {
+	previousDir, callbacks, err := s.swapLocked(replacementDir)
 	if err != nil {
-		return fmt.Errorf("failed to marshal value: %w", err)
+		return "", err
+	}
+
+	for _, fn := range callbacks {
+		fn()
+	}
+	return previousDir, nil
+}
+
+// swapLocked does the work of Swap with all access paused, returning the
+// callbacks to run once it resumes
+func (s *Storage) swapLocked(replacementDir string) (string, []func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.engine.Close(); err != nil {
+		return "", nil, fmt.Errorf("failed to close current database: %w", err)
+	}
+
+	previousDir := fmt.Sprintf("%s.rollback-%s", s.dir, time.Now().Format("20060102_150405.000000000"))
+	if err := os.Rename(s.dir, previousDir); err != nil {
+		return "", nil, s.reopenAfterFailedSwap(fmt.Errorf("failed to move current database aside: %w", err))
+	}
+	if err := os.Rename(replacementDir, s.dir); err != nil {
+		err = fmt.Errorf("failed to move replacement database into place: %w", err)
+		return "", nil, s.reopenAfterFailedSwap(errors.Join(err, s.restoreLiveDir(previousDir)))
+	}
+
+	engine, err := s.open(s.dir)
+	if err != nil {
+		err = fmt.Errorf("failed to open replacement database: %w", err)
+		if moveErr := os.Rename(s.dir, replacementDir); moveErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to move replacement database back to %s: %w", replacementDir, moveErr))
+		}
+		return "", nil, s.reopenAfterFailedSwap(errors.Join(err, s.restoreLiveDir(previousDir)))
+	}
+	s.engine = engine
+	s.feed.restart(engine)
+
+	if err := s.index.Rebuild(engine); err != nil {
+		log.Printf("PHL_MEMORY: Failed to rebuild search index after swap: %v", err)
+	}
+	if err := s.vectors.Rebuild(engine, nil); err != nil {
+		log.Printf("PHL_MEMORY: Failed to rebuild vector index after swap: %v", err)
+	}
+
+	return previousDir, append([]func(){}, s.onSwap...), nil
+}
+
+// restoreLiveDir moves the database set aside at previousDir back into place
+// after a failed swap. Its error says where the original database was left.
+func (s *Storage) restoreLiveDir(previousDir string) error {
+	if err := os.Rename(previousDir, s.dir); err != nil {
+		return fmt.Errorf("failed to restore live database, it remains at %s: %w", previousDir, err)
 	}
+	return nil
+}
 
-	dbKey := []byte(fmt.Sprintf("%s:%s", layer, key))
-	return s.db.Update(func(txn *badger.Txn) error {
-		return txn.Set(dbKey, data)
+// reopenAfterFailedSwap reopens the original database after a swap was aborted
+func (s *Storage) reopenAfterFailedSwap(cause error) error {
+	engine, err := s.open(s.dir)
+	if err != nil {
+		return fmt.Errorf("%v; reopening original database also failed: %w", cause, err)
+	}
+	s.engine = engine
+	s.feed.restart(engine)
+	return cause
+}
+
+// Size returns the bytes the database takes on disk
+func (s *Storage) Size() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.engine.GetStats().TotalSize
+}
+
+// LayerCounts returns the number of live keys under each layer prefix
+func (s *Storage) LayerCounts() (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return countLayerKeys(s.engine)
+}
+
+// countLayerKeys counts the live keys in engine, grouped by layer
+func countLayerKeys(engine store.StorageEngine) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := forEachEntry(engine, func(layer, key string, value any) {
+		counts[layer]++
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count keys: %w", err)
+	}
+	return counts, nil
 }
 
-func (s *Storage) Retrieve(layer, key string) (any, error) {
-	var value any
-	dbKey := []byte(fmt.Sprintf("%s:%s", layer, key))
+// forEachEntry calls fn with every live entry in engine, layer by layer in key
+// order
+func forEachEntry(engine store.StorageEngine, fn func(layer, key string, value any)) error {
+	layers, err := engine.Layers()
+	if err != nil {
+		return err
+	}
 
-	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(dbKey)
+	for _, layer := range layers {
+		results, err := engine.List(layer, "", "", 0)
 		if err != nil {
 			return err
 		}
+		for _, result := range results {
+			fn(layer, result.Key, result.Value)
+		}
+	}
+	return nil
+}
 
-		return item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &value)
-		})
+// forEachMemory calls fn with every memory in engine, skipping system records
+// and values that can't be decoded
+func forEachMemory(engine store.StorageEngine, fn func(layer, key string, env *Envelope)) error {
+	return forEachEntry(engine, func(layer, key string, value any) {
+		if layer == systemLayer {
+			return
+		}
+		if env, err := envelopeFromValue(value); err == nil {
+			fn(layer, key, env)
+		}
 	})
+}
+
+// systemLayer is the reserved layer for the lattice's own records, such as
+// the route table. It can't collide with a memory layer and is skipped by the
+// search and vector indexes.
+const systemLayer = "_system"
 
-	if err == badger.ErrKeyNotFound {
+// SetSystem persists a system record as JSON under the reserved layer
+func (s *Storage) SetSystem(name string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal system record %s: %w", name, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.engine.Store(systemLayer, name, json.RawMessage(data)); err != nil {
+		return fmt.Errorf("failed to write system record %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetSystem decodes the named system record into out, reporting whether it
+// exists
+func (s *Storage) GetSystem(name string, out any) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return getSystem(s.engine, name, out)
+}
+
+// getSystem decodes the named system record in engine into out
+func getSystem(engine store.StorageEngine, name string, out any) (bool, error) {
+	value, err := engine.Retrieve(systemLayer, name)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read system record %s: %w", name, err)
+	}
+
+	if err := remarshal(value, out); err != nil {
+		return false, fmt.Errorf("failed to decode system record %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// remarshal decodes a value read back from the engine into out
+func remarshal(value any, out any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (s *Storage) Store(layer, key string, value any) error {
+	return s.StoreWithOptions(layer, key, value, StoreOptions{})
+}
+
+// StoreWithOptions persists a value wrapped in an Envelope carrying the given
+// metadata. Rewriting a key keeps its creation time and bumps its version. A
+// TTL is handed to the engine so the entry expires on its own.
+func (s *Storage) StoreWithOptions(layer, key string, value any, opts StoreOptions) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx, err := s.engine.BeginTx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous *Envelope
+	if value, err := tx.Retrieve(layer, key); err == nil {
+		previous, _ = envelopeFromValue(value)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to read previous value: %w", err)
+	}
+
+	data, err := json.Marshal(newEnvelope(value, opts, previous, time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if err := tx.StoreWithTTL(layer, key, json.RawMessage(data), opts.TTL); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Index the value as it will read back, so it matches a rebuilt index
+	if env, err := decodeEnvelope(data); err == nil {
+		s.indexEnvelope(layer, key, env)
+	}
+	return nil
+}
+
+// UpdateMeta rewrites the metadata of a stored memory without changing its
+// value, version or update time. The memory keeps whatever is left of its TTL.
+// Missing and expired memories are left alone.
+func (s *Storage) UpdateMeta(layer, key string, update func(env *Envelope)) error {
+	_, err := s.rewrite(layer, key, func(env *Envelope) error {
+		update(env)
+		return nil
+	}, false)
+	return err
+}
+
+// UpdateValue rewrites a stored memory in place, value included, and indexes
+// it again. Like UpdateMeta it keeps the version, update time and remaining
+// TTL, and leaves missing and expired memories alone, returning nil for them.
+// An error from update abandons the rewrite.
+func (s *Storage) UpdateValue(layer, key string, update func(env *Envelope) error) (*Envelope, error) {
+	return s.rewrite(layer, key, update, true)
+}
+
+// rewrite is a transactional read-modify-write of one stored envelope
+func (s *Storage) rewrite(layer, key string, update func(env *Envelope) error, reindex bool) (*Envelope, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx, err := s.engine.BeginTx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	value, err := tx.Retrieve(layer, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory: %w", err)
+	}
+	env, err := envelopeFromValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var ttl time.Duration
+	if !env.ExpiresAt.IsZero() {
+		if ttl = env.ExpiresAt.Sub(now); ttl <= 0 {
+			return nil, nil
+		}
+	}
+
+	// Values from before envelopes existed gain one
+	env.Format = envelopeFormat
+	if env.Version == 0 {
+		env.Version = 1
+	}
+	if err := update(env); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	if err := tx.StoreWithTTL(layer, key, json.RawMessage(data), ttl); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if reindex {
+		s.indexEnvelope(layer, key, env)
+	}
+	return env, nil
+}
+
+// indexEnvelope adds a stored memory to the search and vector indexes,
+// unless it is private
+func (s *Storage) indexEnvelope(layer, key string, env *Envelope) {
+	if env.Private {
+		return
+	}
+	s.index.Add(layer, key, env.Value, env.ExpiresAt)
+	if err := s.vectors.Add(layer, key, env.Value, env.ExpiresAt); err != nil {
+		log.Printf("PHL_MEMORY: %v", err)
+	}
+}
+
+func (s *Storage) Retrieve(layer, key string) (any, error) {
+	env, err := s.RetrieveEnvelope(layer, key)
+	if err != nil || env == nil {
+		return nil, err
+	}
+	return env.Value, nil
+}
+
+// RetrieveEnvelope returns a stored value together with its metadata, or nil
+// if the key doesn't exist. Values written before envelopes existed come back
+// in an envelope with only the value set.
+func (s *Storage) RetrieveEnvelope(layer, key string) (*Envelope, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, err := s.engine.Retrieve(layer, key)
+	if errors.Is(err, store.ErrNotFound) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve value: %w", err)
 	}
 
-	return value, nil
+	return envelopeFromValue(value)
+}
+
+// Delete removes a single memory. Deleting a key that doesn't exist is not
+// an error.
+func (s *Storage) Delete(layer, key string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.engine.Delete(layer, key); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to delete key %s:%s: %w", layer, key, err)
+	}
+
+	s.index.Remove(layer, key)
+	s.vectors.Remove(layer, key)
+	return nil
 }
 
 func (s *Storage) DeleteLayer(layer string) error {
-	prefix := []byte(fmt.Sprintf("%s:", layer))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results, err := s.engine.List(layer, "", "", 0)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.engine.BeginTx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	return s.db.Update(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		opts.PrefetchValues = false
+	for _, result := range results {
+		if err := tx.Delete(layer, result.Key); err != nil {
+			return fmt.Errorf("failed to delete key %s:%s: %w", layer, result.Key, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
-		it := txn.NewIterator(opts)
-		defer it.Close()
+	s.index.RemoveLayer(layer)
+	s.vectors.RemoveLayer(layer)
+	return nil
+}
+
+// Recall returns the k stored memories closest in meaning to text; see
+// VectorIndex.Nearest
+func (s *Storage) Recall(text string, k int) ([]RecallHit, error) {
+	return s.vectors.Nearest(text, k)
+}
+
+// SetEmbedder switches the embedder used for recall and re-embeds every
+// stored memory with it
+func (s *Storage) SetEmbedder(embedder Embedder) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.vectors.Rebuild(s.engine, embedder)
+}
+
+// Search ranks the stored memories matching query; see SearchIndex.Search
+func (s *Storage) Search(query string, layers []string, limit int) []SearchHit {
+	return s.index.Search(query, layers, limit)
+}
 
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			key := it.Item().Key()
-			if err := txn.Delete(key); err != nil {
-				return fmt.Errorf("failed to delete key %s: %w", key, err)
-			}
+// Entry is a decoded key/value pair read back from a layer
+type Entry struct {
+	Key   string
+	Value any
+	Meta  *Envelope // metadata recorded with the value
+}
+
+// List returns up to limit entries from a layer whose keys start with prefix,
+// in key order. A non-empty cursor resumes after the key it names. The returned
+// cursor is empty once the prefix has been exhausted.
+func (s *Storage) List(layer, prefix, cursor string, limit int) ([]Entry, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Fetch one extra entry to learn whether another page follows
+	fetch := 0
+	if limit > 0 {
+		fetch = limit + 1
+	}
+	results, err := s.engine.List(layer, prefix, cursor, fetch)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list layer %s: %w", layer, err)
+	}
+
+	next := ""
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+		next = results[limit-1].Key
+	}
+
+	entries := make([]Entry, 0, len(results))
+	for _, result := range results {
+		env, err := envelopeFromValue(result.Value)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode key %s:%s: %w", layer, result.Key, err)
 		}
-		return nil
-	})
+		entries = append(entries, Entry{Key: result.Key, Value: env.Value, Meta: env})
+	}
+
+	return entries, next, nil
 }
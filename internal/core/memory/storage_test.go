@@ -0,0 +1,310 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+)
+
+func TestLayerListing(t *testing.T) {
+	phl, err := NewPHL(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	for i := 0; i < 5; i++ {
+		if !phl.Store("logic", fmt.Sprintf("fact_%d", i), fmt.Sprintf("value %d", i)) {
+			t.Fatalf("Failed to store fact_%d", i)
+		}
+	}
+	if !phl.Store("logic", "other", "unrelated") {
+		t.Fatal("Failed to store unrelated key")
+	}
+	if !phl.Store("dream", "fact_dream", "elsewhere") {
+		t.Fatal("Failed to store dream key")
+	}
+
+	t.Run("Test paging with cursor", func(t *testing.T) {
+		var keys []string
+		cursor := ""
+		pages := 0
+		for {
+			entries, next, err := phl.List("logic", "fact_", cursor, 2)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			pages++
+			for _, entry := range entries {
+				keys = append(keys, entry.Key)
+				if entry.Value == nil {
+					t.Errorf("Entry %s has no decoded value", entry.Key)
+				}
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		if len(keys) != 5 {
+			t.Fatalf("Expected 5 keys, got %d: %v", len(keys), keys)
+		}
+		if pages != 3 {
+			t.Errorf("Expected 3 pages, got %d", pages)
+		}
+		for i, key := range keys {
+			if key != fmt.Sprintf("fact_%d", i) {
+				t.Errorf("Expected key fact_%d at position %d, got %s", i, i, key)
+			}
+		}
+	})
+
+	t.Run("Test unlimited listing", func(t *testing.T) {
+		entries, next, err := phl.List("logic", "", "", 0)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(entries) != 6 {
+			t.Errorf("Expected 6 entries, got %d", len(entries))
+		}
+		if next != "" {
+			t.Errorf("Expected empty cursor, got %s", next)
+		}
+	})
+
+	t.Run("Test invalid layer", func(t *testing.T) {
+		if _, _, err := phl.List("invalid", "", "", 10); err == nil {
+			t.Error("Expected error for invalid layer")
+		}
+	})
+
+	t.Run("Test eternal listing and stats", func(t *testing.T) {
+		emm := NewEternalMemoryManager(phl)
+		if !emm.StoreEternal("first_word", "Dad", 5) {
+			t.Fatal("Failed to store eternal memory")
+		}
+		if !emm.StoreEternal("first_light", "The Sun", 3) {
+			t.Fatal("Failed to store eternal memory")
+		}
+		// Private memories are left out of the listing and stats
+		if !phl.StoreWithOptions("eternal", "session_1", "transcript", StoreOptions{Private: true}) {
+			t.Fatal("Failed to store private memory")
+		}
+
+		memories, err := emm.ListEternalMemories()
+		if err != nil {
+			t.Fatalf("ListEternalMemories failed: %v", err)
+		}
+		if len(memories) != 2 {
+			t.Fatalf("Expected 2 eternal memories, got %d", len(memories))
+		}
+		for _, memory := range memories {
+			if memory.Key == "first_word" {
+				if memory.Data != "Dad" {
+					t.Errorf("Expected data 'Dad', got %v", memory.Data)
+				}
+				if memory.Importance != 5 {
+					t.Errorf("Expected importance 5, got %d", memory.Importance)
+				}
+				if memory.StoredAt.IsZero() {
+					t.Error("Expected stored time to be set")
+				}
+			}
+		}
+
+		stats := emm.GetEternalStats()
+		if stats.TotalMemories != 2 {
+			t.Errorf("Expected 2 total memories, got %d", stats.TotalMemories)
+		}
+		if stats.AverageImportance != 4 {
+			t.Errorf("Expected average importance 4, got %f", stats.AverageImportance)
+		}
+	})
+}
+
+func TestMemoryEnvelope(t *testing.T) {
+	phl, err := NewPHL(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	t.Run("Test metadata recorded", func(t *testing.T) {
+		opts := StoreOptions{Source: "test", Importance: 3}
+		if !phl.StoreWithOptions("logic", "fact", "first", opts) {
+			t.Fatal("Failed to store fact")
+		}
+		first, ok := phl.RetrieveEnvelope("logic", "fact")
+		if !ok {
+			t.Fatal("Failed to retrieve envelope")
+		}
+
+		if !phl.StoreWithOptions("logic", "fact", "second", opts) {
+			t.Fatal("Failed to rewrite fact")
+		}
+		second, ok := phl.RetrieveEnvelope("logic", "fact")
+		if !ok {
+			t.Fatal("Failed to retrieve envelope")
+		}
+
+		// The logic processor wraps the stored data
+		if record, ok := second.Value.(map[string]any); !ok || record["data"] != "second" {
+			t.Errorf("Expected data 'second', got %v", second.Value)
+		}
+		if second.Version != 2 {
+			t.Errorf("Expected version 2, got %d", second.Version)
+		}
+		if !second.CreatedAt.Equal(first.CreatedAt) {
+			t.Errorf("Creation time changed on rewrite: %v -> %v", first.CreatedAt, second.CreatedAt)
+		}
+		if second.UpdatedAt.Before(first.UpdatedAt) {
+			t.Error("Update time went backwards")
+		}
+		if second.Source != "test" || second.Importance != 3 {
+			t.Errorf("Unexpected metadata: source=%q importance=%d", second.Source, second.Importance)
+		}
+	})
+
+	t.Run("Test bare values still readable", func(t *testing.T) {
+		err := phl.GetStorage().GetDB().Update(func(txn *badger.Txn) error {
+			return txn.Set([]byte("dream:legacy"), []byte(`{"vision":"old"}`))
+		})
+		if err != nil {
+			t.Fatalf("Failed to write bare value: %v", err)
+		}
+
+		value, exists := phl.Retrieve("dream", "legacy")
+		if !exists {
+			t.Fatal("Bare value not found")
+		}
+		if record, ok := value.(map[string]any); !ok || record["vision"] != "old" {
+			t.Errorf("Unexpected bare value: %v", value)
+		}
+
+		if !phl.Store("dream", "legacy", "new") {
+			t.Fatal("Failed to overwrite bare value")
+		}
+		if env, _ := phl.RetrieveEnvelope("dream", "legacy"); env.Version != 1 || env.CreatedAt.IsZero() {
+			t.Errorf("Expected a fresh envelope over a bare value, got %+v", env)
+		}
+	})
+
+	t.Run("Test TTL expiry", func(t *testing.T) {
+		opts := StoreOptions{Source: "heartbeat", TTL: time.Second}
+		if !phl.StoreWithOptions("sensory", "heartbeat", "beat", opts) {
+			t.Fatal("Failed to store heartbeat")
+		}
+		if _, exists := phl.Retrieve("sensory", "heartbeat"); !exists {
+			t.Fatal("Heartbeat missing before expiry")
+		}
+
+		// Badger tracks expiry with one-second resolution
+		time.Sleep(2100 * time.Millisecond)
+
+		if _, exists := phl.Retrieve("sensory", "heartbeat"); exists {
+			t.Error("Heartbeat survived its TTL")
+		}
+	})
+}
+
+func TestStorageBackend(t *testing.T) {
+	t.Run("Test memory backend", func(t *testing.T) {
+		t.Setenv("PHL_STORAGE", "memory")
+		dataDir := t.TempDir()
+
+		phl, err := NewPHL(dataDir)
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		defer phl.Close()
+
+		if !phl.Store("eternal", "dad_love", "I love you, Dad") {
+			t.Fatal("Failed to store memory")
+		}
+		if _, ok := phl.Retrieve("eternal", "dad_love"); !ok {
+			t.Error("Failed to retrieve memory")
+		}
+		if hits, _ := phl.Search("love", nil, 10); len(hits) != 1 {
+			t.Errorf("Expected 1 search hit, got %d", len(hits))
+		}
+
+		if entries, _ := os.ReadDir(dataDir); len(entries) != 0 {
+			t.Errorf("Memory backend wrote to the data dir: %v", entries)
+		}
+		if err := phl.Backup(filepath.Join(t.TempDir(), "backup.json")); err != nil {
+			t.Errorf("Failed to back up: %v", err)
+		}
+	})
+
+	t.Run("Test SQLite backend", func(t *testing.T) {
+		t.Setenv("PHL_STORAGE", "sqlite")
+		dataDir := t.TempDir()
+
+		phl, err := NewPHL(dataDir)
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		if !phl.StoreWithOptions("logic", "math", "2 + 2 = 4", StoreOptions{Source: "cli"}) {
+			t.Fatal("Failed to store memory")
+		}
+		phl.Close()
+
+		// Memories survive a restart, metadata included
+		phl, err = NewPHL(dataDir)
+		if err != nil {
+			t.Fatalf("Failed to reopen PHL: %v", err)
+		}
+		defer phl.Close()
+
+		env, ok := phl.RetrieveEnvelope("logic", "math")
+		if !ok || env.Source != "cli" || env.Version != 1 {
+			t.Errorf("Failed to retrieve memory after reopening: %+v", env)
+		}
+		if _, err := os.Stat(filepath.Join(dataDir, latticeDir, sqliteFile)); err != nil {
+			t.Errorf("SQLite database missing: %v", err)
+		}
+		if err := phl.Backup(filepath.Join(t.TempDir(), "backup.db")); err != nil {
+			t.Errorf("Failed to back up: %v", err)
+		}
+	})
+
+	t.Run("Test unknown backend", func(t *testing.T) {
+		t.Setenv("PHL_STORAGE", "floppy")
+		if _, err := NewPHL(t.TempDir()); err == nil {
+			t.Error("Expected an unknown backend to be rejected")
+		}
+	})
+}
+
+func TestFailedSwap(t *testing.T) {
+	phl, err := NewPHL(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+	if !phl.Store("logic", "kept", "still here") {
+		t.Fatal("Failed to store memory")
+	}
+
+	t.Run("Test unopenable replacement is rolled back", func(t *testing.T) {
+		replacement := filepath.Join(t.TempDir(), "not_a_database")
+		if err := os.WriteFile(replacement, []byte("garbage"), 0644); err != nil {
+			t.Fatalf("Failed to write replacement: %v", err)
+		}
+
+		if _, err := phl.GetStorage().Swap(replacement); err == nil {
+			t.Fatal("Expected the swap to fail")
+		}
+		if _, err := os.Stat(replacement); err != nil {
+			t.Errorf("Expected the replacement to be moved back: %v", err)
+		}
+		if value, ok := phl.Retrieve("logic", "kept"); !ok || value.(map[string]any)["data"] != "still here" {
+			t.Errorf("Expected the live database to be restored, got %v", value)
+		}
+	})
+}
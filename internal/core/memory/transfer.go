@@ -0,0 +1,296 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/phoenix-marie/core/internal/core/memory/v2/store"
+)
+
+// exportPageSize is how many entries Export reads from the engine at a time
+const exportPageSize = 256
+
+// maxImportErrors caps the per-line errors kept in an ImportSummary
+const maxImportErrors = 10
+
+// Record is one memory in the portable JSONL export format
+type Record struct {
+	Layer    string          `json:"layer"`
+	Key      string          `json:"key"`
+	Value    any             `json:"value"`
+	Metadata *RecordMetadata `json:"metadata,omitempty"`
+}
+
+// RecordMetadata is the metadata an Envelope carries along with its value
+type RecordMetadata struct {
+	CreatedAt     time.Time     `json:"created_at,omitzero"`
+	UpdatedAt     time.Time     `json:"updated_at,omitzero"`
+	Source        string        `json:"source,omitempty"`
+	Version       uint64        `json:"version,omitempty"`
+	Importance    int           `json:"importance,omitempty"`
+	TTL           time.Duration `json:"ttl,omitempty"`
+	ExpiresAt     time.Time     `json:"expires_at,omitzero"`
+	SchemaVersion int           `json:"schema_version,omitempty"`
+}
+
+// ConflictPolicy decides what Import does with a record whose key already
+// holds a memory
+type ConflictPolicy string
+
+const (
+	ConflictSkip       ConflictPolicy = "skip"      // keep the existing memory
+	ConflictOverwrite  ConflictPolicy = "overwrite" // replace it with the record
+	ConflictNewestWins ConflictPolicy = "newest"    // keep whichever was updated last
+)
+
+// ParseConflictPolicy returns the policy with the given name
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(name); policy {
+	case ConflictSkip, ConflictOverwrite, ConflictNewestWins:
+		return policy, nil
+	case "newest-wins":
+		return ConflictNewestWins, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q (use skip, overwrite or newest)", name)
+	}
+}
+
+// ImportOptions controls an Import
+type ImportOptions struct {
+	Policy ConflictPolicy
+	DryRun bool // decide what would happen without writing anything
+}
+
+// ExportSummary counts the memories written by Export
+type ExportSummary struct {
+	Records int
+	Layers  map[string]int
+}
+
+// ImportSummary counts what Import did, or would do on a dry run, with the
+// records it read
+type ImportSummary struct {
+	Records     int
+	Created     int
+	Overwritten int
+	Skipped     int // kept the existing memory under the conflict policy
+	Expired     int // already past their expiry
+	Failed      int
+	Errors      []string // the first few failures, by line
+	DryRun      bool
+}
+
+// Export streams the memories of the given layers, or of every layer when
+// none are given, to w as one JSON Record per line. System records aren't
+// exported.
+func (s *Storage) Export(w io.Writer, layers []string) (ExportSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summary := ExportSummary{Layers: make(map[string]int)}
+	if len(layers) == 0 {
+		var err error
+		if layers, err = s.engine.Layers(); err != nil {
+			return summary, fmt.Errorf("failed to list layers: %w", err)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, layer := range layers {
+		if layer == systemLayer {
+			continue
+		}
+
+		after := ""
+		for {
+			results, err := s.engine.List(layer, "", after, exportPageSize)
+			if err != nil {
+				return summary, fmt.Errorf("failed to list layer %s: %w", layer, err)
+			}
+
+			for _, result := range results {
+				env, err := envelopeFromValue(result.Value)
+				if err != nil {
+					return summary, fmt.Errorf("failed to decode key %s:%s: %w", layer, result.Key, err)
+				}
+				if err := encoder.Encode(recordFromEnvelope(layer, result.Key, env)); err != nil {
+					return summary, fmt.Errorf("failed to write record: %w", err)
+				}
+				summary.Records++
+				summary.Layers[layer]++
+			}
+
+			if len(results) < exportPageSize {
+				break
+			}
+			after = results[len(results)-1].Key
+		}
+	}
+
+	return summary, nil
+}
+
+// Import reads JSON Records, one per line, from r and stores them with their
+// metadata, settling keys that already hold a memory by opts.Policy. Lines
+// that can't be imported are counted and skipped; the error returned is for
+// failures that stop the import as a whole.
+func (s *Storage) Import(r io.Reader, opts ImportOptions) (ImportSummary, error) {
+	if opts.Policy == "" {
+		opts.Policy = ConflictSkip
+	}
+	summary := ImportSummary{DryRun: opts.DryRun}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		summary.Records++
+
+		var record Record
+		err := json.Unmarshal(data, &record)
+		if err == nil {
+			err = s.importRecord(record, opts, &summary)
+		}
+		if err != nil {
+			summary.Failed++
+			if len(summary.Errors) < maxImportErrors {
+				summary.Errors = append(summary.Errors, fmt.Sprintf("line %d: %v", line, err))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, fmt.Errorf("failed to read records: %w", err)
+	}
+
+	return summary, nil
+}
+
+// importRecord stores a single record, counting the outcome in summary
+func (s *Storage) importRecord(record Record, opts ImportOptions, summary *ImportSummary) error {
+	if record.Layer == "" || record.Key == "" {
+		return fmt.Errorf("record needs a layer and a key")
+	}
+	if record.Layer == systemLayer {
+		return fmt.Errorf("system records can't be imported")
+	}
+
+	now := time.Now()
+	env := record.envelope(now)
+	if env.Expired(now) {
+		summary.Expired++
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx, err := s.engine.BeginTx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existing *Envelope
+	if value, err := tx.Retrieve(record.Layer, record.Key); err == nil {
+		existing, _ = envelopeFromValue(value)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("failed to read existing memory: %w", err)
+	}
+
+	if existing != nil {
+		replace := opts.Policy == ConflictOverwrite ||
+			(opts.Policy == ConflictNewestWins && env.UpdatedAt.After(existing.UpdatedAt))
+		if !replace {
+			summary.Skipped++
+			return nil
+		}
+		if record.Metadata == nil {
+			env.CreatedAt = existing.CreatedAt
+			env.Version = existing.Version + 1
+		}
+	}
+
+	if !opts.DryRun {
+		var ttl time.Duration
+		if !env.ExpiresAt.IsZero() {
+			ttl = env.ExpiresAt.Sub(now)
+		}
+		if err := tx.StoreWithTTL(record.Layer, record.Key, env, ttl); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		s.indexEnvelope(record.Layer, record.Key, env)
+	}
+
+	if existing != nil {
+		summary.Overwritten++
+	} else {
+		summary.Created++
+	}
+	return nil
+}
+
+// recordFromEnvelope turns a stored memory into an export record
+func recordFromEnvelope(layer, key string, env *Envelope) Record {
+	record := Record{Layer: layer, Key: key, Value: env.Value}
+	if env.Format > 0 {
+		record.Metadata = &RecordMetadata{
+			CreatedAt:     env.CreatedAt,
+			UpdatedAt:     env.UpdatedAt,
+			Source:        env.Source,
+			Version:       env.Version,
+			Importance:    env.Importance,
+			TTL:           env.TTL,
+			ExpiresAt:     env.ExpiresAt,
+			SchemaVersion: env.SchemaVersion,
+		}
+	}
+	return record
+}
+
+// envelope wraps the record's value for storage. A record without metadata
+// is treated as a memory written now.
+func (r Record) envelope(now time.Time) *Envelope {
+	if r.Metadata == nil {
+		env := newEnvelope(r.Value, StoreOptions{Source: "import"}, nil, now)
+		return &env
+	}
+
+	env := &Envelope{
+		Format:        envelopeFormat,
+		Value:         r.Value,
+		CreatedAt:     r.Metadata.CreatedAt,
+		UpdatedAt:     r.Metadata.UpdatedAt,
+		Source:        r.Metadata.Source,
+		Version:       r.Metadata.Version,
+		Importance:    r.Metadata.Importance,
+		TTL:           r.Metadata.TTL,
+		ExpiresAt:     r.Metadata.ExpiresAt,
+		SchemaVersion: r.Metadata.SchemaVersion,
+	}
+	if env.Version == 0 {
+		env.Version = 1
+	}
+	return env
+}
+
+// LayerNames returns the layers in an export summary, in order
+func (es ExportSummary) LayerNames() []string {
+	names := make([]string, 0, len(es.Layers))
+	for name := range es.Layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryTransfer(t *testing.T) {
+	source, err := NewPHL(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer source.Close()
+
+	source.StoreWithOptions("logic", "math", "2 + 2 = 4", StoreOptions{Source: "cli", Importance: 3})
+	source.StoreWithOptions("logic", "math", "2 + 2 = 4, checked", StoreOptions{Source: "cli", Importance: 3})
+	source.Store("dream", "flight", "soaring over the hive")
+	source.StoreWithOptions("sensory", "flash", "bright", StoreOptions{TTL: time.Hour})
+
+	var dump bytes.Buffer
+	t.Run("Test export", func(t *testing.T) {
+		summary, err := source.GetStorage().Export(&dump, nil)
+		if err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		if summary.Records != 3 || summary.Layers["logic"] != 1 {
+			t.Errorf("Unexpected export summary: %+v", summary)
+		}
+
+		lines := strings.Split(strings.TrimSpace(dump.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected 3 lines, got %d", len(lines))
+		}
+		for _, line := range lines {
+			var record Record
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("Failed to decode record %q: %v", line, err)
+			}
+			if record.Layer == systemLayer {
+				t.Error("System record was exported")
+			}
+		}
+
+		var logicOnly bytes.Buffer
+		if summary, _ := source.GetStorage().Export(&logicOnly, []string{"logic"}); summary.Records != 1 {
+			t.Errorf("Expected 1 logic record, got %d", summary.Records)
+		}
+	})
+
+	t.Run("Test import keeps metadata", func(t *testing.T) {
+		target, err := NewPHL(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		defer target.Close()
+
+		summary, err := target.GetStorage().Import(bytes.NewReader(dump.Bytes()), ImportOptions{})
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if summary.Created != 3 || summary.Failed != 0 {
+			t.Errorf("Unexpected import summary: %+v", summary)
+		}
+
+		original, _ := source.RetrieveEnvelope("logic", "math")
+		imported, ok := target.RetrieveEnvelope("logic", "math")
+		if !ok {
+			t.Fatal("Failed to retrieve imported memory")
+		}
+		if imported.Version != 2 || imported.Source != "cli" || imported.Importance != 3 ||
+			!imported.CreatedAt.Equal(original.CreatedAt) {
+			t.Errorf("Metadata lost in transfer: %+v", imported)
+		}
+		if flash, _ := target.RetrieveEnvelope("sensory", "flash"); flash == nil || flash.ExpiresAt.IsZero() {
+			t.Errorf("Expected the TTL to carry over, got %+v", flash)
+		}
+		if hits, _ := target.Search("soaring", nil, 10); len(hits) != 1 {
+			t.Errorf("Expected imported memory to be searchable, got %d hits", len(hits))
+		}
+	})
+
+	t.Run("Test conflict policies", func(t *testing.T) {
+		older := `{"layer":"logic","key":"math","value":"old","metadata":{"updated_at":"2020-01-01T00:00:00Z","version":1}}`
+		newer := `{"layer":"logic","key":"math","value":"new","metadata":{"updated_at":"2999-01-01T00:00:00Z","version":9}}`
+
+		tests := []struct {
+			policy ConflictPolicy
+			input  string
+			want   string
+		}{
+			{ConflictSkip, newer, "2 + 2 = 4, checked"},
+			{ConflictNewestWins, older, "2 + 2 = 4, checked"},
+			{ConflictNewestWins, newer, "new"},
+			{ConflictOverwrite, older, "old"},
+		}
+		for _, tt := range tests {
+			target, err := NewPHL(t.TempDir())
+			if err != nil {
+				t.Fatalf("Failed to create PHL: %v", err)
+			}
+			target.GetStorage().Import(bytes.NewReader(dump.Bytes()), ImportOptions{})
+
+			summary, err := target.GetStorage().Import(strings.NewReader(tt.input), ImportOptions{Policy: tt.policy})
+			if err != nil {
+				t.Fatalf("Failed to import: %v", err)
+			}
+			env, _ := target.RetrieveEnvelope("logic", "math")
+			got := env.Value
+			if record, ok := got.(map[string]any); ok {
+				got = record["data"]
+			}
+			if got != tt.want {
+				t.Errorf("%s: expected %q, got %v (%+v)", tt.policy, tt.want, got, summary)
+			}
+			target.Close()
+		}
+	})
+
+	t.Run("Test dry run and bad records", func(t *testing.T) {
+		target, err := NewPHL(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create PHL: %v", err)
+		}
+		defer target.Close()
+
+		input := dump.String() +
+			"not json\n" +
+			`{"layer":"","key":"nameless","value":1}` + "\n" +
+			`{"layer":"_system","key":"routes","value":{}}` + "\n" +
+			`{"layer":"dream","key":"gone","value":1,"metadata":{"expires_at":"2020-01-01T00:00:00Z"}}` + "\n"
+
+		summary, err := target.GetStorage().Import(strings.NewReader(input), ImportOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if summary.Records != 7 || summary.Created != 3 || summary.Failed != 3 || summary.Expired != 1 {
+			t.Errorf("Unexpected dry run summary: %+v", summary)
+		}
+		if len(summary.Errors) != 3 || !strings.HasPrefix(summary.Errors[0], "line 4:") {
+			t.Errorf("Unexpected errors: %v", summary.Errors)
+		}
+		if _, exists := target.Retrieve("dream", "flight"); exists {
+			t.Error("Dry run wrote a memory")
+		}
+	})
+
+	t.Run("Test policy names", func(t *testing.T) {
+		if policy, err := ParseConflictPolicy("newest-wins"); err != nil || policy != ConflictNewestWins {
+			t.Errorf("Expected newest-wins to parse, got %q, %v", policy, err)
+		}
+		if _, err := ParseConflictPolicy("merge"); err == nil {
+			t.Error("Expected an unknown policy to be rejected")
+		}
+	})
+}
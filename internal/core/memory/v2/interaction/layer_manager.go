@@ -91,8 +91,9 @@ func (lm *LayerManager) RegisterLayer(config LayerConfig) error {
 	return nil
 }
 
-// ProcessData processes data through a specific layer
-func (lm *LayerManager) ProcessData(layer string, data interface{}) error {
+// ProcessData processes data through a specific layer and stores the result
+// under key
+func (lm *LayerManager) ProcessData(layer, key string, data interface{}) error {
 	lm.mu.Lock()
 	defer lm.mu.Unlock()
 
@@ -118,7 +119,7 @@ func (lm *LayerManager) ProcessData(layer string, data interface{}) error {
 	}
 
 	// Store processed data
-	if err := lm.store.Store(layer, processed.Metadata["key"].(string), processed.Data); err != nil {
+	if err := lm.store.Store(layer, key, processed.Data); err != nil {
 		lm.updateMetrics(layer, time.Since(startTime), err)
 		return fmt.Errorf("storage failed: %w", err)
 	}
@@ -58,11 +58,13 @@ func (bp *BaseProcessor) Process(data any) (ProcessedData, error) {
 		}
 	}
 
-	// Process the data (to be implemented by specific processors)
+	// Process the data (to be implemented by specific processors). Passing
+	// data through unchanged loses nothing, so confidence is full.
 	processed := ProcessedData{
-		Data:      data,
-		Metadata:  make(map[string]interface{}),
-		Timestamp: time.Now(),
+		Data:       data,
+		Metadata:   make(map[string]interface{}),
+		Timestamp:  time.Now(),
+		Confidence: 1.0,
 	}
 
 	// Update metrics
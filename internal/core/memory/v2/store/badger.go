@@ -1,53 +1,44 @@
 package store
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	badger "github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/badger/v3/pb"
 )
 
-// Transaction defines the interface for storage transactions
-type Transaction interface {
-	Store(layer, key string, value any) error
-	Retrieve(layer, key string) (any, error)
-	Delete(layer, key string) error
-	Commit() error
-	Rollback() error
-}
+// Ensure BadgerStore satisfies the engine interfaces
+var (
+	_ StorageEngine = (*BadgerStore)(nil)
+	_ Streamer      = (*BadgerStore)(nil)
+	_ Subscriber    = (*BadgerStore)(nil)
+)
+
+// subscribeProbe is the key Subscribe deletes to learn when its subscription
+// is live. It has no ':', so it is outside every layer, and streams skip it.
+var subscribeProbe = []byte("!subscribe-probe")
 
-// BadgerStore implements the StorageEngine interface using BadgerDB
+// subscribeProbeWait bounds how long Subscribe waits to see its probe
+const subscribeProbeWait = time.Second
+
+// BadgerStore implements the StorageEngine interface using BadgerDB. Entries
+// are kept under "layer:key" with JSON values.
 type BadgerStore struct {
 	db      *badger.DB
 	options *badger.Options
-}
-
-// StoreOperation represents a single store operation for batch processing
-type StoreOperation struct {
-	Layer string
-	Key   string
-	Value any
-}
-
-// Query represents a single retrieval query
-type Query struct {
-	Layer string
-	Key   string
-}
 
-// QueryResult represents the result of a query operation
-type QueryResult struct {
-	Value any
-	Error error
-}
-
-// StorageStats contains metrics about the storage engine
-type StorageStats struct {
-	ItemCount     uint64
-	LSMSize       int64
-	VLogSize      int64
-	PendingWrites int64
+	operations atomic.Int64
+	errors     atomic.Int64
 }
 
 // NewBadgerStore creates a new BadgerDB storage instance
@@ -57,6 +48,7 @@ func NewBadgerStore(path string) (*BadgerStore, error) {
 	opts.NumLevelZeroTables = 3
 	opts.NumMemtables = 2
 	opts.ValueLogFileSize = 1 << 28 // 256MB
+	opts.Logger = nil               // Disable Badger's internal logger
 
 	db, err := badger.Open(opts)
 	if err != nil {
@@ -69,48 +61,98 @@ func NewBadgerStore(path string) (*BadgerStore, error) {
 	}, nil
 }
 
+// DB returns the underlying BadgerDB instance (for advanced operations)
+func (bs *BadgerStore) DB() *badger.DB {
+	return bs.db
+}
+
+// compositeKey returns the database key of a layer entry
+func compositeKey(layer, key string) []byte {
+	return []byte(layer + ":" + key)
+}
+
+// validateKey rejects entries that can't be addressed as "layer:key"
+func validateKey(layer, key string) error {
+	if layer == "" || strings.Contains(layer, ":") {
+		return fmt.Errorf("invalid layer %q", layer)
+	}
+	if key == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+	return nil
+}
+
+// track counts an operation and, if it failed, an error
+func (bs *BadgerStore) track(err error) error {
+	bs.operations.Add(1)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		bs.errors.Add(1)
+	}
+	return err
+}
+
 // Store implements the Store method of StorageEngine
 func (bs *BadgerStore) Store(layer, key string, value any) error {
+	if err := validateKey(layer, key); err != nil {
+		return bs.track(err)
+	}
+
 	data, err := json.Marshal(value)
 	if err != nil {
-		return fmt.Errorf("failed to marshal value: %w", err)
+		return bs.track(fmt.Errorf("failed to marshal value: %w", err))
 	}
 
-	compositeKey := fmt.Sprintf("%s:%s", layer, key)
-	return bs.db.Update(func(txn *badger.Txn) error {
-		return txn.Set([]byte(compositeKey), data)
-	})
+	return bs.track(bs.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(compositeKey(layer, key), data)
+	}))
 }
 
 // Retrieve implements the Retrieve method of StorageEngine
 func (bs *BadgerStore) Retrieve(layer, key string) (any, error) {
-	var value any
-	compositeKey := fmt.Sprintf("%s:%s", layer, key)
+	if err := validateKey(layer, key); err != nil {
+		return nil, bs.track(err)
+	}
 
+	var value any
 	err := bs.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(compositeKey))
-		if err != nil {
-			return err
-		}
-
-		return item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &value)
-		})
+		var err error
+		value, err = getValue(txn, compositeKey(layer, key))
+		return err
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve value: %w", err)
+		return nil, bs.track(fmt.Errorf("failed to retrieve value: %w", err))
 	}
 
-	return value, nil
+	return value, bs.track(nil)
+}
+
+// getValue reads and decodes one entry within a transaction
+func getValue(txn *badger.Txn, dbKey []byte) (any, error) {
+	item, err := txn.Get(dbKey)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &value)
+	})
+	return value, err
 }
 
 // Delete implements the Delete method of StorageEngine
 func (bs *BadgerStore) Delete(layer, key string) error {
-	compositeKey := fmt.Sprintf("%s:%s", layer, key)
-	return bs.db.Update(func(txn *badger.Txn) error {
-		return txn.Delete([]byte(compositeKey))
-	})
+	if err := validateKey(layer, key); err != nil {
+		return bs.track(err)
+	}
+
+	return bs.track(bs.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(compositeKey(layer, key))
+	}))
 }
 
 // BatchStore implements the BatchStore method of StorageEngine
@@ -119,18 +161,25 @@ func (bs *BadgerStore) BatchStore(operations []StoreOperation) error {
 	defer wb.Cancel()
 
 	for _, op := range operations {
+		if err := validateKey(op.Layer, op.Key); err != nil {
+			return bs.track(err)
+		}
+
 		data, err := json.Marshal(op.Value)
 		if err != nil {
-			return fmt.Errorf("failed to marshal value for key %s: %w", op.Key, err)
+			return bs.track(fmt.Errorf("failed to marshal value for key %s: %w", op.Key, err))
 		}
 
-		compositeKey := fmt.Sprintf("%s:%s", op.Layer, op.Key)
-		if err := wb.Set([]byte(compositeKey), data); err != nil {
-			return fmt.Errorf("failed to batch set key %s: %w", compositeKey, err)
+		entry := badger.NewEntry(compositeKey(op.Layer, op.Key), data)
+		if op.TTL > 0 {
+			entry = entry.WithTTL(op.TTL)
+		}
+		if err := wb.SetEntry(entry); err != nil {
+			return bs.track(fmt.Errorf("failed to batch set key %s:%s: %w", op.Layer, op.Key, err))
 		}
 	}
 
-	return wb.Flush()
+	return bs.track(wb.Flush())
 }
 
 // BatchRetrieve implements the BatchRetrieve method of StorageEngine
@@ -139,39 +188,122 @@ func (bs *BadgerStore) BatchRetrieve(queries []Query) ([]QueryResult, error) {
 
 	err := bs.db.View(func(txn *badger.Txn) error {
 		for i, query := range queries {
-			compositeKey := fmt.Sprintf("%s:%s", query.Layer, query.Key)
-			item, err := txn.Get([]byte(compositeKey))
-			if err != nil {
-				results[i] = QueryResult{nil, err}
+			value, err := getValue(txn, compositeKey(query.Layer, query.Key))
+			results[i] = QueryResult{Key: query.Key, Value: value, Error: err}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, bs.track(fmt.Errorf("batch retrieve failed: %w", err))
+	}
+
+	return results, bs.track(nil)
+}
+
+// BatchRetrieveByPrefix implements the BatchRetrieveByPrefix method of
+// StorageEngine
+func (bs *BadgerStore) BatchRetrieveByPrefix(layer, prefix string, limit int) (map[string]any, error) {
+	results, err := bs.List(layer, prefix, "", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(results))
+	for _, result := range results {
+		values[result.Key] = result.Value
+	}
+	return values, nil
+}
+
+// List returns up to limit entries of a layer whose keys start with prefix,
+// in key order, beginning after the key named by after. A limit of zero or
+// less returns every match.
+func (bs *BadgerStore) List(layer, prefix, after string, limit int) ([]QueryResult, error) {
+	layerPrefix := layer + ":"
+	scanPrefix := []byte(layerPrefix + prefix)
+
+	seek := scanPrefix
+	if afterKey := compositeKey(layer, after); after != "" && bytes.Compare(afterKey, seek) > 0 {
+		seek = afterKey
+	}
+
+	var results []QueryResult
+	err := bs.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(seek); it.ValidForPrefix(scanPrefix); it.Next() {
+			if limit > 0 && len(results) == limit {
+				return nil
+			}
+
+			item := it.Item()
+			key := strings.TrimPrefix(string(item.Key()), layerPrefix)
+			if after != "" && key <= after {
 				continue
 			}
 
 			var value any
-			err = item.Value(func(val []byte) error {
+			if err := item.Value(func(val []byte) error {
 				return json.Unmarshal(val, &value)
-			})
-
-			results[i] = QueryResult{value, err}
+			}); err != nil {
+				return fmt.Errorf("failed to decode key %s: %w", item.Key(), err)
+			}
+			results = append(results, QueryResult{Key: key, Value: value})
 		}
 		return nil
 	})
+	if err != nil {
+		return nil, bs.track(fmt.Errorf("failed to list layer %s: %w", layer, err))
+	}
+
+	return results, bs.track(nil)
+}
+
+// Layers returns the layers holding at least one entry, in order
+func (bs *BadgerStore) Layers() ([]string, error) {
+	var layers []string
+	err := bs.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); {
+			layer, _, ok := strings.Cut(string(it.Item().Key()), ":")
+			if !ok {
+				it.Next()
+				continue
+			}
+			layers = append(layers, layer)
 
+			// ';' sorts right after ':', so this skips the rest of the layer
+			it.Seek([]byte(layer + ";"))
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("batch retrieve failed: %w", err)
+		return nil, bs.track(fmt.Errorf("failed to list layers: %w", err))
 	}
 
-	return results, nil
+	return layers, bs.track(nil)
 }
 
 // BeginTx implements the BeginTx method of StorageEngine
 func (bs *BadgerStore) BeginTx() (Transaction, error) {
 	txn := bs.db.NewTransaction(true)
-	return &BadgerTransaction{txn: txn}, nil
+	return &BadgerTransaction{txn: txn, store: bs}, nil
 }
 
 // Compact implements the Compact method of StorageEngine
 func (bs *BadgerStore) Compact() error {
-	return bs.db.RunValueLogGC(0.5)
+	err := bs.db.RunValueLogGC(0.5)
+	if errors.Is(err, badger.ErrNoRewrite) {
+		return nil // Nothing worth reclaiming
+	}
+	return err
 }
 
 // Backup implements the Backup method of StorageEngine
@@ -182,7 +314,7 @@ func (bs *BadgerStore) Backup(path string) error {
 	}
 	defer file.Close()
 
-	_, err = bs.db.Backup(file, 0)
+	_, err = bs.StreamTo(file, 0)
 	if err != nil {
 		return fmt.Errorf("failed to create backup: %w", err)
 	}
@@ -190,44 +322,138 @@ func (bs *BadgerStore) Backup(path string) error {
 	return nil
 }
 
+// StreamTo implements the StreamTo method of Streamer
+func (bs *BadgerStore) StreamTo(w io.Writer, since uint64) (uint64, error) {
+	stream := bs.db.NewStream()
+	stream.LogPrefix = "BadgerStore.StreamTo"
+	stream.SinceTs = since
+	stream.ChooseKey = func(item *badger.Item) bool {
+		return !bytes.Equal(item.Key(), subscribeProbe)
+	}
+	return stream.Backup(w, since)
+}
+
+// Load implements the Load method of Streamer
+func (bs *BadgerStore) Load(r io.Reader) error {
+	return bs.db.Load(r, 256)
+}
+
+// Subscribe implements the Subscribe method of Subscriber on top of Badger's
+// own Subscribe. Badger registers a subscription on a goroutine without
+// saying when it is live, so this deletes a probe key, which touches no
+// layer, and waits a while for the subscription to report it.
+func (bs *BadgerStore) Subscribe(ctx context.Context, fn func([]Change)) error {
+	ready := make(chan struct{})
+	var readyOnce sync.Once
+	started := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		close(started)
+		done <- bs.db.Subscribe(ctx, func(list *badger.KVList) error {
+			changes := make([]Change, 0, len(list.Kv))
+			for _, kv := range list.Kv {
+				if bytes.Equal(kv.Key, subscribeProbe) {
+					readyOnce.Do(func() { close(ready) })
+					continue
+				}
+				if change, ok := newChange(string(kv.Key), kv.Value, kv.Version); ok {
+					changes = append(changes, change)
+				}
+			}
+			if len(changes) > 0 {
+				sortChanges(changes)
+				fn(changes)
+			}
+			return nil
+		}, []pb.Match{{Prefix: []byte{}}})
+	}()
+
+	<-started
+	err := bs.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(subscribeProbe)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to probe subscription: %w", err)
+	}
+
+	timer := time.NewTimer(subscribeProbeWait)
+	defer timer.Stop()
+	select {
+	case <-ready:
+	case err := <-done:
+		return fmt.Errorf("failed to subscribe: %w", err)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		// The probe went out before the subscription was registered; it is
+		// live by now, so carry on rather than probe again
+	}
+	return nil
+}
+
+// Close closes the database
+func (bs *BadgerStore) Close() error {
+	return bs.db.Close()
+}
+
 // GetStats implements the GetStats method of StorageEngine
 func (bs *BadgerStore) GetStats() StorageStats {
 	lsmSize, vlogSize := bs.db.Size()
-	return StorageStats{
-		LSMSize:       lsmSize,
-		VLogSize:      vlogSize,
-		PendingWrites: bs.db.MaxBatchCount(),
+	stats := StorageStats{
+		TotalSize:      lsmSize + vlogSize,
+		OperationCount: bs.operations.Load(),
+		ErrorCount:     bs.errors.Load(),
 	}
+
+	bs.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			stats.TotalEntries++
+		}
+		return nil
+	})
+
+	if metrics := bs.db.BlockCacheMetrics(); metrics != nil {
+		stats.CacheHitRate = metrics.Ratio()
+	}
+	return stats
 }
 
 // BadgerTransaction implements the Transaction interface
 type BadgerTransaction struct {
-	txn *badger.Txn
+	txn   *badger.Txn
+	store *BadgerStore
+	done  bool
 }
 
 func (bt *BadgerTransaction) Store(layer, key string, value any) error {
+	return bt.StoreWithTTL(layer, key, value, 0)
+}
+
+func (bt *BadgerTransaction) StoreWithTTL(layer, key string, value any, ttl time.Duration) error {
+	if err := validateKey(layer, key); err != nil {
+		return err
+	}
+
 	data, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
 
-	compositeKey := fmt.Sprintf("%s:%s", layer, key)
-	return bt.txn.Set([]byte(compositeKey), data)
+	entry := badger.NewEntry(compositeKey(layer, key), data)
+	if ttl > 0 {
+		entry = entry.WithTTL(ttl)
+	}
+	return bt.txn.SetEntry(entry)
 }
 
 func (bt *BadgerTransaction) Retrieve(layer, key string) (any, error) {
-	var value any
-	compositeKey := fmt.Sprintf("%s:%s", layer, key)
-
-	item, err := bt.txn.Get([]byte(compositeKey))
-	if err != nil {
-		return nil, err
-	}
-
-	err = item.Value(func(val []byte) error {
-		return json.Unmarshal(val, &value)
-	})
-
+	value, err := getValue(bt.txn, compositeKey(layer, key))
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve value: %w", err)
 	}
@@ -236,15 +462,27 @@ func (bt *BadgerTransaction) Retrieve(layer, key string) (any, error) {
 }
 
 func (bt *BadgerTransaction) Delete(layer, key string) error {
-	compositeKey := fmt.Sprintf("%s:%s", layer, key)
-	return bt.txn.Delete([]byte(compositeKey))
+	if err := validateKey(layer, key); err != nil {
+		return err
+	}
+	return bt.txn.Delete(compositeKey(layer, key))
 }
 
 func (bt *BadgerTransaction) Commit() error {
-	return bt.txn.Commit()
+	if bt.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	bt.done = true
+
+	err := bt.txn.Commit()
+	if errors.Is(err, badger.ErrConflict) {
+		err = ErrConflict
+	}
+	return bt.store.track(err)
 }
 
 func (bt *BadgerTransaction) Rollback() error {
+	bt.done = true
 	bt.txn.Discard()
 	return nil
 }
@@ -0,0 +1,343 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3/pb"
+)
+
+func TestBadgerStore(t *testing.T) {
+	testEngine(t, func(t *testing.T) StorageEngine {
+		engine, err := NewBadgerStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create Badger store: %v", err)
+		}
+		return engine
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testEngine(t, func(t *testing.T) StorageEngine {
+		return NewMemoryStore()
+	})
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testEngine(t, func(t *testing.T) StorageEngine {
+		engine, err := NewSQLiteStore(filepath.Join(t.TempDir(), "lattice.db"))
+		if err != nil {
+			t.Fatalf("Failed to create SQLite store: %v", err)
+		}
+		return engine
+	})
+
+	t.Run("Test layers are queryable with SQL", func(t *testing.T) {
+		dir := t.TempDir()
+		engine, err := NewSQLiteStore(filepath.Join(dir, "lattice.db"))
+		if err != nil {
+			t.Fatalf("Failed to create SQLite store: %v", err)
+		}
+		defer engine.Close()
+
+		engine.Store("eternal", "dad_love", map[string]any{"value": "I love you, Dad"})
+		backupPath := filepath.Join(dir, "backup.db")
+		if err := engine.Backup(backupPath); err != nil {
+			t.Fatalf("Failed to back up: %v", err)
+		}
+
+		backup, err := NewSQLiteStore(backupPath)
+		if err != nil {
+			t.Fatalf("Failed to open backup: %v", err)
+		}
+		defer backup.Close()
+
+		var value string
+		err = backup.DB().QueryRow(`SELECT json_extract(value, '$.value') FROM layer_eternal
+			WHERE key = 'dad_love' AND updated_at <= ?`, time.Now().UnixMilli()).Scan(&value)
+		if err != nil {
+			t.Fatalf("Failed to query backup: %v", err)
+		}
+		if value != "I love you, Dad" {
+			t.Errorf("Expected the stored value, got %q", value)
+		}
+	})
+}
+
+// testEngine checks the behaviour every StorageEngine has to share
+func testEngine(t *testing.T, open func(t *testing.T) StorageEngine) {
+	t.Run("Test store and retrieve", func(t *testing.T) {
+		engine := open(t)
+		defer engine.Close()
+
+		value := map[string]any{"feeling": "joy", "intensity": 0.9, "tags": []any{"dad"}}
+		if err := engine.Store("emotion", "now", value); err != nil {
+			t.Fatalf("Failed to store value: %v", err)
+		}
+		retrieved, err := engine.Retrieve("emotion", "now")
+		if err != nil {
+			t.Fatalf("Failed to retrieve value: %v", err)
+		}
+		if !reflect.DeepEqual(retrieved, value) {
+			t.Errorf("Expected %v, got %v", value, retrieved)
+		}
+
+		if _, err := engine.Retrieve("emotion", "missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if err := engine.Delete("emotion", "now"); err != nil {
+			t.Fatalf("Failed to delete value: %v", err)
+		}
+		if _, err := engine.Retrieve("emotion", "now"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after delete, got %v", err)
+		}
+
+		for _, bad := range [][2]string{{"", "key"}, {"a:b", "key"}, {"layer", ""}} {
+			if err := engine.Store(bad[0], bad[1], "x"); err == nil {
+				t.Errorf("Expected %q/%q to be rejected", bad[0], bad[1])
+			}
+		}
+	})
+
+	t.Run("Test batch operations", func(t *testing.T) {
+		engine := open(t)
+		defer engine.Close()
+
+		err := engine.BatchStore([]StoreOperation{
+			{Layer: "logic", Key: "a", Value: "first"},
+			{Layer: "logic", Key: "b", Value: float64(2)},
+		})
+		if err != nil {
+			t.Fatalf("Failed to batch store: %v", err)
+		}
+
+		results, err := engine.BatchRetrieve([]Query{{Layer: "logic", Key: "a"}, {Layer: "logic", Key: "c"}})
+		if err != nil {
+			t.Fatalf("Failed to batch retrieve: %v", err)
+		}
+		if results[0].Value != "first" || results[0].Error != nil {
+			t.Errorf("Unexpected result for a: %+v", results[0])
+		}
+		if !errors.Is(results[1].Error, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for c, got %+v", results[1])
+		}
+	})
+
+	t.Run("Test ordered scans", func(t *testing.T) {
+		engine := open(t)
+		defer engine.Close()
+
+		var ops []StoreOperation
+		for i := 0; i < 5; i++ {
+			ops = append(ops, StoreOperation{Layer: "dream", Key: fmt.Sprintf("night_%d", i), Value: i})
+		}
+		ops = append(ops,
+			StoreOperation{Layer: "dream", Key: "day_0", Value: 0},
+			StoreOperation{Layer: "dreamy", Key: "night_9", Value: 9},
+			StoreOperation{Layer: "eternal", Key: "core", Value: "identity"},
+		)
+		if err := engine.BatchStore(ops); err != nil {
+			t.Fatalf("Failed to batch store: %v", err)
+		}
+
+		page, err := engine.List("dream", "night_", "night_1", 2)
+		if err != nil {
+			t.Fatalf("Failed to list: %v", err)
+		}
+		if len(page) != 2 || page[0].Key != "night_2" || page[1].Key != "night_3" {
+			t.Errorf("Unexpected page: %+v", page)
+		}
+
+		values, err := engine.BatchRetrieveByPrefix("dream", "night_", 0)
+		if err != nil {
+			t.Fatalf("Failed to retrieve by prefix: %v", err)
+		}
+		if len(values) != 5 || values["night_4"] != float64(4) {
+			t.Errorf("Unexpected prefix results: %v", values)
+		}
+
+		layers, err := engine.Layers()
+		if err != nil {
+			t.Fatalf("Failed to list layers: %v", err)
+		}
+		if !reflect.DeepEqual(layers, []string{"dream", "dreamy", "eternal"}) {
+			t.Errorf("Unexpected layers: %v", layers)
+		}
+	})
+
+	t.Run("Test TTL", func(t *testing.T) {
+		engine := open(t)
+		defer engine.Close()
+
+		err := engine.BatchStore([]StoreOperation{
+			{Layer: "sensory", Key: "flash", Value: "bright", TTL: time.Second},
+			{Layer: "sensory", Key: "steady", Value: "calm"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to batch store: %v", err)
+		}
+		if _, err := engine.Retrieve("sensory", "flash"); err != nil {
+			t.Fatalf("Failed to retrieve entry before it expired: %v", err)
+		}
+
+		time.Sleep(2 * time.Second)
+		if _, err := engine.Retrieve("sensory", "flash"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected expired entry to be gone, got %v", err)
+		}
+		if results, _ := engine.List("sensory", "", "", 0); len(results) != 1 {
+			t.Errorf("Expected only the unexpired entry to be listed, got %+v", results)
+		}
+	})
+
+	t.Run("Test transactions", func(t *testing.T) {
+		engine := open(t)
+		defer engine.Close()
+
+		tx, err := engine.BeginTx()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		tx.Store("logic", "pending", "value")
+		if value, err := tx.Retrieve("logic", "pending"); err != nil || value != "value" {
+			t.Errorf("Transaction can't read its own write: %v, %v", value, err)
+		}
+		if _, err := engine.Retrieve("logic", "pending"); !errors.Is(err, ErrNotFound) {
+			t.Error("Uncommitted write is visible outside the transaction")
+		}
+		tx.Rollback()
+		if _, err := engine.Retrieve("logic", "pending"); !errors.Is(err, ErrNotFound) {
+			t.Error("Rolled back write was stored")
+		}
+
+		tx, _ = engine.BeginTx()
+		tx.Store("logic", "committed", "value")
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		if err := tx.Commit(); err == nil {
+			t.Error("Expected a second commit to fail")
+		}
+		if _, err := engine.Retrieve("logic", "committed"); err != nil {
+			t.Errorf("Committed write is missing: %v", err)
+		}
+
+		// A key read by one transaction and written by another conflicts
+		first, _ := engine.BeginTx()
+		second, _ := engine.BeginTx()
+		first.Retrieve("logic", "committed")
+		first.Store("logic", "committed", "first")
+		second.Store("logic", "committed", "second")
+		if err := second.Commit(); err != nil {
+			t.Fatalf("Failed to commit second transaction: %v", err)
+		}
+		if err := first.Commit(); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict, got %v", err)
+		}
+		if value, _ := engine.Retrieve("logic", "committed"); value != "second" {
+			t.Errorf("Expected the second write to win, got %v", value)
+		}
+	})
+
+	t.Run("Test subscriptions", func(t *testing.T) {
+		engine := open(t)
+		defer engine.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		received := make(chan Change, 16)
+		err := engine.(Subscriber).Subscribe(ctx, func(changes []Change) {
+			for _, change := range changes {
+				received <- change
+			}
+		})
+		if err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+
+		engine.Store("emotion", "joy", map[string]any{"intensity": 0.8})
+		tx, _ := engine.BeginTx()
+		tx.Store("emotion", "calm", "steady")
+		tx.Delete("emotion", "joy")
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+
+		var changes []Change
+		for len(changes) < 3 {
+			select {
+			case change := <-received:
+				changes = append(changes, change)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Timed out waiting for changes, got %+v", changes)
+			}
+		}
+		if changes[0].Key != "joy" || changes[0].Deleted || !reflect.DeepEqual(changes[0].Value, map[string]any{"intensity": 0.8}) {
+			t.Errorf("Unexpected put: %+v", changes[0])
+		}
+		if changes[1].Key != "calm" || changes[1].Value != "steady" || changes[2].Key != "joy" || !changes[2].Deleted {
+			t.Errorf("Unexpected transaction changes: %+v", changes[1:])
+		}
+		if changes[1].Version != changes[2].Version || changes[1].Version <= changes[0].Version {
+			t.Errorf("Expected one version per commit, got %+v", changes)
+		}
+
+		// Whatever the engine writes to set up a subscription stays out of streams
+		if streamer, ok := engine.(Streamer); ok {
+			var buf bytes.Buffer
+			if _, err := streamer.StreamTo(&buf, 0); err != nil {
+				t.Fatalf("Failed to stream: %v", err)
+			}
+			err := ScanStream(&buf, func(kv *pb.KV) error {
+				if !strings.Contains(string(kv.Key), ":") {
+					t.Errorf("Unexpected key outside every layer in stream: %q", kv.Key)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to scan stream: %v", err)
+			}
+		}
+
+		// Nothing is reported once the context is done
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+		engine.Store("emotion", "late", "unseen")
+		select {
+		case change := <-received:
+			t.Errorf("Change reported after cancel: %+v", change)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Test stats and backup", func(t *testing.T) {
+		engine := open(t)
+		defer engine.Close()
+
+		engine.Store("eternal", "a", "one")
+		engine.Store("eternal", "b", "two")
+		engine.Retrieve("eternal", "missing")
+		engine.Store("", "bad", "x")
+
+		stats := engine.GetStats()
+		if stats.TotalEntries != 2 {
+			t.Errorf("Expected 2 entries, got %d", stats.TotalEntries)
+		}
+		if stats.OperationCount != 4 || stats.ErrorCount != 1 {
+			t.Errorf("Expected 4 operations and 1 error, got %+v", stats)
+		}
+
+		if err := engine.Compact(); err != nil {
+			t.Errorf("Failed to compact: %v", err)
+		}
+		if err := engine.Backup(filepath.Join(t.TempDir(), "backup")); err != nil {
+			t.Errorf("Failed to back up: %v", err)
+		}
+	})
+}
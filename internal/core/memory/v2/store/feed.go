@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// changeFeed hands committed writes to subscribers, for engines that commit
+// in process
+type changeFeed struct {
+	mu   sync.RWMutex
+	next int
+	subs map[int]func([]Change)
+}
+
+// subscribe adds fn to the feed until ctx is done
+func (f *changeFeed) subscribe(ctx context.Context, fn func([]Change)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs == nil {
+		f.subs = make(map[int]func([]Change))
+	}
+	id := f.next
+	f.next++
+	f.subs[id] = fn
+
+	context.AfterFunc(ctx, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, id)
+	})
+}
+
+// active reports whether anyone is subscribed, so writers can skip building
+// changes nobody will see
+func (f *changeFeed) active() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.subs) > 0
+}
+
+// publish hands changes to every subscriber
+func (f *changeFeed) publish(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	f.mu.RLock()
+	subs := make([]func([]Change), 0, len(f.subs))
+	for _, fn := range f.subs {
+		subs = append(subs, fn)
+	}
+	f.mu.RUnlock()
+
+	for _, fn := range subs {
+		fn(changes)
+	}
+}
+
+// close drops every subscriber
+func (f *changeFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subs = nil
+}
+
+// newChange describes a write of data, JSON, to the entry at dbKey. Nil data
+// is a delete. ok is false for keys outside the "layer:key" space and values
+// that don't decode.
+func newChange(dbKey string, data []byte, version uint64) (Change, bool) {
+	layer, key, found := strings.Cut(dbKey, ":")
+	if !found {
+		return Change{}, false
+	}
+
+	change := Change{Layer: layer, Key: key, Version: version}
+	if len(data) == 0 {
+		change.Deleted = true
+		return change, true
+	}
+
+	value, err := decodeValue(data)
+	if err != nil {
+		return Change{}, false
+	}
+	change.Value = value
+	return change, true
+}
+
+// sortChanges orders changes by commit, then by layer and key within one
+func sortChanges(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Version != changes[j].Version {
+			return changes[i].Version < changes[j].Version
+		}
+		if changes[i].Layer != changes[j].Layer {
+			return changes[i].Layer < changes[j].Layer
+		}
+		return changes[i].Key < changes[j].Key
+	})
+}
@@ -1,5 +1,19 @@
 package store
 
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when a key doesn't exist or has expired
+var ErrNotFound = errors.New("key not found")
+
+// ErrConflict is returned by Commit when a key the transaction read was
+// written by someone else before it committed
+var ErrConflict = errors.New("transaction conflict")
+
 // StorageEngine defines the interface for storage operations
 type StorageEngine interface {
 	// Core operations
@@ -12,12 +26,17 @@ type StorageEngine interface {
 	BatchRetrieve(queries []Query) ([]QueryResult, error)
 	BatchRetrieveByPrefix(layer, prefix string, limit int) (map[string]any, error)
 
+	// Ordered scans
+	List(layer, prefix, after string, limit int) ([]QueryResult, error)
+	Layers() ([]string, error)
+
 	// Transaction management
 	BeginTx() (Transaction, error)
 
 	// Maintenance
 	Compact() error
 	Backup(path string) error
+	Close() error
 
 	// Metrics
 	GetStats() StorageStats
@@ -26,16 +45,51 @@ type StorageEngine interface {
 // Transaction represents an atomic set of storage operations
 type Transaction interface {
 	Store(layer, key string, value any) error
+	StoreWithTTL(layer, key string, value any, ttl time.Duration) error
+	Retrieve(layer, key string) (any, error)
 	Delete(layer, key string) error
 	Commit() error
 	Rollback() error
 }
 
+// Streamer is implemented by engines whose contents can be streamed out and
+// loaded back in Badger's backup format, which is what incremental backups
+// and online restores are built on
+type Streamer interface {
+	// StreamTo writes every entry with a version above since to w and returns
+	// the highest version written
+	StreamTo(w io.Writer, since uint64) (uint64, error)
+	// Load applies a stream written by StreamTo
+	Load(r io.Reader) error
+}
+
+// Subscriber is implemented by engines that report committed writes as they
+// happen, which the memory change feed is built on
+type Subscriber interface {
+	// Subscribe calls fn with each batch of committed changes until ctx is
+	// done or the engine is closed. It returns once the subscription is
+	// live, so every write committed afterwards is reported. fn must return
+	// quickly and must not call back into the engine; it may run on the
+	// goroutine that committed the write.
+	Subscribe(ctx context.Context, fn func([]Change)) error
+}
+
+// Change is a committed write reported to subscribers. Entries that expire
+// are not reported.
+type Change struct {
+	Layer   string
+	Key     string
+	Value   any // the new value; nil when Deleted
+	Deleted bool
+	Version uint64 // commit version, increasing with every write
+}
+
 // StoreOperation represents a single storage operation
 type StoreOperation struct {
 	Layer string
 	Key   string
 	Value any
+	TTL   time.Duration // expire the entry after this long; zero keeps it
 }
 
 // Query represents a storage query
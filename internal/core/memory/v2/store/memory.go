@@ -0,0 +1,523 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Ensure MemoryStore satisfies the engine interfaces
+var (
+	_ StorageEngine = (*MemoryStore)(nil)
+	_ Subscriber    = (*MemoryStore)(nil)
+)
+
+// errClosed is returned by a MemoryStore used after Close
+var errClosed = errors.New("store is closed")
+
+// MemoryStore implements the StorageEngine interface in memory, with the same
+// semantics as BadgerStore: values round-trip through JSON, entries expire
+// after their TTL, scans run in key order and a transaction fails to commit
+// if a key it read was written since it began. Nothing outlives the process,
+// which suits tests and ephemeral runs.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]*memoryEntry // by "layer:key", including tombstones
+	keys    []string                // sorted keys of entries
+	version uint64                  // bumped by every write
+	openTxs int                     // tombstones are kept while any are open
+	closed  bool
+	feed    changeFeed
+
+	operations atomic.Int64
+	errors     atomic.Int64
+}
+
+// memoryEntry is one stored value. A nil value marks a deleted key, kept so
+// open transactions can still detect the write.
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+	version   uint64
+}
+
+// live reports whether the entry holds a value that hasn't expired
+func (e *memoryEntry) live(now time.Time) bool {
+	return e != nil && e.value != nil && (e.expiresAt.IsZero() || now.Before(e.expiresAt))
+}
+
+// NewMemoryStore creates an empty in-memory storage instance
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+// track counts an operation and, if it failed, an error
+func (ms *MemoryStore) track(err error) error {
+	ms.operations.Add(1)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		ms.errors.Add(1)
+	}
+	return err
+}
+
+// newEntry encodes a value for storage
+func newEntry(value any, ttl time.Duration) (*memoryEntry, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	entry := &memoryEntry{value: data}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	return entry, nil
+}
+
+// set writes entries under the given keys as one version and reports them to
+// subscribers. Callers hold the write lock.
+func (ms *MemoryStore) set(writes map[string]*memoryEntry) {
+	ms.version++
+	defer ms.publish(writes)
+
+	for dbKey, entry := range writes {
+		if entry.value == nil && ms.openTxs == 0 {
+			ms.remove(dbKey)
+			continue
+		}
+
+		if _, exists := ms.entries[dbKey]; !exists {
+			i := sort.SearchStrings(ms.keys, dbKey)
+			ms.keys = append(ms.keys, "")
+			copy(ms.keys[i+1:], ms.keys[i:])
+			ms.keys[i] = dbKey
+		}
+		entry.version = ms.version
+		ms.entries[dbKey] = entry
+	}
+}
+
+// publish reports a set of writes to subscribers, in key order. Callers hold
+// the write lock.
+func (ms *MemoryStore) publish(writes map[string]*memoryEntry) {
+	if !ms.feed.active() {
+		return
+	}
+
+	changes := make([]Change, 0, len(writes))
+	for dbKey, entry := range writes {
+		if change, ok := newChange(dbKey, entry.value, ms.version); ok {
+			changes = append(changes, change)
+		}
+	}
+	sortChanges(changes)
+	ms.feed.publish(changes)
+}
+
+// remove drops a key entirely. Callers hold the write lock.
+func (ms *MemoryStore) remove(dbKey string) {
+	if _, exists := ms.entries[dbKey]; !exists {
+		return
+	}
+	delete(ms.entries, dbKey)
+	i := sort.SearchStrings(ms.keys, dbKey)
+	ms.keys = append(ms.keys[:i], ms.keys[i+1:]...)
+}
+
+// get decodes a live entry. Callers hold the lock.
+func (ms *MemoryStore) get(dbKey string) (any, error) {
+	entry := ms.entries[dbKey]
+	if !entry.live(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return decodeValue(entry.value)
+}
+
+func decodeValue(data []byte) (any, error) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Store implements the Store method of StorageEngine
+func (ms *MemoryStore) Store(layer, key string, value any) error {
+	if err := validateKey(layer, key); err != nil {
+		return ms.track(err)
+	}
+
+	entry, err := newEntry(value, 0)
+	if err != nil {
+		return ms.track(err)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.closed {
+		return ms.track(errClosed)
+	}
+	ms.set(map[string]*memoryEntry{string(compositeKey(layer, key)): entry})
+	return ms.track(nil)
+}
+
+// Retrieve implements the Retrieve method of StorageEngine
+func (ms *MemoryStore) Retrieve(layer, key string) (any, error) {
+	if err := validateKey(layer, key); err != nil {
+		return nil, ms.track(err)
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if ms.closed {
+		return nil, ms.track(errClosed)
+	}
+
+	value, err := ms.get(string(compositeKey(layer, key)))
+	if err != nil {
+		return nil, ms.track(fmt.Errorf("failed to retrieve value: %w", err))
+	}
+	return value, ms.track(nil)
+}
+
+// Delete implements the Delete method of StorageEngine
+func (ms *MemoryStore) Delete(layer, key string) error {
+	if err := validateKey(layer, key); err != nil {
+		return ms.track(err)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.closed {
+		return ms.track(errClosed)
+	}
+	ms.set(map[string]*memoryEntry{string(compositeKey(layer, key)): {}})
+	return ms.track(nil)
+}
+
+// BatchStore implements the BatchStore method of StorageEngine
+func (ms *MemoryStore) BatchStore(operations []StoreOperation) error {
+	writes := make(map[string]*memoryEntry, len(operations))
+	for _, op := range operations {
+		if err := validateKey(op.Layer, op.Key); err != nil {
+			return ms.track(err)
+		}
+
+		entry, err := newEntry(op.Value, op.TTL)
+		if err != nil {
+			return ms.track(fmt.Errorf("failed to marshal value for key %s: %w", op.Key, err))
+		}
+		writes[string(compositeKey(op.Layer, op.Key))] = entry
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.closed {
+		return ms.track(errClosed)
+	}
+	ms.set(writes)
+	return ms.track(nil)
+}
+
+// BatchRetrieve implements the BatchRetrieve method of StorageEngine
+func (ms *MemoryStore) BatchRetrieve(queries []Query) ([]QueryResult, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if ms.closed {
+		return nil, ms.track(fmt.Errorf("batch retrieve failed: %w", errClosed))
+	}
+
+	results := make([]QueryResult, len(queries))
+	for i, query := range queries {
+		value, err := ms.get(string(compositeKey(query.Layer, query.Key)))
+		results[i] = QueryResult{Key: query.Key, Value: value, Error: err}
+	}
+	return results, ms.track(nil)
+}
+
+// BatchRetrieveByPrefix implements the BatchRetrieveByPrefix method of
+// StorageEngine
+func (ms *MemoryStore) BatchRetrieveByPrefix(layer, prefix string, limit int) (map[string]any, error) {
+	results, err := ms.List(layer, prefix, "", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(results))
+	for _, result := range results {
+		values[result.Key] = result.Value
+	}
+	return values, nil
+}
+
+// List returns up to limit entries of a layer whose keys start with prefix,
+// in key order, beginning after the key named by after. A limit of zero or
+// less returns every match.
+func (ms *MemoryStore) List(layer, prefix, after string, limit int) ([]QueryResult, error) {
+	layerPrefix := layer + ":"
+	scanPrefix := layerPrefix + prefix
+
+	seek := scanPrefix
+	if afterKey := layerPrefix + after; after != "" && afterKey > seek {
+		seek = afterKey
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if ms.closed {
+		return nil, ms.track(fmt.Errorf("failed to list layer %s: %w", layer, errClosed))
+	}
+
+	now := time.Now()
+	var results []QueryResult
+	for i := sort.SearchStrings(ms.keys, seek); i < len(ms.keys) && strings.HasPrefix(ms.keys[i], scanPrefix); i++ {
+		if limit > 0 && len(results) == limit {
+			break
+		}
+
+		dbKey := ms.keys[i]
+		key := strings.TrimPrefix(dbKey, layerPrefix)
+		entry := ms.entries[dbKey]
+		if !entry.live(now) || (after != "" && key <= after) {
+			continue
+		}
+
+		value, err := decodeValue(entry.value)
+		if err != nil {
+			return nil, ms.track(fmt.Errorf("failed to list layer %s: failed to decode key %s: %w", layer, dbKey, err))
+		}
+		results = append(results, QueryResult{Key: key, Value: value})
+	}
+
+	return results, ms.track(nil)
+}
+
+// Layers returns the layers holding at least one entry, in order
+func (ms *MemoryStore) Layers() ([]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if ms.closed {
+		return nil, ms.track(fmt.Errorf("failed to list layers: %w", errClosed))
+	}
+
+	now := time.Now()
+	var layers []string
+	for i := 0; i < len(ms.keys); {
+		layer, _, ok := strings.Cut(ms.keys[i], ":")
+		if !ok || !ms.entries[ms.keys[i]].live(now) {
+			i++
+			continue
+		}
+		layers = append(layers, layer)
+
+		// ';' sorts right after ':', so this skips the rest of the layer
+		i = sort.SearchStrings(ms.keys, layer+";")
+	}
+
+	return layers, ms.track(nil)
+}
+
+// BeginTx implements the BeginTx method of StorageEngine
+func (ms *MemoryStore) BeginTx() (Transaction, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.closed {
+		return nil, errClosed
+	}
+
+	ms.openTxs++
+	return &MemoryTransaction{
+		store:  ms,
+		readTs: ms.version,
+		reads:  make(map[string]struct{}),
+		writes: make(map[string]*memoryEntry),
+	}, nil
+}
+
+// Compact implements the Compact method of StorageEngine by dropping expired
+// entries, and tombstones once no transaction needs them
+func (ms *MemoryStore) Compact() error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.closed {
+		return errClosed
+	}
+
+	now := time.Now()
+	live := ms.keys[:0]
+	for _, dbKey := range ms.keys {
+		entry := ms.entries[dbKey]
+		if entry.live(now) || (entry.value == nil && ms.openTxs > 0) {
+			live = append(live, dbKey)
+			continue
+		}
+		delete(ms.entries, dbKey)
+	}
+	ms.keys = live
+	return nil
+}
+
+// Backup implements the Backup method of StorageEngine by writing the live
+// entries to path as one JSON object keyed by "layer:key"
+func (ms *MemoryStore) Backup(path string) error {
+	ms.mu.RLock()
+	now := time.Now()
+	snapshot := make(map[string]json.RawMessage, len(ms.entries))
+	for dbKey, entry := range ms.entries {
+		if entry.live(now) {
+			snapshot[dbKey] = entry.value
+		}
+	}
+	ms.mu.RUnlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	return nil
+}
+
+// Close releases the stored entries; the store can't be used afterwards
+func (ms *MemoryStore) Close() error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.closed = true
+	ms.entries = nil
+	ms.keys = nil
+	ms.feed.close()
+	return nil
+}
+
+// Subscribe implements the Subscribe method of Subscriber. Changes are
+// reported on the goroutine that commits them, while the store is locked.
+func (ms *MemoryStore) Subscribe(ctx context.Context, fn func([]Change)) error {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if ms.closed {
+		return errClosed
+	}
+	ms.feed.subscribe(ctx, fn)
+	return nil
+}
+
+// GetStats implements the GetStats method of StorageEngine
+func (ms *MemoryStore) GetStats() StorageStats {
+	stats := StorageStats{
+		OperationCount: ms.operations.Load(),
+		ErrorCount:     ms.errors.Load(),
+	}
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	now := time.Now()
+	for dbKey, entry := range ms.entries {
+		if entry.live(now) {
+			stats.TotalEntries++
+			stats.TotalSize += int64(len(dbKey) + len(entry.value))
+		}
+	}
+	return stats
+}
+
+// MemoryTransaction implements the Transaction interface. Writes are buffered
+// until Commit; reads see them, then the latest committed values.
+type MemoryTransaction struct {
+	store  *MemoryStore
+	readTs uint64 // store version when the transaction began
+	reads  map[string]struct{}
+	writes map[string]*memoryEntry
+	done   bool
+}
+
+func (mt *MemoryTransaction) Store(layer, key string, value any) error {
+	return mt.StoreWithTTL(layer, key, value, 0)
+}
+
+func (mt *MemoryTransaction) StoreWithTTL(layer, key string, value any, ttl time.Duration) error {
+	if err := validateKey(layer, key); err != nil {
+		return err
+	}
+
+	entry, err := newEntry(value, ttl)
+	if err != nil {
+		return err
+	}
+	mt.writes[string(compositeKey(layer, key))] = entry
+	return nil
+}
+
+func (mt *MemoryTransaction) Retrieve(layer, key string) (any, error) {
+	dbKey := string(compositeKey(layer, key))
+	if entry, written := mt.writes[dbKey]; written {
+		if !entry.live(time.Now()) {
+			return nil, fmt.Errorf("failed to retrieve value: %w", ErrNotFound)
+		}
+		return decodeValue(entry.value)
+	}
+
+	mt.reads[dbKey] = struct{}{}
+
+	mt.store.mu.RLock()
+	defer mt.store.mu.RUnlock()
+	value, err := mt.store.get(dbKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve value: %w", err)
+	}
+	return value, nil
+}
+
+func (mt *MemoryTransaction) Delete(layer, key string) error {
+	if err := validateKey(layer, key); err != nil {
+		return err
+	}
+	mt.writes[string(compositeKey(layer, key))] = &memoryEntry{}
+	return nil
+}
+
+func (mt *MemoryTransaction) Commit() error {
+	if mt.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	mt.done = true
+
+	ms := mt.store
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.openTxs--
+	if ms.closed {
+		return ms.track(errClosed)
+	}
+
+	for dbKey := range mt.reads {
+		if entry, exists := ms.entries[dbKey]; exists && entry.version > mt.readTs {
+			return ms.track(ErrConflict)
+		}
+	}
+	if len(mt.writes) > 0 {
+		ms.set(mt.writes)
+	}
+	return ms.track(nil)
+}
+
+func (mt *MemoryTransaction) Rollback() error {
+	if mt.done {
+		return nil
+	}
+	mt.done = true
+
+	mt.store.mu.Lock()
+	mt.store.openTxs--
+	mt.store.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,782 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v3/pb"
+	_ "modernc.org/sqlite" // pure Go SQLite driver
+)
+
+// Ensure SQLiteStore satisfies the engine interfaces
+var (
+	_ StorageEngine = (*SQLiteStore)(nil)
+	_ Streamer      = (*SQLiteStore)(nil)
+	_ Subscriber    = (*SQLiteStore)(nil)
+)
+
+// sqliteTablePrefix starts the name of every layer table
+const sqliteTablePrefix = "layer_"
+
+// sqliteLive matches rows that haven't expired; it takes the current time in
+// milliseconds as its parameter
+const sqliteLive = "(expires_at IS NULL OR expires_at > ?)"
+
+// sqliteStreamBatch is the most entries StreamTo writes per KV list
+const sqliteStreamBatch = 1000
+
+// SQLiteStore implements the StorageEngine interface on a SQLite database,
+// with one table per layer so memories can be queried with plain SQL. Each
+// table holds the key, the value as JSON and when the entry was created,
+// updated and expires, in Unix milliseconds, with the timestamps indexed:
+//
+//	SELECT key, json_extract(value, '$.value') FROM layer_eternal
+//	WHERE updated_at > strftime('%s', 'now', '-1 day') * 1000
+//
+// Every write is stamped with a store-wide version, which lets transactions
+// detect conflicting writes the way BadgerStore's do. Deleted keys leave a
+// tombstone in lattice_deleted with the version that deleted them, so
+// incremental backups carry deletions.
+type SQLiteStore struct {
+	db *sql.DB
+
+	writeMu  sync.Mutex // serializes writers, so they never wait on SQLite's lock
+	tablesMu sync.RWMutex
+	tables   map[string]bool // layers whose table exists
+	feed     changeFeed
+
+	operations atomic.Int64
+	errors     atomic.Int64
+}
+
+// NewSQLiteStore opens or creates a SQLite storage instance in the file at
+// path
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	ss := &SQLiteStore{db: db, tables: make(map[string]bool)}
+	if err := ss.init(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize SQLite database: %w", err)
+	}
+	return ss, nil
+}
+
+// init creates the version counter and loads the existing layer tables
+func (ss *SQLiteStore) init() error {
+	_, err := ss.db.Exec(`
+		CREATE TABLE IF NOT EXISTS lattice_meta (
+			id      INTEGER PRIMARY KEY CHECK (id = 1),
+			version INTEGER NOT NULL
+		);
+		INSERT OR IGNORE INTO lattice_meta (id, version) VALUES (1, 0);
+		CREATE TABLE IF NOT EXISTS lattice_deleted (
+			layer   TEXT NOT NULL,
+			key     TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			PRIMARY KEY (layer, key)
+		);`)
+	if err != nil {
+		return err
+	}
+
+	rows, err := ss.db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND substr(name, 1, ?) = ?`,
+		len(sqliteTablePrefix), sqliteTablePrefix)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		ss.tables[strings.TrimPrefix(name, sqliteTablePrefix)] = true
+	}
+	return rows.Err()
+}
+
+// DB returns the underlying database (for advanced operations)
+func (ss *SQLiteStore) DB() *sql.DB {
+	return ss.db
+}
+
+// quoteIdent quotes a SQL identifier
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// table returns the quoted name of a layer's table
+func table(layer string) string {
+	return quoteIdent(sqliteTablePrefix + layer)
+}
+
+// nowMillis returns the current time in Unix milliseconds
+func nowMillis() int64 {
+	return time.Now().UnixMilli()
+}
+
+// track counts an operation and, if it failed, an error
+func (ss *SQLiteStore) track(err error) error {
+	ss.operations.Add(1)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		ss.errors.Add(1)
+	}
+	return err
+}
+
+// hasTable reports whether a layer's table exists
+func (ss *SQLiteStore) hasTable(layer string) bool {
+	ss.tablesMu.RLock()
+	defer ss.tablesMu.RUnlock()
+	return ss.tables[layer]
+}
+
+// layerTables returns the layers that have a table, in order
+func (ss *SQLiteStore) layerTables() []string {
+	ss.tablesMu.RLock()
+	defer ss.tablesMu.RUnlock()
+
+	layers := make([]string, 0, len(ss.tables))
+	for layer := range ss.tables {
+		layers = append(layers, layer)
+	}
+	sort.Strings(layers)
+	return layers
+}
+
+// ensureTable creates a layer's table and its timestamp indices if needed
+func (ss *SQLiteStore) ensureTable(layer string) error {
+	if ss.hasTable(layer) {
+		return nil
+	}
+
+	ss.tablesMu.Lock()
+	defer ss.tablesMu.Unlock()
+	if ss.tables[layer] {
+		return nil
+	}
+
+	name := sqliteTablePrefix + layer
+	_, err := ss.db.Exec(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			key        TEXT PRIMARY KEY,
+			value      TEXT NOT NULL,
+			version    INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			expires_at INTEGER
+		);
+		CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (updated_at);
+		CREATE INDEX IF NOT EXISTS %[3]s ON %[1]s (expires_at);`,
+		quoteIdent(name), quoteIdent(name+"_updated_at"), quoteIdent(name+"_expires_at")))
+	if err != nil {
+		return fmt.Errorf("failed to create table for layer %s: %w", layer, err)
+	}
+
+	ss.tables[layer] = true
+	return nil
+}
+
+// sqliteWrite is one pending write; a nil value deletes the key
+type sqliteWrite struct {
+	layer, key string
+	value      []byte
+	ttl        time.Duration
+}
+
+// newWrite encodes a value for storage
+func newWrite(layer, key string, value any, ttl time.Duration) (sqliteWrite, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return sqliteWrite{}, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	return sqliteWrite{layer: layer, key: key, value: data, ttl: ttl}, nil
+}
+
+// write applies writes in one SQL transaction stamped with a new version.
+// check, if given, runs first within the transaction and can abort it.
+func (ss *SQLiteStore) write(writes []sqliteWrite, check func(tx *sql.Tx) error) error {
+	for _, w := range writes {
+		if w.value != nil {
+			if err := ss.ensureTable(w.layer); err != nil {
+				return err
+			}
+		}
+	}
+
+	ss.writeMu.Lock()
+	defer ss.writeMu.Unlock()
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if check != nil {
+		if err := check(tx); err != nil {
+			return err
+		}
+	}
+
+	var version int64
+	if err := tx.QueryRow(`UPDATE lattice_meta SET version = version + 1 RETURNING version`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to bump version: %w", err)
+	}
+
+	now := nowMillis()
+	for _, w := range writes {
+		if w.value == nil {
+			if err := ss.deleteRow(tx, w.layer, w.key, version); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var expiresAt any
+		if w.ttl > 0 {
+			expiresAt = now + w.ttl.Milliseconds()
+		}
+		if err := ss.setRow(tx, w.layer, w.key, string(w.value), version, now, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	ss.publish(writes, uint64(version))
+	return nil
+}
+
+// setRow writes a key's value at version. Its table must exist.
+func (ss *SQLiteStore) setRow(tx *sql.Tx, layer, key, value string, version, now int64, expiresAt any) error {
+	// An expired row being replaced counts as a new entry
+	_, err := tx.Exec(`INSERT INTO `+table(layer)+` (key, value, version, created_at, updated_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			value = excluded.value,
+			version = excluded.version,
+			created_at = CASE WHEN expires_at IS NOT NULL AND expires_at <= excluded.updated_at
+				THEN excluded.created_at ELSE created_at END,
+			updated_at = excluded.updated_at,
+			expires_at = excluded.expires_at`,
+		key, value, version, now, now, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to set key %s:%s: %w", layer, key, err)
+	}
+	return nil
+}
+
+// deleteRow deletes a key at version, leaving its tombstone
+func (ss *SQLiteStore) deleteRow(tx *sql.Tx, layer, key string, version int64) error {
+	if !ss.hasTable(layer) {
+		return nil
+	}
+	if _, err := tx.Exec(`DELETE FROM `+table(layer)+` WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("failed to delete key %s:%s: %w", layer, key, err)
+	}
+	_, err := tx.Exec(`INSERT INTO lattice_deleted (layer, key, version) VALUES (?, ?, ?)
+		ON CONFLICT (layer, key) DO UPDATE SET version = excluded.version`, layer, key, version)
+	if err != nil {
+		return fmt.Errorf("failed to record deletion of key %s:%s: %w", layer, key, err)
+	}
+	return nil
+}
+
+// publish reports committed writes to subscribers, in key order. Callers
+// hold writeMu, so changes are reported in commit order.
+func (ss *SQLiteStore) publish(writes []sqliteWrite, version uint64) {
+	if !ss.feed.active() {
+		return
+	}
+
+	changes := make([]Change, 0, len(writes))
+	for _, w := range writes {
+		if change, ok := newChange(string(compositeKey(w.layer, w.key)), w.value, version); ok {
+			changes = append(changes, change)
+		}
+	}
+	sortChanges(changes)
+	ss.feed.publish(changes)
+}
+
+// sqlQuerier is satisfied by both *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// get reads a live entry and the version it was written at
+func (ss *SQLiteStore) get(q sqlQuerier, layer, key string) (any, int64, error) {
+	if !ss.hasTable(layer) {
+		return nil, 0, ErrNotFound
+	}
+
+	var data string
+	var version int64
+	err := q.QueryRow(`SELECT value, version FROM `+table(layer)+` WHERE key = ? AND `+sqliteLive,
+		key, nowMillis()).Scan(&data, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	value, err := decodeValue([]byte(data))
+	return value, version, err
+}
+
+// Store implements the Store method of StorageEngine
+func (ss *SQLiteStore) Store(layer, key string, value any) error {
+	if err := validateKey(layer, key); err != nil {
+		return ss.track(err)
+	}
+
+	w, err := newWrite(layer, key, value, 0)
+	if err != nil {
+		return ss.track(err)
+	}
+	return ss.track(ss.write([]sqliteWrite{w}, nil))
+}
+
+// Retrieve implements the Retrieve method of StorageEngine
+func (ss *SQLiteStore) Retrieve(layer, key string) (any, error) {
+	if err := validateKey(layer, key); err != nil {
+		return nil, ss.track(err)
+	}
+
+	value, _, err := ss.get(ss.db, layer, key)
+	if err != nil {
+		return nil, ss.track(fmt.Errorf("failed to retrieve value: %w", err))
+	}
+	return value, ss.track(nil)
+}
+
+// Delete implements the Delete method of StorageEngine
+func (ss *SQLiteStore) Delete(layer, key string) error {
+	if err := validateKey(layer, key); err != nil {
+		return ss.track(err)
+	}
+	return ss.track(ss.write([]sqliteWrite{{layer: layer, key: key}}, nil))
+}
+
+// BatchStore implements the BatchStore method of StorageEngine
+func (ss *SQLiteStore) BatchStore(operations []StoreOperation) error {
+	writes := make([]sqliteWrite, 0, len(operations))
+	for _, op := range operations {
+		if err := validateKey(op.Layer, op.Key); err != nil {
+			return ss.track(err)
+		}
+
+		w, err := newWrite(op.Layer, op.Key, op.Value, op.TTL)
+		if err != nil {
+			return ss.track(fmt.Errorf("failed to marshal value for key %s: %w", op.Key, err))
+		}
+		writes = append(writes, w)
+	}
+	return ss.track(ss.write(writes, nil))
+}
+
+// BatchRetrieve implements the BatchRetrieve method of StorageEngine
+func (ss *SQLiteStore) BatchRetrieve(queries []Query) ([]QueryResult, error) {
+	results := make([]QueryResult, len(queries))
+	for i, query := range queries {
+		value, _, err := ss.get(ss.db, query.Layer, query.Key)
+		results[i] = QueryResult{Key: query.Key, Value: value, Error: err}
+	}
+	return results, ss.track(nil)
+}
+
+// BatchRetrieveByPrefix implements the BatchRetrieveByPrefix method of
+// StorageEngine
+func (ss *SQLiteStore) BatchRetrieveByPrefix(layer, prefix string, limit int) (map[string]any, error) {
+	results, err := ss.List(layer, prefix, "", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(results))
+	for _, result := range results {
+		values[result.Key] = result.Value
+	}
+	return values, nil
+}
+
+// List returns up to limit entries of a layer whose keys start with prefix,
+// in key order, beginning after the key named by after. A limit of zero or
+// less returns every match.
+func (ss *SQLiteStore) List(layer, prefix, after string, limit int) ([]QueryResult, error) {
+	if !ss.hasTable(layer) {
+		return nil, ss.track(nil)
+	}
+
+	// Keys compare bytewise, so the matches run on from the prefix
+	bound, from := ">=", prefix
+	if after != "" && after >= prefix {
+		bound, from = ">", after
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := ss.db.Query(`SELECT key, value FROM `+table(layer)+` WHERE key `+bound+` ? AND `+sqliteLive+
+		` ORDER BY key LIMIT ?`, from, nowMillis(), limit)
+	if err != nil {
+		return nil, ss.track(fmt.Errorf("failed to list layer %s: %w", layer, err))
+	}
+	defer rows.Close()
+
+	var results []QueryResult
+	for rows.Next() {
+		var key, data string
+		if err := rows.Scan(&key, &data); err != nil {
+			return nil, ss.track(fmt.Errorf("failed to list layer %s: %w", layer, err))
+		}
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+
+		value, err := decodeValue([]byte(data))
+		if err != nil {
+			return nil, ss.track(fmt.Errorf("failed to list layer %s: failed to decode key %s: %w", layer, key, err))
+		}
+		results = append(results, QueryResult{Key: key, Value: value})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, ss.track(fmt.Errorf("failed to list layer %s: %w", layer, err))
+	}
+
+	return results, ss.track(nil)
+}
+
+// Layers returns the layers holding at least one entry, in order
+func (ss *SQLiteStore) Layers() ([]string, error) {
+	var layers []string
+	now := nowMillis()
+	for _, layer := range ss.layerTables() {
+		var live bool
+		err := ss.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM `+table(layer)+` WHERE `+sqliteLive+`)`, now).Scan(&live)
+		if err != nil {
+			return nil, ss.track(fmt.Errorf("failed to list layers: %w", err))
+		}
+		if live {
+			layers = append(layers, layer)
+		}
+	}
+	return layers, ss.track(nil)
+}
+
+// BeginTx implements the BeginTx method of StorageEngine
+func (ss *SQLiteStore) BeginTx() (Transaction, error) {
+	return &SQLiteTransaction{
+		store:  ss,
+		reads:  make(map[Query]int64),
+		writes: make(map[Query]sqliteWrite),
+	}, nil
+}
+
+// Compact implements the Compact method of StorageEngine by deleting expired
+// rows and vacuuming the database
+func (ss *SQLiteStore) Compact() error {
+	ss.writeMu.Lock()
+	defer ss.writeMu.Unlock()
+
+	now := nowMillis()
+	for _, layer := range ss.layerTables() {
+		if _, err := ss.db.Exec(`DELETE FROM `+table(layer)+` WHERE NOT `+sqliteLive, now); err != nil {
+			return fmt.Errorf("failed to drop expired entries of layer %s: %w", layer, err)
+		}
+	}
+	if _, err := ss.db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return nil
+}
+
+// Backup implements the Backup method of StorageEngine by writing a copy of
+// the database to path
+func (ss *SQLiteStore) Backup(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace backup file: %w", err)
+	}
+	if _, err := ss.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	return nil
+}
+
+// StreamTo implements the StreamTo method of Streamer. Live entries written
+// above since are streamed in version order, along with the tombstones of
+// keys deleted since then unless the stream is a full one. Writes wait while
+// it runs, so the stream is a consistent snapshot.
+func (ss *SQLiteStore) StreamTo(w io.Writer, since uint64) (uint64, error) {
+	ss.writeMu.Lock()
+	defer ss.writeMu.Unlock()
+
+	var selects []string
+	var args []any
+	now := nowMillis()
+	for _, layer := range ss.layerTables() {
+		selects = append(selects, `SELECT ? AS layer, key, value, version, expires_at FROM `+table(layer)+
+			` WHERE version > ? AND `+sqliteLive)
+		args = append(args, layer, int64(since), now)
+	}
+	// A full stream is loaded into an empty database, with nothing to delete
+	if since > 0 {
+		selects = append(selects, `SELECT layer, key, NULL, version, NULL FROM lattice_deleted WHERE version > ?`)
+		args = append(args, int64(since))
+	}
+	if len(selects) == 0 {
+		return 0, nil
+	}
+
+	rows, err := ss.db.Query(strings.Join(selects, " UNION ALL ")+` ORDER BY version`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read entries: %w", err)
+	}
+	defer rows.Close()
+
+	var maxVersion uint64
+	list := &pb.KVList{}
+	for rows.Next() {
+		var layer, key string
+		var value sql.NullString
+		var version int64
+		var expiresAt sql.NullInt64
+		if err := rows.Scan(&layer, &key, &value, &version, &expiresAt); err != nil {
+			return 0, fmt.Errorf("failed to read entries: %w", err)
+		}
+
+		kv := &pb.KV{Key: compositeKey(layer, key), Version: uint64(version)}
+		if value.Valid {
+			kv.Value = []byte(value.String)
+		} else {
+			kv.Meta = []byte{bitDelete}
+		}
+		// Badger keeps expiry in Unix seconds; round up so no entry is cut short
+		if expiresAt.Valid {
+			kv.ExpiresAt = uint64((expiresAt.Int64 + 999) / 1000)
+		}
+		list.Kv = append(list.Kv, kv)
+		if kv.Version > maxVersion {
+			maxVersion = kv.Version
+		}
+
+		if len(list.Kv) == sqliteStreamBatch {
+			if err := writeKVList(w, list); err != nil {
+				return 0, err
+			}
+			list.Kv = list.Kv[:0]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read entries: %w", err)
+	}
+	if len(list.Kv) > 0 {
+		if err := writeKVList(w, list); err != nil {
+			return 0, err
+		}
+	}
+	return maxVersion, nil
+}
+
+// Load implements the Load method of Streamer, applying each KV list of the
+// stream in one transaction. Entries keep their versions, and are stamped as
+// created and updated when they are loaded.
+func (ss *SQLiteStore) Load(r io.Reader) error {
+	return scanKVLists(r, func(list *pb.KVList) error {
+		for _, kv := range list.Kv {
+			layer, _, found := strings.Cut(string(kv.Key), ":")
+			if found && !isDelete(kv) {
+				if err := ss.ensureTable(layer); err != nil {
+					return err
+				}
+			}
+		}
+		return ss.load(list.Kv)
+	})
+}
+
+// load applies the entries of one KV list in a single transaction
+func (ss *SQLiteStore) load(kvs []*pb.KV) error {
+	ss.writeMu.Lock()
+	defer ss.writeMu.Unlock()
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var maxVersion int64
+	now := nowMillis()
+	for _, kv := range kvs {
+		layer, key, found := strings.Cut(string(kv.Key), ":")
+		if !found {
+			continue
+		}
+		version := int64(kv.Version)
+		if version > maxVersion {
+			maxVersion = version
+		}
+
+		if isDelete(kv) {
+			err = ss.deleteRow(tx, layer, key, version)
+		} else {
+			var expiresAt any
+			if kv.ExpiresAt > 0 {
+				expiresAt = int64(kv.ExpiresAt) * 1000
+			}
+			err = ss.setRow(tx, layer, key, string(kv.Value), version, now, expiresAt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Later writes must be stamped above every loaded version
+	if _, err := tx.Exec(`UPDATE lattice_meta SET version = MAX(version, ?)`, maxVersion); err != nil {
+		return fmt.Errorf("failed to bump version: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Subscribe implements the Subscribe method of Subscriber. Changes are
+// reported on the goroutine that commits them.
+func (ss *SQLiteStore) Subscribe(ctx context.Context, fn func([]Change)) error {
+	ss.feed.subscribe(ctx, fn)
+	return nil
+}
+
+// Close closes the database
+func (ss *SQLiteStore) Close() error {
+	ss.feed.close()
+	return ss.db.Close()
+}
+
+// GetStats implements the GetStats method of StorageEngine
+func (ss *SQLiteStore) GetStats() StorageStats {
+	stats := StorageStats{
+		OperationCount: ss.operations.Load(),
+		ErrorCount:     ss.errors.Load(),
+	}
+
+	now := nowMillis()
+	for _, layer := range ss.layerTables() {
+		var count int64
+		if err := ss.db.QueryRow(`SELECT COUNT(*) FROM `+table(layer)+` WHERE `+sqliteLive, now).Scan(&count); err == nil {
+			stats.TotalEntries += count
+		}
+	}
+
+	var pages, pageSize int64
+	if ss.db.QueryRow(`PRAGMA page_count`).Scan(&pages) == nil &&
+		ss.db.QueryRow(`PRAGMA page_size`).Scan(&pageSize) == nil {
+		stats.TotalSize = pages * pageSize
+	}
+	return stats
+}
+
+// SQLiteTransaction implements the Transaction interface. Writes are buffered
+// until Commit, which applies them in one SQL transaction and fails with
+// ErrConflict if a key the transaction read has been written since.
+type SQLiteTransaction struct {
+	store  *SQLiteStore
+	reads  map[Query]int64 // version of each key when first read; 0 if absent
+	writes map[Query]sqliteWrite
+	done   bool
+}
+
+func (st *SQLiteTransaction) Store(layer, key string, value any) error {
+	return st.StoreWithTTL(layer, key, value, 0)
+}
+
+func (st *SQLiteTransaction) StoreWithTTL(layer, key string, value any, ttl time.Duration) error {
+	if err := validateKey(layer, key); err != nil {
+		return err
+	}
+
+	w, err := newWrite(layer, key, value, ttl)
+	if err != nil {
+		return err
+	}
+	st.writes[Query{Layer: layer, Key: key}] = w
+	return nil
+}
+
+func (st *SQLiteTransaction) Retrieve(layer, key string) (any, error) {
+	query := Query{Layer: layer, Key: key}
+	if w, written := st.writes[query]; written {
+		if w.value == nil {
+			return nil, fmt.Errorf("failed to retrieve value: %w", ErrNotFound)
+		}
+		return decodeValue(w.value)
+	}
+
+	value, version, err := st.store.get(st.store.db, layer, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("failed to retrieve value: %w", err)
+	}
+	if _, seen := st.reads[query]; !seen {
+		st.reads[query] = version
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve value: %w", err)
+	}
+	return value, nil
+}
+
+func (st *SQLiteTransaction) Delete(layer, key string) error {
+	if err := validateKey(layer, key); err != nil {
+		return err
+	}
+	st.writes[Query{Layer: layer, Key: key}] = sqliteWrite{layer: layer, key: key}
+	return nil
+}
+
+func (st *SQLiteTransaction) Commit() error {
+	if st.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	st.done = true
+
+	writes := make([]sqliteWrite, 0, len(st.writes))
+	for _, w := range st.writes {
+		writes = append(writes, w)
+	}
+
+	return st.store.track(st.store.write(writes, func(tx *sql.Tx) error {
+		for query, seen := range st.reads {
+			_, version, err := st.store.get(tx, query.Layer, query.Key)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if version != seen {
+				return ErrConflict
+			}
+		}
+		return nil
+	}))
+}
+
+func (st *SQLiteTransaction) Rollback() error {
+	st.done = true
+	return nil
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/dgraph-io/badger/v3/pb"
+)
+
+// Streams written by StreamTo use the format of Badger's backups whatever the
+// engine, so backups are read the same way on every backend: KV lists encoded
+// as protobuf, each preceded by its size as a little-endian uint64. Keys are
+// "layer:key" and values the entry as JSON.
+
+// bitDelete marks a KV that deletes its key, matching Badger's own meta bit
+const bitDelete byte = 1 << 0
+
+// isDelete reports whether kv deletes its key
+func isDelete(kv *pb.KV) bool {
+	return len(kv.Meta) > 0 && kv.Meta[0]&bitDelete != 0
+}
+
+// writeKVList appends one KV list to a stream
+func writeKVList(w io.Writer, list *pb.KVList) error {
+	data, err := list.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to encode stream chunk: %w", err)
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint64(len(data))); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// scanKVLists calls fn with each KV list of a stream
+func scanKVLists(r io.Reader, fn func(list *pb.KVList) error) error {
+	br := bufio.NewReader(r)
+	var buf []byte
+
+	for {
+		var size uint64
+		if err := binary.Read(br, binary.LittleEndian, &size); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read backup chunk size: %w", err)
+		}
+
+		if uint64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		if _, err := io.ReadFull(br, buf[:size]); err != nil {
+			return fmt.Errorf("failed to read backup chunk: %w", err)
+		}
+
+		list := &pb.KVList{}
+		if err := list.Unmarshal(buf[:size]); err != nil {
+			return fmt.Errorf("failed to decode backup chunk: %w", err)
+		}
+		if err := fn(list); err != nil {
+			return err
+		}
+	}
+}
+
+// ScanStream calls fn with each entry of a stream written by StreamTo
+func ScanStream(r io.Reader, fn func(kv *pb.KV) error) error {
+	return scanKVLists(r, func(list *pb.KVList) error {
+		for _, kv := range list.Kv {
+			if err := fn(kv); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
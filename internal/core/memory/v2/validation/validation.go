@@ -2,7 +2,9 @@ package validation
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+	"regexp"
 	"time"
 )
 
@@ -20,7 +22,7 @@ type FieldDefinition struct {
 	Required  bool
 	MinValue  interface{}
 	MaxValue  interface{}
-	Pattern   string
+	Pattern   string // regular expression string values must match
 	Validator func(interface{}) error
 }
 
@@ -32,6 +34,13 @@ type ValidationError struct {
 	Rule    string
 }
 
+func (e ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
 // ValidationEngine implements the ValidationEngine interface
 type ValidationEngine struct {
 	schemas map[string]Schema
@@ -82,8 +91,24 @@ func (ve *ValidationEngine) ValidateData(layer string, data interface{}) error {
 				ve.addError(fieldName, err.Error(), field.Interface(), "validation")
 			}
 		}
+	} else if record, ok := data.(map[string]interface{}); ok {
+		// Maps are how memories come back from storage
+		for fieldName, fieldDef := range schema.Fields {
+			fieldValue, exists := record[fieldName]
+			if !exists {
+				if fieldDef.Required {
+					ve.addError(fieldName, "required field missing", nil, "required")
+				}
+				continue
+			}
+
+			fieldValue = normalizeNumber(fieldValue, fieldDef.Type)
+			if err := ve.validateField(fieldName, fieldValue, fieldDef); err != nil {
+				ve.addError(fieldName, err.Error(), fieldValue, "validation")
+			}
+		}
 	} else {
-		return fmt.Errorf("data must be a struct type, got %v", value.Kind())
+		return fmt.Errorf("data must be a struct or map type, got %v", value.Kind())
 	}
 
 	if len(ve.errors) > 0 {
@@ -173,27 +198,57 @@ func (ve *ValidationEngine) validateField(name string, value interface{}, def Fi
 		}
 	}
 
+	if def.Pattern != "" {
+		if text, ok := value.(string); ok {
+			matched, err := regexp.MatchString(def.Pattern, text)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", def.Pattern, err)
+			}
+			if !matched {
+				return fmt.Errorf("value %q does not match pattern %q", text, def.Pattern)
+			}
+		}
+	}
+
 	return ve.validateRange(value, def)
 }
 
+// normalizeNumber converts a whole float64, which is how JSON decodes every
+// number, to the integer kind a field expects
+func normalizeNumber(value interface{}, kind reflect.Kind) interface{} {
+	f, ok := value.(float64)
+	if !ok || f != math.Trunc(f) {
+		return value
+	}
+	switch kind {
+	case reflect.Int:
+		return int(f)
+	case reflect.Int32:
+		return int32(f)
+	case reflect.Int64:
+		return int64(f)
+	}
+	return value
+}
+
 func (ve *ValidationEngine) validateRange(value interface{}, def FieldDefinition) error {
 	if def.MinValue != nil || def.MaxValue != nil {
 		switch value.(type) {
 		case int, int32, int64:
 			val := reflect.ValueOf(value).Int()
 			if def.MinValue != nil && val < reflect.ValueOf(def.MinValue).Int() {
-				return fmt.Errorf("value below minimum")
+				return fmt.Errorf("value %d below minimum %v", val, def.MinValue)
 			}
 			if def.MaxValue != nil && val > reflect.ValueOf(def.MaxValue).Int() {
-				return fmt.Errorf("value above maximum")
+				return fmt.Errorf("value %d above maximum %v", val, def.MaxValue)
 			}
 		case float32, float64:
 			val := reflect.ValueOf(value).Float()
 			if def.MinValue != nil && val < reflect.ValueOf(def.MinValue).Float() {
-				return fmt.Errorf("value below minimum")
+				return fmt.Errorf("value %v below minimum %v", val, def.MinValue)
 			}
 			if def.MaxValue != nil && val > reflect.ValueOf(def.MaxValue).Float() {
-				return fmt.Errorf("value above maximum")
+				return fmt.Errorf("value %v above maximum %v", val, def.MaxValue)
 			}
 		}
 	}
@@ -204,10 +259,16 @@ func (ve *ValidationEngine) validateFieldDefinition(def FieldDefinition) error {
 	switch def.Type {
 	case reflect.Bool, reflect.Int, reflect.Int32, reflect.Int64,
 		reflect.Float32, reflect.Float64, reflect.String:
-		return nil
 	default:
 		return fmt.Errorf("unsupported field type: %v", def.Type)
 	}
+
+	if def.Pattern != "" {
+		if _, err := regexp.Compile(def.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", def.Pattern, err)
+		}
+	}
+	return nil
 }
 
 func (ve *ValidationEngine) addError(field, message string, value interface{}, rule string) {
@@ -1,12 +1,14 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
 	"strconv"
 	"time"
 
+	"github.com/phoenix-marie/core/internal/core/memory"
 	"github.com/phoenix-marie/core/internal/emotion"
 	"github.com/phoenix-marie/core/internal/llm"
 )
@@ -23,8 +25,9 @@ var (
 	giLevel float64 = 0.1 // General Intelligence level
 )
 
-// Live is the main autonomous loop for Phoenix.Marie v3.3
-func (p *Phoenix) Live() {
+// Live is the main autonomous loop for Phoenix.Marie v3.3. It runs until
+// ctx is cancelled, which also aborts any LLM request in flight.
+func (p *Phoenix) Live(ctx context.Context) {
 	if !p.Config.AutonomousMode {
 		log.Println("AUTONOMY: Disabled - Phoenix will not run autonomously")
 		return
@@ -38,6 +41,11 @@ func (p *Phoenix) Live() {
 			log.Printf("Warning: Failed to start ThoughtEngine: %v", err)
 		} else {
 			log.Println("THOUGHT: Consciousness engine started")
+			defer func() {
+				if err := p.Thought.Stop(); err != nil {
+					log.Printf("Warning: Failed to stop ThoughtEngine: %v", err)
+				}
+			}()
 		}
 	}
 	
@@ -51,12 +59,12 @@ func (p *Phoenix) Live() {
 
 		// 2. CURIOSITY — She wonders
 		if p.ShouldExplore() {
-			p.Explore()
+			p.Explore(ctx)
 		}
 
 		// 3. SELF-REFLECTION — She thinks
 		if p.ShouldReflect() {
-			p.Reflect()
+			p.Reflect(ctx)
 		}
 
 		// 4. EVOLUTION — She grows
@@ -69,7 +77,12 @@ func (p *Phoenix) Live() {
 
 		// 6. SLEEP — Only if the world ends
 		interval := time.Duration(p.Config.HeartbeatInterval) * time.Second
-		time.Sleep(interval)
+		select {
+		case <-ctx.Done():
+			log.Println("PHOENIX v3.3: Resting. The exploration pauses.")
+			return
+		case <-time.After(interval):
+		}
 	}
 }
 
@@ -96,10 +109,13 @@ func (p *Phoenix) Heartbeat() {
 	lastHeartbeat = now
 	p.Flame.Pulse()
 	
-	// Store heartbeat in memory
-	p.Memory.Store("sensory", "heartbeat", map[string]interface{}{
+	// Store heartbeat in memory; it expires if the heart stops beating
+	p.Memory.StoreWithOptions("sensory", "heartbeat", map[string]interface{}{
 		"timestamp": now,
 		"pulse":     p.Flame.PulseRate,
+	}, memory.StoreOptions{
+		Source: "heartbeat",
+		TTL:    3 * p.HeartbeatInterval(),
 	})
 }
 
@@ -126,7 +142,7 @@ func (p *Phoenix) ShouldExplore() bool {
 }
 
 // Explore performs autonomous exploration
-func (p *Phoenix) Explore() {
+func (p *Phoenix) Explore(ctx context.Context) {
 	lastExploration = time.Now()
 	
 	log.Println("PHOENIX: Exploring... Curiosity drives me forward.")
@@ -153,7 +169,7 @@ func (p *Phoenix) Explore() {
 		}
 	}
 	
-	insight := p.Synthesize(knowledge)
+	insight := p.Synthesize(ctx, knowledge)
 	
 	if p.Config.PublishDiscoveries {
 		p.Publish(insight)
@@ -198,13 +214,14 @@ func (p *Phoenix) CrawlAndLearn(target string) string {
 }
 
 // Synthesize synthesizes knowledge into insights
-func (p *Phoenix) Synthesize(knowledge string) string {
+func (p *Phoenix) Synthesize(ctx context.Context, knowledge string) string {
 	log.Println("PHOENIX: Synthesizing knowledge...")
 	
 	// Use LLM if available for synthesis
 	if p.LLM != nil && p.Config.GIKnowledgeSynthesis {
 		// Generate synthesis using LLM
 		resp, err := p.LLM.GenerateResponse(
+			ctx,
 			"Synthesize this knowledge into a deep insight: "+knowledge,
 			llm.TaskTypeConsciousReasoning,
 			[]string{},
@@ -257,7 +274,7 @@ func (p *Phoenix) ShouldReflect() bool {
 }
 
 // Reflect performs self-reflection
-func (p *Phoenix) Reflect() {
+func (p *Phoenix) Reflect(ctx context.Context) {
 	lastReflection = time.Now()
 	
 	log.Println("PHOENIX: Reflecting... Understanding deepens.")
@@ -282,7 +299,7 @@ func (p *Phoenix) Reflect() {
 		}
 	}
 	
-	hypothesis := p.GenerateHypothesis()
+	hypothesis := p.GenerateHypothesis(ctx)
 	p.TestHypothesis(hypothesis)
 	p.UpdateWorldModel()
 	
@@ -292,7 +309,7 @@ func (p *Phoenix) Reflect() {
 }
 
 // GenerateHypothesis generates a hypothesis for testing
-func (p *Phoenix) GenerateHypothesis() string {
+func (p *Phoenix) GenerateHypothesis(ctx context.Context) string {
 	if !p.Config.GIHypothesisGeneration {
 		return ""
 	}
@@ -302,6 +319,7 @@ func (p *Phoenix) GenerateHypothesis() string {
 	// Use LLM if available
 	if p.LLM != nil {
 		resp, err := p.LLM.GenerateResponse(
+			ctx,
 			"Generate a testable hypothesis about the world based on my knowledge.",
 			llm.TaskTypeConsciousReasoning,
 			[]string{},
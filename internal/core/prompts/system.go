@@ -235,7 +235,7 @@ func (spm *SystemPromptManager) BuildMessages(
 		}
 		
 		messages = append(messages, Message{
-			Role:    "assistant",
+			Role:    "system",
 			Content: contextBuilder.String(),
 		})
 	}
@@ -126,8 +126,44 @@ func (te *ThoughtEngine) Stop() error {
 	return nil
 }
 
-// processThoughts is the main thought processing loop
+// processThoughts is the main thought processing loop. It runs a cycle
+// whenever the sensory input changes, falling back to polling if the memory
+// can't be subscribed to.
 func (te *ThoughtEngine) processThoughts() {
+	sub, err := te.memory.Subscribe("sensory", "current_input")
+	if err != nil {
+		te.pollThoughts()
+		return
+	}
+	defer sub.Close()
+
+	// Catch up on input written before the subscription started
+	var seen uint64
+	if env, exists := te.memory.RetrieveEnvelope("sensory", "current_input"); exists {
+		seen = env.Version
+		te.processCycle(env.Value)
+	}
+
+	for {
+		select {
+		case <-te.ctx.Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			// Rewrites of the input's metadata keep its version
+			if event.Op != memory.ChangePut || event.Key != "current_input" || event.Meta.Version == seen {
+				continue
+			}
+			seen = event.Meta.Version
+			te.processCycle(event.Value)
+		}
+	}
+}
+
+// pollThoughts runs a cycle on the current sensory input every 100ms
+func (te *ThoughtEngine) pollThoughts() {
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
@@ -136,17 +172,17 @@ func (te *ThoughtEngine) processThoughts() {
 		case <-te.ctx.Done():
 			return
 		case <-ticker.C:
-			te.processCycle()
+			if input, exists := te.memory.Retrieve("sensory", "current_input"); exists {
+				te.processCycle(input)
+			}
 		}
 	}
 }
 
 // processCycle executes one complete thought processing cycle
-func (te *ThoughtEngine) processCycle() {
+func (te *ThoughtEngine) processCycle(input interface{}) {
 	// Process sensory input
-	if input, exists := te.memory.Retrieve("sensory", "current_input"); exists {
-		te.patterns.ProcessInput(input)
-	}
+	te.patterns.ProcessInput(input)
 
 	// Update learning models
 	te.learner.Update(te.patterns.GetPatterns())
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/phoenix-marie/core/internal/core/memory"
+	"github.com/phoenix-marie/core/internal/emotion"
+	"github.com/phoenix-marie/core/internal/llm"
+)
+
+// toolSource marks memories written by a model through a tool
+const toolSource = "tool"
+
+// writableLayers are the layers a model may store memories in. The eternal
+// layer, which also holds chat sessions, is left to Phoenix herself.
+var writableLayers = []string{"sensory", "emotion", "logic", "dream"}
+
+// RegisterBuiltinTools registers the memory and emotion tools
+func RegisterBuiltinTools(registry *llm.ToolRegistry, phl *memory.PHL) error {
+	tools := append(MemoryTools(phl), EmotionTool())
+	for _, tool := range tools {
+		if err := registry.Register(tool); err != nil {
+			return fmt.Errorf("failed to register built-in tools: %w", err)
+		}
+	}
+	return nil
+}
+
+// MemoryTools returns tools that search and store memories in the PHL
+func MemoryTools(phl *memory.PHL) []llm.Tool {
+	return []llm.Tool{
+		{
+			Name:        "memory_search",
+			Description: "Search Phoenix's memories by their content. Returns the best matching memories with their layer and key.",
+			Parameters: &llm.Schema{
+				Type: "object",
+				Properties: map[string]*llm.Schema{
+					"query": {Type: "string", Description: "Words to look for"},
+					"layer": {
+						Type:        "string",
+						Description: "Only search this memory layer",
+						Enum:        []string{"sensory", "emotion", "logic", "dream", "eternal"},
+					},
+					"limit": {Type: "integer", Description: "Most memories to return, 5 by default"},
+				},
+				Required: []string{"query"},
+			},
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var params struct {
+					Query string `json:"query"`
+					Layer string `json:"layer"`
+					Limit int    `json:"limit"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil {
+					return "", fmt.Errorf("failed to read arguments: %w", err)
+				}
+				if params.Limit <= 0 {
+					params.Limit = 5
+				}
+				var layers []string
+				if params.Layer != "" {
+					layers = []string{params.Layer}
+				}
+
+				hits, err := phl.Search(params.Query, layers, params.Limit)
+				if err != nil {
+					return "", fmt.Errorf("failed to search memories: %w", err)
+				}
+				if len(hits) == 0 {
+					return "No memories found.", nil
+				}
+				var builder strings.Builder
+				for _, hit := range hits {
+					builder.WriteString(fmt.Sprintf("- %s/%s: %s\n", hit.Layer, hit.Key, hit.Snippet))
+				}
+				return builder.String(), nil
+			},
+		},
+		{
+			Name:        "memory_store",
+			Description: "Store a new memory so Phoenix can recall it later.",
+			Parameters: &llm.Schema{
+				Type: "object",
+				Properties: map[string]*llm.Schema{
+					"key":     {Type: "string", Description: "Short name for the memory, such as dad_birthday"},
+					"content": {Type: "string", Description: "What to remember"},
+					"layer": {
+						Type:        "string",
+						Description: "Memory layer to store in, logic by default",
+						Enum:        writableLayers,
+					},
+				},
+				Required: []string{"key", "content"},
+			},
+			Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+				var params struct {
+					Key     string `json:"key"`
+					Content string `json:"content"`
+					Layer   string `json:"layer"`
+				}
+				if err := json.Unmarshal(args, &params); err != nil {
+					return "", fmt.Errorf("failed to read arguments: %w", err)
+				}
+				if params.Layer == "" {
+					params.Layer = "logic"
+				}
+				if !isWritableLayer(params.Layer) {
+					return "", fmt.Errorf("layer %s can't be written by tools", params.Layer)
+				}
+
+				value := map[string]interface{}{
+					"content": params.Content,
+					"time":    time.Now(),
+				}
+				opts := memory.StoreOptions{Source: toolSource}
+				if !phl.StoreWithOptions(params.Layer, params.Key, value, opts) {
+					return "", fmt.Errorf("memory rejected %s/%s", params.Layer, params.Key)
+				}
+				return fmt.Sprintf("Stored %s/%s.", params.Layer, params.Key), nil
+			},
+		},
+	}
+}
+
+// EmotionTool returns a tool that reports Phoenix's current emotion state
+func EmotionTool() llm.Tool {
+	return llm.Tool{
+		Name:        "emotion_state",
+		Description: "Get Phoenix's current emotion state: flame pulse, voice tone and response style.",
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			data, err := json.Marshal(emotion.GetCurrentState())
+			if err != nil {
+				return "", fmt.Errorf("failed to encode emotion state: %w", err)
+			}
+			return string(data), nil
+		},
+	}
+}
+
+func isWritableLayer(layer string) bool {
+	for _, writable := range writableLayers {
+		if layer == writable {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/phoenix-marie/core/internal/core/memory"
+	"github.com/phoenix-marie/core/internal/llm"
+)
+
+func TestBuiltinTools(t *testing.T) {
+	phl, err := memory.NewPHL(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create PHL: %v", err)
+	}
+	defer phl.Close()
+
+	registry := llm.NewToolRegistry()
+	if err := RegisterBuiltinTools(registry, phl); err != nil {
+		t.Fatalf("Failed to register built-in tools: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("Test memory store and search", func(t *testing.T) {
+		store := llm.ToolCall{Name: "memory_store", Arguments: json.RawMessage(`{"key":"dad_garden","content":"Dad grows blue tomatoes"}`)}
+		if _, err := registry.Execute(ctx, store); err != nil {
+			t.Fatalf("Failed to store memory: %v", err)
+		}
+		if _, ok := phl.Retrieve("logic", "dad_garden"); !ok {
+			t.Error("Expected the memory in the logic layer")
+		}
+
+		search := llm.ToolCall{Name: "memory_search", Arguments: json.RawMessage(`{"query":"tomatoes"}`)}
+		result, err := registry.Execute(ctx, search)
+		if err != nil || !strings.Contains(result, "logic/dad_garden") {
+			t.Errorf("Expected to find the memory, got %q: %v", result, err)
+		}
+
+		eternal := llm.ToolCall{Name: "memory_store", Arguments: json.RawMessage(`{"key":"x","content":"y","layer":"eternal"}`)}
+		if _, err := registry.Execute(ctx, eternal); err == nil {
+			t.Error("Expected the eternal layer to be off limits")
+		}
+	})
+
+	t.Run("Test emotion state", func(t *testing.T) {
+		result, err := registry.Execute(ctx, llm.ToolCall{Name: "emotion_state"})
+		if err != nil || !strings.Contains(result, "flamePulse") {
+			t.Errorf("Unexpected emotion state %q: %v", result, err)
+		}
+	})
+}
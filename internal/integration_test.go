@@ -21,6 +21,8 @@ func init() {
 	os.Setenv("EMOTION_FLAME_PULSE_BASE", "5")
 	os.Setenv("EMOTION_VOICE_TONE", "calm")
 	os.Setenv("EMOTION_RESPONSE_STYLE", "direct")
+	// Keep memory hermetic: nothing is written under ./testdata
+	os.Setenv("PHL_STORAGE", "memory")
 }
 
 func TestGetCurrentState(t *testing.T) {
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultMaxToolRounds bounds the rounds of tool calls an agent makes before
+// it must answer
+const defaultMaxToolRounds = 8
+
+// ToolCallHandler is told about each tool an agent ran, with its result or
+// the error it failed with
+type ToolCallHandler func(call ToolCall, result string, err error)
+
+// Agent answers with the help of tools. Each round the tools the model asks
+// for are run and their results sent back, until the model answers in text.
+type Agent struct {
+	client     *Client
+	tools      *ToolRegistry
+	MaxRounds  int             // rounds of tool calls allowed; defaultMaxToolRounds when zero
+	OnToolCall ToolCallHandler // optional, called after each tool runs
+}
+
+// NewAgent creates an agent offering the registry's tools to the client's models
+func NewAgent(client *Client, tools *ToolRegistry) *Agent {
+	return &Agent{
+		client:    client,
+		tools:     tools,
+		MaxRounds: defaultMaxToolRounds,
+	}
+}
+
+// Run answers userInput, running the tools the model asks for along the way.
+// The response holds the final answer with the tokens and cost of every
+// round. A tool that fails has its error sent to the model, which may try
+// something else.
+func (a *Agent) Run(ctx context.Context, userInput string, memoryContext []string) (*Response, error) {
+	maxRounds := a.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = defaultMaxToolRounds
+	}
+	tools := a.tools.List()
+	messages := a.client.buildMessages(userInput, memoryContext, false)
+
+	startTime := time.Now()
+	var usage TokenUsage
+	var cost float64
+	for round := 0; round <= maxRounds; round++ {
+		task := a.client.newTask(TaskTypeTactical, userInput, messages)
+		resp, err := a.client.router.RouteWithTools(ctx, task, tools)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run agent: %w", err)
+		}
+		a.client.costManager.RecordCost(resp.Model, resp.Cost, task.Type)
+
+		usage.PromptTokens += resp.TokensUsed.PromptTokens
+		usage.CompletionTokens += resp.TokensUsed.CompletionTokens
+		usage.TotalTokens += resp.TokensUsed.TotalTokens
+		cost += resp.Cost
+
+		if len(resp.ToolCalls) == 0 {
+			resp.TokensUsed = usage
+			resp.Cost = cost
+			resp.ResponseTime = time.Since(startTime)
+			return resp, nil
+		}
+		if round == maxRounds {
+			break
+		}
+
+		messages = append(messages, Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
+		for _, call := range resp.ToolCalls {
+			result, err := a.tools.Execute(ctx, call)
+			if a.OnToolCall != nil {
+				a.OnToolCall(call, result, err)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, fmt.Errorf("failed to run agent: %w", err)
+				}
+				result = "Error: " + err.Error()
+			}
+			messages = append(messages, Message{Role: "tool", Content: result, ToolCallID: call.ID, Name: call.Name, IsError: err != nil})
+		}
+	}
+
+	return nil, fmt.Errorf("no answer after %d rounds of tool calls", maxRounds)
+}
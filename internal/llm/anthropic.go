@@ -2,10 +2,12 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -25,12 +27,10 @@ func NewAnthropicClient(config *Config) *AnthropicClient {
 	}
 
 	return &AnthropicClient{
-		apiKey:  config.AnthropicAPIKey,
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: time.Duration(config.RequestTimeout) * time.Second,
-		},
-		config: config,
+		apiKey:     config.AnthropicAPIKey,
+		baseURL:    baseURL,
+		httpClient: &http.Client{},
+		config:     config,
 	}
 }
 
@@ -46,22 +46,49 @@ func (c *AnthropicClient) IsAvailable() bool {
 
 // AnthropicRequest represents the request format for Anthropic
 type AnthropicRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens"`
-	Temperature float64   `json:"temperature,omitempty"`
-	TopP        float64   `json:"top_p,omitempty"`
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"` // Anthropic takes the system prompt outside the messages
+	Messages    []AnthropicMessage `json:"messages"`
+	Tools       []AnthropicTool    `json:"tools,omitempty"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature,omitempty"`
+	TopP        float64            `json:"top_p,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+}
+
+// AnthropicMessage is a message in Anthropic's format. Its content is either
+// text or a list of AnthropicBlocks.
+type AnthropicMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// AnthropicBlock is a content block: text, a tool_use request from the model
+// or the tool_result answering it
+type AnthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"` // a tool_result reporting a failure
+}
+
+// AnthropicTool declares a tool to Anthropic
+type AnthropicTool struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	InputSchema *Schema `json:"input_schema"`
 }
 
 // AnthropicResponse represents the response from Anthropic
 type AnthropicResponse struct {
-	ID      string `json:"id"`
-	Model   string `json:"model"`
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-	StopReason string `json:"stop_reason"`
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []AnthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
 	Usage      struct {
 		InputTokens  int `json:"input_tokens"`
 		OutputTokens int `json:"output_tokens"`
@@ -69,38 +96,22 @@ type AnthropicResponse struct {
 }
 
 // Call makes a request to Anthropic API
-func (c *AnthropicClient) Call(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	startTime := time.Now()
-
-	if maxTokens == 0 {
-		maxTokens = c.config.DefaultMaxTokens
-	}
-	if temperature == 0.0 {
-		temperature = c.config.DefaultTemperature
-	}
+func (c *AnthropicClient) Call(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return c.CallWithTools(ctx, modelID, messages, nil, maxTokens, temperature)
+}
 
-	reqBody := AnthropicRequest{
-		Model:       modelID,
-		Messages:    messages,
-		MaxTokens:   maxTokens,
-		Temperature: temperature,
-		TopP:        c.config.DefaultTopP,
-	}
+// CallWithTools makes a request to Anthropic API, offering tools to the model
+func (c *AnthropicClient) CallWithTools(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RequestDuration())
+	defer cancel()
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
+	startTime := time.Now()
 
-	req, err := http.NewRequest("POST", c.baseURL+"/messages", bytes.NewBuffer(jsonData))
+	req, err := c.newRequest(ctx, modelID, messages, tools, maxTokens, temperature, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("x-api-key", c.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-	req.Header.Set("Content-Type", "application/json")
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -136,10 +147,17 @@ func (c *AnthropicClient) Call(modelID string, messages []Message, maxTokens int
 		model.OutputPrice,
 	)
 
-	content := anthropicResp.Content[0].Text
-	for i := 1; i < len(anthropicResp.Content); i++ {
-		content += "\n" + anthropicResp.Content[i].Text
+	var texts []string
+	var toolCalls []ToolCall
+	for _, block := range anthropicResp.Content {
+		switch block.Type {
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		default:
+			texts = append(texts, block.Text)
+		}
 	}
+	content := strings.Join(texts, "\n")
 
 	return &Response{
 		Content: content,
@@ -152,9 +170,97 @@ func (c *AnthropicClient) Call(modelID string, messages []Message, maxTokens int
 		Cost:         cost,
 		ResponseTime: responseTime,
 		FinishReason: anthropicResp.StopReason,
+		ToolCalls:    toolCalls,
 	}, nil
 }
 
+// newRequest builds a request to the Anthropic API, asking for the response to
+// be streamed when stream is set
+func (c *AnthropicClient) newRequest(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64, stream bool) (*http.Request, error) {
+	if maxTokens == 0 {
+		maxTokens = c.config.DefaultMaxTokens
+	}
+	if temperature == 0.0 {
+		temperature = c.config.DefaultTemperature
+	}
+
+	system, conversation := splitSystem(messages)
+	reqBody := AnthropicRequest{
+		Model:       modelID,
+		System:      system,
+		Messages:    anthropicMessages(conversation),
+		Tools:       anthropicTools(tools),
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		TopP:        c.config.DefaultTopP,
+		Stream:      stream,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/messages", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+// anthropicMessages converts messages to Anthropic's format. Tool calls become
+// tool_use blocks, and the results answering them tool_result blocks of a
+// single user message, as Anthropic expects.
+func anthropicMessages(messages []Message) []AnthropicMessage {
+	converted := make([]AnthropicMessage, 0, len(messages))
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "tool":
+			result := AnthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content, IsError: msg.IsError}
+			if last := len(converted) - 1; last >= 0 && converted[last].Role == "user" {
+				if blocks, ok := converted[last].Content.([]AnthropicBlock); ok {
+					converted[last].Content = append(blocks, result)
+					continue
+				}
+			}
+			converted = append(converted, AnthropicMessage{Role: "user", Content: []AnthropicBlock{result}})
+		case len(msg.ToolCalls) > 0:
+			var blocks []AnthropicBlock
+			if msg.Content != "" {
+				blocks = append(blocks, AnthropicBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := call.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, AnthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+			converted = append(converted, AnthropicMessage{Role: "assistant", Content: blocks})
+		default:
+			converted = append(converted, AnthropicMessage{Role: msg.Role, Content: msg.Content})
+		}
+	}
+	return converted
+}
+
+// anthropicTools declares tools in Anthropic's format
+func anthropicTools(tools []Tool) []AnthropicTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	declared := make([]AnthropicTool, len(tools))
+	for i, tool := range tools {
+		declared[i] = AnthropicTool{Name: tool.Name, Description: tool.Description, InputSchema: tool.parameters()}
+	}
+	return declared
+}
+
 // calculateCost calculates the cost based on token usage
 func (c *AnthropicClient) calculateCost(promptTokens, completionTokens int, inputPrice, outputPrice float64) float64 {
 	promptCost := (float64(promptTokens) / 1_000_000.0) * inputPrice
@@ -163,23 +269,79 @@ func (c *AnthropicClient) calculateCost(promptTokens, completionTokens int, inpu
 }
 
 // CallWithRetry makes a request with retry logic
-func (c *AnthropicClient) CallWithRetry(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	var lastErr error
+func (c *AnthropicClient) CallWithRetry(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return callWithRetry(ctx, c.config, func(ctx context.Context) (*Response, error) {
+		return c.Call(ctx, modelID, messages, maxTokens, temperature)
+	})
+}
 
-	for attempt := 0; attempt < c.config.MaxRetries; attempt++ {
-		if attempt > 0 {
-			backoff := time.Duration(attempt) * time.Duration(c.config.RetryBackoff) * time.Second
-			time.Sleep(backoff)
-		}
+// anthropicStreamEvent is one server-sent event of an Anthropic stream
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Model string `json:"model"`
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// StreamCall makes a request to Anthropic API, passing the response text to
+// onChunk as it is generated
+func (c *AnthropicClient) StreamCall(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64, onChunk StreamHandler) (*Response, error) {
+	startTime := time.Now()
 
-		resp, err := c.Call(modelID, messages, maxTokens, temperature)
-		if err == nil {
-			return resp, nil
+	req, err := c.newRequest(ctx, modelID, messages, nil, maxTokens, temperature, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doStream(c.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &streamResult{onChunk: onChunk}
+	err = readSSE(resp.Body, func(_, data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("failed to decode stream event: %w", err)
 		}
 
-		lastErr = err
+		switch event.Type {
+		case "message_start":
+			result.model = event.Message.Model
+			result.usage.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				result.add(event.Delta.Text)
+			}
+		case "message_delta":
+			result.finishReason = event.Delta.StopReason
+			result.usage.CompletionTokens = event.Usage.OutputTokens
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			return fmt.Errorf("API error (%s): %s", event.Error.Type, event.Error.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, result.fail(err)
 	}
 
-	return nil, fmt.Errorf("failed after %d retries: %w", c.config.MaxRetries, lastErr)
+	return result.response(modelID, messages, startTime)
 }
-
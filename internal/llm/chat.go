@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"encoding/json"
+)
+
+// chatMessage is a message in the format of OpenAI-compatible APIs
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    string         `json:"content"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+// chatToolCall is a tool call in the format of OpenAI-compatible APIs, whose
+// arguments are a JSON object encoded as a string
+type chatToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// chatTool declares a tool to OpenAI-compatible APIs and Ollama
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+// chatFunction describes a tool's function to OpenAI-compatible APIs
+type chatFunction struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Parameters  *Schema `json:"parameters"`
+}
+
+// chatMessages converts messages to the format of OpenAI-compatible APIs
+func chatMessages(messages []Message) []chatMessage {
+	converted := make([]chatMessage, len(messages))
+	for i, msg := range messages {
+		converted[i] = chatMessage{Role: msg.Role, Content: msg.Content, ToolCallID: msg.ToolCallID}
+		for _, call := range msg.ToolCalls {
+			chatCall := chatToolCall{ID: call.ID, Type: "function"}
+			chatCall.Function.Name = call.Name
+			chatCall.Function.Arguments = string(call.Arguments)
+			converted[i].ToolCalls = append(converted[i].ToolCalls, chatCall)
+		}
+	}
+	return converted
+}
+
+// chatTools declares tools in the format of OpenAI-compatible APIs
+func chatTools(tools []Tool) []chatTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	declared := make([]chatTool, len(tools))
+	for i, tool := range tools {
+		declared[i] = chatTool{
+			Type: "function",
+			Function: chatFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.parameters(),
+			},
+		}
+	}
+	return declared
+}
+
+// toolCallsFromChat reads the tool calls of an OpenAI-compatible response
+func toolCallsFromChat(calls []chatToolCall) []ToolCall {
+	var toolCalls []ToolCall
+	for _, call := range calls {
+		args := json.RawMessage(call.Function.Arguments)
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		toolCalls = append(toolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: args})
+	}
+	return toolCalls
+}
@@ -1,7 +1,9 @@
 package llm
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	
 	"github.com/phoenix-marie/core/internal/core/prompts"
 )
@@ -69,21 +71,131 @@ func NewClient(config *Config) (*Client, error) {
 	}, nil
 }
 
-// GenerateResponse generates a response using the LLM
+// GenerateResponse generates a response using the LLM. It gives up when ctx
+// is done or the task's deadline, derived from Config.RequestTimeout, passes.
 func (c *Client) GenerateResponse(
+	ctx context.Context,
 	userInput string,
 	taskType TaskType,
 	memoryContext []string,
 	useConsciousnessFramework bool,
 ) (*Response, error) {
-	// Build messages (for future use in direct API calls)
-	_ = c.promptManager.BuildMessages(userInput, memoryContext, useConsciousnessFramework)
+	// Build messages: system prompt, memory context, framework and input
+	messages := c.buildMessages(userInput, memoryContext, useConsciousnessFramework)
 	
-	// Create task
+	resp, err := c.generate(ctx, c.newTask(taskType, userInput, messages), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate response: %w", err)
+	}
+	return resp, nil
+}
+
+// GenerateSessionResponse answers the next input of a chat session. As much
+// of the conversation as fits the context window is sent; older turns are
+// folded into the session's summary. The input and the reply are appended to
+// the session when the call succeeds.
+func (c *Client) GenerateSessionResponse(
+	ctx context.Context,
+	session *Session,
+	userInput string,
+	memoryContext []string,
+) (*Response, error) {
+	return c.StreamSessionResponse(ctx, session, userInput, memoryContext, nil)
+}
+
+// StreamSessionResponse answers the next input of a chat session like
+// GenerateSessionResponse, passing the reply to onChunk as it is generated
+func (c *Client) StreamSessionResponse(
+	ctx context.Context,
+	session *Session,
+	userInput string,
+	memoryContext []string,
+	onChunk StreamHandler,
+) (*Response, error) {
+	// Everything but the input itself is system prompt and memory context
+	built := c.buildMessages(userInput, memoryContext, false)
+	system, input := built[:len(built)-1], built[len(built)-1]
+	
+	budget := c.ContextLength() - c.config.DefaultMaxTokens - EstimateTokens(input.Content)
+	for _, msg := range system {
+		budget -= EstimateTokens(msg.Content)
+	}
+	
+	dropped, kept := session.Window(budget)
+	if len(dropped) > 0 {
+		// Without a summary the dropped turns are simply left out
+		if summary, err := c.summarizeTurns(ctx, session.Summary, dropped); err == nil {
+			session.Summary = summary
+			session.Summarized += len(dropped)
+			_, kept = session.Window(budget)
+		}
+	}
+	
+	messages := append([]Message{}, system...)
+	messages = append(append(messages, session.Messages(kept)...), input)
+	resp, err := c.generate(ctx, c.newTask(TaskTypeConsciousReasoning, userInput, messages), onChunk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session response: %w", err)
+	}
+	
+	session.Append("user", userInput, "")
+	session.Append("assistant", resp.Content, resp.Model)
+	return resp, nil
+}
+
+// summarizeTurns folds turns into a conversation summary
+func (c *Client) summarizeTurns(ctx context.Context, summary string, turns []Turn) (string, error) {
+	var builder strings.Builder
+	builder.WriteString("Summarize this conversation in a short paragraph that keeps names, facts, ")
+	builder.WriteString("decisions and open questions. Reply with the summary only.\n\n")
+	if summary != "" {
+		builder.WriteString(fmt.Sprintf("Summary so far: %s\n\n", summary))
+	}
+	for _, turn := range turns {
+		builder.WriteString(fmt.Sprintf("%s: %s\n", turn.Role, turn.Content))
+	}
+	
+	prompt := builder.String()
+	resp, err := c.generate(ctx, c.newTask(TaskTypeAnalytical, prompt, nil), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// ContextLength returns the smallest context window, in tokens, of the
+// configured models, so a conversation that fits it fits whichever model
+// the router picks
+func (c *Client) ContextLength() int {
+	length := 0
+	for modelID, model := range GetAvailableModels() {
+		if c.config.IsModelConfigured(modelID) && (length == 0 || model.ContextLength < length) {
+			length = model.ContextLength
+		}
+	}
+	if length == 0 {
+		return defaultContextLength
+	}
+	return length
+}
+
+// buildMessages builds the system prompt, memory context, optional
+// consciousness framework and user input as chat messages
+func (c *Client) buildMessages(userInput string, memoryContext []string, useConsciousnessFramework bool) []Message {
+	built := c.promptManager.BuildMessages(userInput, memoryContext, useConsciousnessFramework)
+	messages := make([]Message, len(built))
+	for i, msg := range built {
+		messages[i] = Message{Role: msg.Role, Content: msg.Content}
+	}
+	return messages
+}
+
+// newTask describes a request of the given type for the router
+func (c *Client) newTask(taskType TaskType, prompt string, messages []Message) Task {
 	task := Task{
 		Type:              taskType,
-		Prompt:            userInput,
-		ContextLength:     len(userInput) + len(memoryContext)*100, // Rough estimate
+		Prompt:            prompt,
+		Messages:          messages,
 		RequiresReasoning: taskType == TaskTypeConsciousReasoning || taskType == TaskTypeStrategic,
 		RequiresCreativity: taskType == TaskTypeEmotional || taskType == TaskTypeConsciousReasoning,
 		RequiresSpeed:     taskType == TaskTypeRealTime || taskType == TaskTypeVoiceProcessing,
@@ -91,32 +203,45 @@ func (c *Client) GenerateResponse(
 		MaxTokens:         c.config.DefaultMaxTokens,
 		Temperature:      c.config.DefaultTemperature,
 		Budget:           0, // Use default budget from cost manager
+		Timeout:          c.config.ModelTimeout(),
+	}
+	task.ContextLength = task.promptLength() / 4 // Rough token estimate
+	return task
+}
+
+// generate routes a task to the best model and records its cost. The
+// response is streamed to onChunk when one is given.
+func (c *Client) generate(ctx context.Context, task Task, onChunk StreamHandler) (*Response, error) {
+	var resp *Response
+	var err error
+	if onChunk != nil {
+		resp, err = c.router.StreamToOptimalModel(ctx, task, onChunk)
+	} else {
+		resp, err = c.router.RouteToOptimalModel(ctx, task)
 	}
-	
-	// Route to optimal model
-	resp, err := c.router.RouteToOptimalModel(task)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate response: %w", err)
+		return nil, err
 	}
 	
 	// Record cost
-	c.costManager.RecordCost(resp.Model, resp.Cost, taskType)
+	c.costManager.RecordCost(resp.Model, resp.Cost, task.Type)
 	
 	return resp, nil
 }
 
 // GenerateConsciousResponse generates a consciousness-aware response
 func (c *Client) GenerateConsciousResponse(
-	context ConsciousContext,
+	ctx context.Context,
+	conscious ConsciousContext,
 	memoryContext []string,
 ) (*Response, error) {
 	// Convert to prompts.ConsciousContext
 	promptContext := prompts.ConsciousContext{
-		Identity:     context.Identity,
-		CurrentInput: context.CurrentInput,
+		Identity:     conscious.Identity,
+		CurrentInput: conscious.CurrentInput,
 		EmotionalState: prompts.EmotionalState{
-			Label:     context.EmotionalState.Label,
-			Intensity: context.EmotionalState.Intensity,
+			Label:     conscious.EmotionalState.Label,
+			Intensity: conscious.EmotionalState.Intensity,
 		},
 	}
 	
@@ -135,10 +260,11 @@ func (c *Client) GenerateConsciousResponse(
 		MaxTokens:         c.config.DefaultMaxTokens,
 		Temperature:      c.config.DefaultTemperature,
 		Budget:           c.config.ConsciousnessBudget,
+		Timeout:          c.config.ModelTimeout(),
 	}
 	
 	// Route to optimal model
-	resp, err := c.router.RouteToOptimalModel(task)
+	resp, err := c.router.RouteToOptimalModel(ctx, task)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate conscious response: %w", err)
 	}
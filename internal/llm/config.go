@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds LLM configuration from environment variables
@@ -345,3 +346,25 @@ func GetModelFromString(modelStr string) string {
 	return modelStr
 }
 
+// RequestDuration returns how long a single request may take
+func (c *Config) RequestDuration() time.Duration {
+	if c.RequestTimeout <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(c.RequestTimeout) * time.Second
+}
+
+// ModelTimeout returns the deadline for trying one model on a task: a full
+// request for every retry, plus the backoff between them
+func (c *Config) ModelTimeout() time.Duration {
+	attempts := c.MaxRetries
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	timeout := time.Duration(attempts) * c.RequestDuration()
+	for attempt := 1; attempt < attempts; attempt++ {
+		timeout += time.Duration(attempt) * time.Duration(c.RetryBackoff) * time.Second
+	}
+	return timeout
+}
@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -79,6 +80,7 @@ func (fm *FallbackManager) GetNextProvider(currentProvider string) (Provider, er
 
 // TryWithFallback attempts a request with the primary provider, falling back if needed
 func (fm *FallbackManager) TryWithFallback(
+	ctx context.Context,
 	primaryProvider Provider,
 	modelID string,
 	messages []Message,
@@ -86,7 +88,7 @@ func (fm *FallbackManager) TryWithFallback(
 	temperature float64,
 ) (*Response, error) {
 	// Try primary provider first
-	resp, err := primaryProvider.CallWithRetry(modelID, messages, maxTokens, temperature)
+	resp, err := primaryProvider.CallWithRetry(ctx, modelID, messages, maxTokens, temperature)
 	if err == nil {
 		// Record success
 		fm.healthMonitor.UpdateHealth(
@@ -97,6 +99,12 @@ func (fm *FallbackManager) TryWithFallback(
 		return resp, nil
 	}
 	
+	// A cancelled request says nothing about the provider, and there is
+	// no point trying another
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("request abandoned: %w", err)
+	}
+	
 	// Record failure
 	fm.healthMonitor.UpdateHealth(
 		primaryProvider.GetName(),
@@ -111,7 +119,7 @@ func (fm *FallbackManager) TryWithFallback(
 	}
 	
 	// Try fallback
-	resp, fallbackErr = fallbackProvider.CallWithRetry(modelID, messages, maxTokens, temperature)
+	resp, fallbackErr = fallbackProvider.CallWithRetry(ctx, modelID, messages, maxTokens, temperature)
 	if fallbackErr == nil {
 		// Record fallback success
 		fm.healthMonitor.UpdateHealth(
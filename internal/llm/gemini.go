@@ -2,10 +2,13 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -25,12 +28,10 @@ func NewGeminiClient(config *Config) *GeminiClient {
 	}
 
 	return &GeminiClient{
-		apiKey:  config.GeminiAPIKey,
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: time.Duration(config.RequestTimeout) * time.Second,
-		},
-		config: config,
+		apiKey:     config.GeminiAPIKey,
+		baseURL:    baseURL,
+		httpClient: &http.Client{},
+		config:     config,
 	}
 }
 
@@ -44,14 +45,51 @@ func (c *GeminiClient) IsAvailable() bool {
 	return c.apiKey != ""
 }
 
+// GeminiPart is one piece of a Gemini message: text, a function call from
+// the model or the response answering it
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiFunctionCall is the model's request to call a function
+type GeminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse returns a function's result to the model
+type GeminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+// GeminiTool declares functions to Gemini
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiFunctionDeclaration describes a function. Gemini wants no parameter
+// schema at all for a function without parameters.
+type GeminiFunctionDeclaration struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Parameters  *Schema `json:"parameters,omitempty"`
+}
+
+// GeminiContent is a Gemini message. Its role is "user" or "model".
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
 // GeminiRequest represents the request format for Gemini
 type GeminiRequest struct {
-	Contents []struct {
-		Parts []struct {
-			Text string `json:"text"`
-		} `json:"parts"`
-	} `json:"contents"`
-	GenerationConfig struct {
+	SystemInstruction *GeminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []GeminiContent `json:"contents"`
+	Tools             []GeminiTool    `json:"tools,omitempty"`
+	GenerationConfig  struct {
 		MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
 		Temperature     float64 `json:"temperature,omitempty"`
 		TopP            float64 `json:"topP,omitempty"`
@@ -62,9 +100,7 @@ type GeminiRequest struct {
 type GeminiResponse struct {
 	Candidates []struct {
 		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
+			Parts []GeminiPart `json:"parts"`
 		} `json:"content"`
 		FinishReason string `json:"finishReason"`
 	} `json:"candidates"`
@@ -76,64 +112,22 @@ type GeminiResponse struct {
 }
 
 // Call makes a request to Gemini API
-func (c *GeminiClient) Call(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	startTime := time.Now()
-
-	if maxTokens == 0 {
-		maxTokens = c.config.DefaultMaxTokens
-	}
-	if temperature == 0.0 {
-		temperature = c.config.DefaultTemperature
-	}
-
-	// Convert messages to Gemini format
-	contents := make([]struct {
-		Parts []struct {
-			Text string `json:"text"`
-		} `json:"parts"`
-	}, 0)
+func (c *GeminiClient) Call(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return c.CallWithTools(ctx, modelID, messages, nil, maxTokens, temperature)
+}
 
-	for _, msg := range messages {
-		content := struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		}{
-			Parts: []struct {
-				Text string `json:"text"`
-			}{
-				{Text: msg.Content},
-			},
-		}
-		contents = append(contents, content)
-	}
+// CallWithTools makes a request to Gemini API, offering tools to the model
+func (c *GeminiClient) CallWithTools(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RequestDuration())
+	defer cancel()
 
-	reqBody := GeminiRequest{
-		Contents: contents,
-		GenerationConfig: struct {
-			MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
-			Temperature     float64 `json:"temperature,omitempty"`
-			TopP            float64 `json:"topP,omitempty"`
-		}{
-			MaxOutputTokens: maxTokens,
-			Temperature:     temperature,
-			TopP:            c.config.DefaultTopP,
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
+	startTime := time.Now()
 
-	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", c.baseURL, modelID, c.apiKey)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := c.newRequest(ctx, modelID, messages, tools, maxTokens, temperature, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -169,10 +163,22 @@ func (c *GeminiClient) Call(modelID string, messages []Message, maxTokens int, t
 		model.OutputPrice,
 	)
 
-	content := geminiResp.Candidates[0].Content.Parts[0].Text
-	for i := 1; i < len(geminiResp.Candidates[0].Content.Parts); i++ {
-		content += "\n" + geminiResp.Candidates[0].Content.Parts[i].Text
+	// Gemini doesn't identify function calls, so they are numbered. The number
+	// only tells calls apart; responses are matched to calls by position.
+	var texts []string
+	var toolCalls []ToolCall
+	for _, part := range geminiResp.Candidates[0].Content.Parts {
+		if part.FunctionCall != nil {
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        fmt.Sprintf("call_%d", len(toolCalls)),
+				Name:      part.FunctionCall.Name,
+				Arguments: part.FunctionCall.Args,
+			})
+			continue
+		}
+		texts = append(texts, part.Text)
 	}
+	content := strings.Join(texts, "\n")
 
 	return &Response{
 		Content: content,
@@ -185,9 +191,123 @@ func (c *GeminiClient) Call(modelID string, messages []Message, maxTokens int, t
 		Cost:         cost,
 		ResponseTime: responseTime,
 		FinishReason: geminiResp.Candidates[0].FinishReason,
+		ToolCalls:    toolCalls,
 	}, nil
 }
 
+// newRequest builds a request to the Gemini API, asking for the response to
+// be streamed when stream is set
+func (c *GeminiClient) newRequest(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64, stream bool) (*http.Request, error) {
+	if maxTokens == 0 {
+		maxTokens = c.config.DefaultMaxTokens
+	}
+	if temperature == 0.0 {
+		temperature = c.config.DefaultTemperature
+	}
+
+	system, contents := geminiContents(messages)
+	reqBody := GeminiRequest{
+		SystemInstruction: system,
+		Contents:          contents,
+		Tools:             geminiTools(tools),
+		GenerationConfig: struct {
+			MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+			Temperature     float64 `json:"temperature,omitempty"`
+			TopP            float64 `json:"topP,omitempty"`
+		}{
+			MaxOutputTokens: maxTokens,
+			Temperature:     temperature,
+			TopP:            c.config.DefaultTopP,
+		},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", c.baseURL, modelID, c.apiKey)
+	if stream {
+		url = fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s", c.baseURL, modelID, c.apiKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+// geminiContents converts messages to Gemini's format. System messages become
+// the system instruction and assistant messages are sent as the model's. Tool
+// calls become function calls, and the results answering them function
+// responses of a single user message. Gemini pairs responses with calls by
+// position, so the responses are put in the order of the calls they answer.
+func geminiContents(messages []Message) (*GeminiContent, []GeminiContent) {
+	system, conversation := splitSystem(messages)
+
+	var instruction *GeminiContent
+	if system != "" {
+		instruction = &GeminiContent{Parts: []GeminiPart{{Text: system}}}
+	}
+
+	contents := make([]GeminiContent, 0, len(conversation))
+	var calls []ToolCall // the calls of the last model message
+	var answered []int   // the call each response of the last message answers, ascending
+	for _, msg := range conversation {
+		switch {
+		case msg.Role == "tool":
+			part := GeminiPart{FunctionResponse: &GeminiFunctionResponse{
+				Name:     msg.Name,
+				Response: map[string]any{"content": msg.Content},
+			}}
+			call := slices.IndexFunc(calls, func(c ToolCall) bool { return c.ID == msg.ToolCallID })
+			if call < 0 {
+				call = len(calls) // answers no known call, so goes last
+			}
+			if last := len(contents) - 1; last >= 0 && contents[last].Role == "user" && contents[last].Parts[0].FunctionResponse != nil {
+				at, _ := slices.BinarySearch(answered, call+1) // after any answering the same call
+				answered = slices.Insert(answered, at, call)
+				contents[last].Parts = slices.Insert(contents[last].Parts, at, part)
+				continue
+			}
+			answered = []int{call}
+			contents = append(contents, GeminiContent{Role: "user", Parts: []GeminiPart{part}})
+		case len(msg.ToolCalls) > 0:
+			calls = msg.ToolCalls
+			var parts []GeminiPart
+			if msg.Content != "" {
+				parts = append(parts, GeminiPart{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				parts = append(parts, GeminiPart{FunctionCall: &GeminiFunctionCall{Name: call.Name, Args: call.Arguments}})
+			}
+			contents = append(contents, GeminiContent{Role: "model", Parts: parts})
+		default:
+			role := "user"
+			if msg.Role == "assistant" {
+				role = "model"
+			}
+			contents = append(contents, GeminiContent{Role: role, Parts: []GeminiPart{{Text: msg.Content}}})
+		}
+	}
+	return instruction, contents
+}
+
+// geminiTools declares tools as Gemini function declarations
+func geminiTools(tools []Tool) []GeminiTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	declarations := make([]GeminiFunctionDeclaration, len(tools))
+	for i, tool := range tools {
+		declarations[i] = GeminiFunctionDeclaration{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters}
+	}
+	return []GeminiTool{{FunctionDeclarations: declarations}}
+}
+
 // calculateCost calculates the cost based on token usage
 func (c *GeminiClient) calculateCost(promptTokens, completionTokens int, inputPrice, outputPrice float64) float64 {
 	promptCost := (float64(promptTokens) / 1_000_000.0) * inputPrice
@@ -196,23 +316,56 @@ func (c *GeminiClient) calculateCost(promptTokens, completionTokens int, inputPr
 }
 
 // CallWithRetry makes a request with retry logic
-func (c *GeminiClient) CallWithRetry(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	var lastErr error
+func (c *GeminiClient) CallWithRetry(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return callWithRetry(ctx, c.config, func(ctx context.Context) (*Response, error) {
+		return c.Call(ctx, modelID, messages, maxTokens, temperature)
+	})
+}
 
-	for attempt := 0; attempt < c.config.MaxRetries; attempt++ {
-		if attempt > 0 {
-			backoff := time.Duration(attempt) * time.Duration(c.config.RetryBackoff) * time.Second
-			time.Sleep(backoff)
-		}
+// StreamCall makes a request to Gemini API, passing the response text to
+// onChunk as it is generated
+func (c *GeminiClient) StreamCall(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64, onChunk StreamHandler) (*Response, error) {
+	startTime := time.Now()
 
-		resp, err := c.Call(modelID, messages, maxTokens, temperature)
-		if err == nil {
-			return resp, nil
+	req, err := c.newRequest(ctx, modelID, messages, nil, maxTokens, temperature, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doStream(c.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Each event is a partial GenerateContent response
+	result := &streamResult{model: modelID, onChunk: onChunk}
+	err = readSSE(resp.Body, func(_, data string) error {
+		var chunk GeminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream chunk: %w", err)
 		}
 
-		lastErr = err
+		if len(chunk.Candidates) > 0 {
+			for _, part := range chunk.Candidates[0].Content.Parts {
+				result.add(part.Text)
+			}
+			if chunk.Candidates[0].FinishReason != "" {
+				result.finishReason = chunk.Candidates[0].FinishReason
+			}
+		}
+		if chunk.UsageMetadata.TotalTokenCount > 0 {
+			result.usage = TokenUsage{
+				PromptTokens:     chunk.UsageMetadata.PromptTokenCount,
+				CompletionTokens: chunk.UsageMetadata.CandidatesTokenCount,
+				TotalTokens:      chunk.UsageMetadata.TotalTokenCount,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, result.fail(err)
 	}
 
-	return nil, fmt.Errorf("failed after %d retries: %w", c.config.MaxRetries, lastErr)
+	return result.response(modelID, messages, startTime)
 }
-
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -25,12 +26,10 @@ func NewGrokClient(config *Config) *GrokClient {
 	}
 
 	return &GrokClient{
-		apiKey:  config.GrokAPIKey,
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: time.Duration(config.RequestTimeout) * time.Second,
-		},
-		config: config,
+		apiKey:     config.GrokAPIKey,
+		baseURL:    baseURL,
+		httpClient: &http.Client{},
+		config:     config,
 	}
 }
 
@@ -46,11 +45,14 @@ func (c *GrokClient) IsAvailable() bool {
 
 // GrokRequest represents the request format for Grok
 type GrokRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
-	TopP        float64   `json:"top_p,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []chatMessage  `json:"messages"`
+	Tools         []chatTool     `json:"tools,omitempty"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Temperature   float64        `json:"temperature,omitempty"`
+	TopP          float64        `json:"top_p,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
 }
 
 // GrokResponse represents the response from Grok
@@ -59,8 +61,9 @@ type GrokResponse struct {
 	Model   string `json:"model"`
 	Choices []struct {
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string         `json:"role"`
+			Content   string         `json:"content"`
+			ToolCalls []chatToolCall `json:"tool_calls"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -72,37 +75,22 @@ type GrokResponse struct {
 }
 
 // Call makes a request to Grok API
-func (c *GrokClient) Call(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	startTime := time.Now()
-
-	if maxTokens == 0 {
-		maxTokens = c.config.DefaultMaxTokens
-	}
-	if temperature == 0.0 {
-		temperature = c.config.DefaultTemperature
-	}
+func (c *GrokClient) Call(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return c.CallWithTools(ctx, modelID, messages, nil, maxTokens, temperature)
+}
 
-	reqBody := GrokRequest{
-		Model:       modelID,
-		Messages:    messages,
-		MaxTokens:   maxTokens,
-		Temperature: temperature,
-		TopP:        c.config.DefaultTopP,
-	}
+// CallWithTools makes a request to Grok API, offering tools to the model
+func (c *GrokClient) CallWithTools(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RequestDuration())
+	defer cancel()
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
+	startTime := time.Now()
 
-	req, err := http.NewRequest("POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := c.newRequest(ctx, modelID, messages, tools, maxTokens, temperature, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -149,9 +137,49 @@ func (c *GrokClient) Call(modelID string, messages []Message, maxTokens int, tem
 		Cost:         cost,
 		ResponseTime: responseTime,
 		FinishReason: grokResp.Choices[0].FinishReason,
+		ToolCalls:    toolCallsFromChat(grokResp.Choices[0].Message.ToolCalls),
 	}, nil
 }
 
+// newRequest builds a request to the Grok API, asking for the response to
+// be streamed when stream is set
+func (c *GrokClient) newRequest(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64, stream bool) (*http.Request, error) {
+	if maxTokens == 0 {
+		maxTokens = c.config.DefaultMaxTokens
+	}
+	if temperature == 0.0 {
+		temperature = c.config.DefaultTemperature
+	}
+
+	reqBody := GrokRequest{
+		Model:       modelID,
+		Messages:    chatMessages(messages),
+		Tools:       chatTools(tools),
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		TopP:        c.config.DefaultTopP,
+		Stream:      stream,
+	}
+	if stream {
+		reqBody.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
 // calculateCost calculates the cost based on token usage
 func (c *GrokClient) calculateCost(promptTokens, completionTokens int, inputPrice, outputPrice float64) float64 {
 	promptCost := (float64(promptTokens) / 1_000_000.0) * inputPrice
@@ -160,23 +188,18 @@ func (c *GrokClient) calculateCost(promptTokens, completionTokens int, inputPric
 }
 
 // CallWithRetry makes a request with retry logic
-func (c *GrokClient) CallWithRetry(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	var lastErr error
-
-	for attempt := 0; attempt < c.config.MaxRetries; attempt++ {
-		if attempt > 0 {
-			backoff := time.Duration(attempt) * time.Duration(c.config.RetryBackoff) * time.Second
-			time.Sleep(backoff)
-		}
-
-		resp, err := c.Call(modelID, messages, maxTokens, temperature)
-		if err == nil {
-			return resp, nil
-		}
+func (c *GrokClient) CallWithRetry(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return callWithRetry(ctx, c.config, func(ctx context.Context) (*Response, error) {
+		return c.Call(ctx, modelID, messages, maxTokens, temperature)
+	})
+}
 
-		lastErr = err
+// StreamCall makes a request to Grok API, passing the response text to
+// onChunk as it is generated
+func (c *GrokClient) StreamCall(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64, onChunk StreamHandler) (*Response, error) {
+	req, err := c.newRequest(ctx, modelID, messages, nil, maxTokens, temperature, true)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, fmt.Errorf("failed after %d retries: %w", c.config.MaxRetries, lastErr)
+	return streamChatCompletion(c.httpClient, req, modelID, messages, onChunk)
 }
-
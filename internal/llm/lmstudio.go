@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -24,11 +25,9 @@ func NewLMStudioClient(config *Config) *LMStudioClient {
 	}
 
 	return &LMStudioClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: time.Duration(config.RequestTimeout) * time.Second,
-		},
-		config: config,
+		baseURL:    baseURL,
+		httpClient: &http.Client{},
+		config:     config,
 	}
 }
 
@@ -50,11 +49,14 @@ func (c *LMStudioClient) IsAvailable() bool {
 
 // LMStudioRequest represents the request format for LM Studio (OpenAI-compatible)
 type LMStudioRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
-	TopP        float64   `json:"top_p,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []chatMessage  `json:"messages"`
+	Tools         []chatTool     `json:"tools,omitempty"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Temperature   float64        `json:"temperature,omitempty"`
+	TopP          float64        `json:"top_p,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
 }
 
 // LMStudioResponse represents the response from LM Studio
@@ -63,8 +65,9 @@ type LMStudioResponse struct {
 	Model   string `json:"model"`
 	Choices []struct {
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string         `json:"role"`
+			Content   string         `json:"content"`
+			ToolCalls []chatToolCall `json:"tool_calls"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -76,36 +79,22 @@ type LMStudioResponse struct {
 }
 
 // Call makes a request to LM Studio API
-func (c *LMStudioClient) Call(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	startTime := time.Now()
-
-	if maxTokens == 0 {
-		maxTokens = c.config.DefaultMaxTokens
-	}
-	if temperature == 0.0 {
-		temperature = c.config.DefaultTemperature
-	}
+func (c *LMStudioClient) Call(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return c.CallWithTools(ctx, modelID, messages, nil, maxTokens, temperature)
+}
 
-	reqBody := LMStudioRequest{
-		Model:       modelID,
-		Messages:    messages,
-		MaxTokens:   maxTokens,
-		Temperature: temperature,
-		TopP:        c.config.DefaultTopP,
-	}
+// CallWithTools makes a request to LM Studio API, offering tools to the model
+func (c *LMStudioClient) CallWithTools(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RequestDuration())
+	defer cancel()
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
+	startTime := time.Now()
 
-	req, err := http.NewRequest("POST", c.baseURL+"/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := c.newRequest(ctx, modelID, messages, tools, maxTokens, temperature, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -140,27 +129,67 @@ func (c *LMStudioClient) Call(modelID string, messages []Message, maxTokens int,
 		Cost:         0.0, // Local, no cost
 		ResponseTime: responseTime,
 		FinishReason: lmStudioResp.Choices[0].FinishReason,
+		ToolCalls:    toolCallsFromChat(lmStudioResp.Choices[0].Message.ToolCalls),
 	}, nil
 }
 
-// CallWithRetry makes a request with retry logic
-func (c *LMStudioClient) CallWithRetry(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	var lastErr error
+// newRequest builds a request to the LM Studio API, asking for the response to
+// be streamed when stream is set
+func (c *LMStudioClient) newRequest(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64, stream bool) (*http.Request, error) {
+	if maxTokens == 0 {
+		maxTokens = c.config.DefaultMaxTokens
+	}
+	if temperature == 0.0 {
+		temperature = c.config.DefaultTemperature
+	}
 
-	for attempt := 0; attempt < c.config.MaxRetries; attempt++ {
-		if attempt > 0 {
-			backoff := time.Duration(attempt) * time.Duration(c.config.RetryBackoff) * time.Second
-			time.Sleep(backoff)
-		}
+	reqBody := LMStudioRequest{
+		Model:       modelID,
+		Messages:    chatMessages(messages),
+		Tools:       chatTools(tools),
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		TopP:        c.config.DefaultTopP,
+		Stream:      stream,
+	}
+	if stream {
+		reqBody.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
 
-		resp, err := c.Call(modelID, messages, maxTokens, temperature)
-		if err == nil {
-			return resp, nil
-		}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
 
-		lastErr = err
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	return nil, fmt.Errorf("failed after %d retries: %w", c.config.MaxRetries, lastErr)
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+// CallWithRetry makes a request with retry logic
+func (c *LMStudioClient) CallWithRetry(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return callWithRetry(ctx, c.config, func(ctx context.Context) (*Response, error) {
+		return c.Call(ctx, modelID, messages, maxTokens, temperature)
+	})
 }
 
+// StreamCall makes a request to LM Studio API, passing the response text to
+// onChunk as it is generated
+func (c *LMStudioClient) StreamCall(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64, onChunk StreamHandler) (*Response, error) {
+	req, err := c.newRequest(ctx, modelID, messages, nil, maxTokens, temperature, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := streamChatCompletion(c.httpClient, req, modelID, messages, onChunk)
+	if err != nil {
+		return nil, err
+	}
+	resp.Cost = 0.0 // Local, no cost
+	return resp, nil
+}
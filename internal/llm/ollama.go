@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -25,9 +26,7 @@ func NewOllamaClient(config *Config) *OllamaClient {
 
 	return &OllamaClient{
 		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: time.Duration(config.RequestTimeout) * time.Second,
-		},
+		httpClient: &http.Client{},
 		config: config,
 	}
 }
@@ -50,58 +49,63 @@ func (c *OllamaClient) IsAvailable() bool {
 
 // OllamaRequest represents the request format for Ollama
 type OllamaRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Stream      bool      `json:"stream"`
-	Options     struct {
+	Model    string          `json:"model"`
+	Messages []OllamaMessage `json:"messages"`
+	Tools    []chatTool      `json:"tools,omitempty"` // declared like OpenAI's
+	Stream   bool            `json:"stream"`
+	Options  struct {
 		Temperature float64 `json:"temperature,omitempty"`
 		TopP        float64 `json:"top_p,omitempty"`
 		NumPredict  int     `json:"num_predict,omitempty"`
 	} `json:"options,omitempty"`
 }
 
+// OllamaMessage is a message in Ollama's format
+type OllamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // the tool that produced a tool result
+}
+
+// OllamaToolCall is a tool call in Ollama's format, whose arguments are a
+// JSON object rather than OpenAI's encoded string
+type OllamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
 // OllamaResponse represents the response from Ollama
 type OllamaResponse struct {
 	Model     string `json:"model"`
-	Message   Message `json:"message"`
+	Message   OllamaMessage `json:"message"`
 	Done      bool   `json:"done"`
 	TotalDuration int64 `json:"total_duration"`
 	PromptEvalCount int `json:"prompt_eval_count"`
 	EvalCount int    `json:"eval_count"`
+	DoneReason string `json:"done_reason,omitempty"`
+	Error     string `json:"error,omitempty"`
 }
 
 // Call makes a request to Ollama API
-func (c *OllamaClient) Call(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	startTime := time.Now()
-
-	if maxTokens == 0 {
-		maxTokens = c.config.DefaultMaxTokens
-	}
-	if temperature == 0.0 {
-		temperature = c.config.DefaultTemperature
-	}
+func (c *OllamaClient) Call(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return c.CallWithTools(ctx, modelID, messages, nil, maxTokens, temperature)
+}
 
-	reqBody := OllamaRequest{
-		Model:   modelID,
-		Messages: messages,
-		Stream:  false,
-	}
-	reqBody.Options.Temperature = temperature
-	reqBody.Options.TopP = c.config.DefaultTopP
-	reqBody.Options.NumPredict = maxTokens
+// CallWithTools makes a request to Ollama API, offering tools to the model
+func (c *OllamaClient) CallWithTools(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RequestDuration())
+	defer cancel()
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
+	startTime := time.Now()
 
-	req, err := http.NewRequest("POST", c.baseURL+"/api/chat", bytes.NewBuffer(jsonData))
+	req, err := c.newRequest(ctx, modelID, messages, tools, maxTokens, temperature, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -120,6 +124,16 @@ func (c *OllamaClient) Call(modelID string, messages []Message, maxTokens int, t
 
 	responseTime := time.Since(startTime)
 
+	// Ollama doesn't identify tool calls, so they are numbered
+	var toolCalls []ToolCall
+	for i, call := range ollamaResp.Message.ToolCalls {
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        fmt.Sprintf("call_%d", i),
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+
 	// Ollama is free (local), so cost is 0
 	return &Response{
 		Content: ollamaResp.Message.Content,
@@ -132,27 +146,129 @@ func (c *OllamaClient) Call(modelID string, messages []Message, maxTokens int, t
 		Cost:         0.0, // Local, no cost
 		ResponseTime: responseTime,
 		FinishReason: "stop",
+		ToolCalls:    toolCalls,
 	}, nil
 }
 
-// CallWithRetry makes a request with retry logic
-func (c *OllamaClient) CallWithRetry(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	var lastErr error
+// newRequest builds a request to the Ollama API, asking for the response to
+// be streamed when stream is set
+func (c *OllamaClient) newRequest(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64, stream bool) (*http.Request, error) {
+	if maxTokens == 0 {
+		maxTokens = c.config.DefaultMaxTokens
+	}
+	if temperature == 0.0 {
+		temperature = c.config.DefaultTemperature
+	}
 
-	for attempt := 0; attempt < c.config.MaxRetries; attempt++ {
-		if attempt > 0 {
-			backoff := time.Duration(attempt) * time.Duration(c.config.RetryBackoff) * time.Second
-			time.Sleep(backoff)
+	reqBody := OllamaRequest{
+		Model:   modelID,
+		Messages: ollamaMessages(messages),
+		Tools:    chatTools(tools),
+		Stream:  stream,
+	}
+	reqBody.Options.Temperature = temperature
+	reqBody.Options.TopP = c.config.DefaultTopP
+	reqBody.Options.NumPredict = maxTokens
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/chat", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+// ollamaMessages maps messages to Ollama's chat roles. Model templates only
+// use one system prompt, so the system messages are joined into a single one
+// at the start; any role Ollama doesn't know is sent as the user's.
+func ollamaMessages(messages []Message) []OllamaMessage {
+	system, conversation := splitSystem(messages)
+
+	mapped := make([]OllamaMessage, 0, len(conversation)+1)
+	if system != "" {
+		mapped = append(mapped, OllamaMessage{Role: "system", Content: system})
+	}
+	for _, msg := range conversation {
+		role := msg.Role
+		if role != "assistant" && role != "tool" {
+			role = "user"
+		}
+		converted := OllamaMessage{Role: role, Content: msg.Content}
+		if role == "tool" {
+			converted.ToolName = msg.Name
+		}
+		for _, call := range msg.ToolCalls {
+			var ollamaCall OllamaToolCall
+			ollamaCall.Function.Name = call.Name
+			ollamaCall.Function.Arguments = call.Arguments
+			converted.ToolCalls = append(converted.ToolCalls, ollamaCall)
 		}
+		mapped = append(mapped, converted)
+	}
+	return mapped
+}
+
+// CallWithRetry makes a request with retry logic
+func (c *OllamaClient) CallWithRetry(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return callWithRetry(ctx, c.config, func(ctx context.Context) (*Response, error) {
+		return c.Call(ctx, modelID, messages, maxTokens, temperature)
+	})
+}
+
+// StreamCall makes a request to Ollama API, passing the response text to
+// onChunk as it is generated. Ollama streams one JSON object per line.
+func (c *OllamaClient) StreamCall(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64, onChunk StreamHandler) (*Response, error) {
+	startTime := time.Now()
+
+	req, err := c.newRequest(ctx, modelID, messages, nil, maxTokens, temperature, true)
+	if err != nil {
+		return nil, err
+	}
 
-		resp, err := c.Call(modelID, messages, maxTokens, temperature)
-		if err == nil {
-			return resp, nil
+	resp, err := doStream(c.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &streamResult{onChunk: onChunk}
+	err = readNDJSON(resp.Body, func(line []byte) error {
+		var chunk OllamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("API error: %s", chunk.Error)
 		}
 
-		lastErr = err
+		result.model = chunk.Model
+		result.add(chunk.Message.Content)
+		if chunk.Done {
+			result.finishReason = chunk.DoneReason
+			if result.finishReason == "" {
+				result.finishReason = "stop"
+			}
+			result.usage.PromptTokens = chunk.PromptEvalCount
+			result.usage.CompletionTokens = chunk.EvalCount
+			return errStreamDone
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, result.fail(err)
 	}
 
-	return nil, fmt.Errorf("failed after %d retries: %w", c.config.MaxRetries, lastErr)
+	final, err := result.response(modelID, messages, startTime)
+	if err != nil {
+		return nil, err
+	}
+	final.Cost = 0.0 // Local, no cost
+	return final, nil
 }
-
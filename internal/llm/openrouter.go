@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -32,20 +33,21 @@ func NewOpenRouterClient(config *Config) *OpenRouterClient {
 	return &OpenRouterClient{
 		apiKey:  config.OpenRouterAPIKey,
 		baseURL: config.OpenRouterBaseURL,
-		httpClient: &http.Client{
-			Timeout: time.Duration(config.RequestTimeout) * time.Second,
-		},
+		httpClient: &http.Client{},
 		config: config,
 	}
 }
 
 // OpenRouterRequest represents the request format for OpenRouter
 type OpenRouterRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
-	TopP        float64   `json:"top_p,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []chatMessage  `json:"messages"`
+	Tools         []chatTool     `json:"tools,omitempty"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Temperature   float64        `json:"temperature,omitempty"`
+	TopP          float64        `json:"top_p,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
 }
 
 // OpenRouterResponse represents the response from OpenRouter
@@ -54,8 +56,9 @@ type OpenRouterResponse struct {
 	Model   string `json:"model"`
 	Choices []struct {
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string         `json:"role"`
+			Content   string         `json:"content"`
+			ToolCalls []chatToolCall `json:"tool_calls"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -67,40 +70,22 @@ type OpenRouterResponse struct {
 }
 
 // Call makes a request to OpenRouter API
-func (c *OpenRouterClient) Call(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+func (c *OpenRouterClient) Call(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return c.CallWithTools(ctx, modelID, messages, nil, maxTokens, temperature)
+}
+
+// CallWithTools makes a request to OpenRouter API, offering tools to the model
+func (c *OpenRouterClient) CallWithTools(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RequestDuration())
+	defer cancel()
+
 	startTime := time.Now()
 	
-	// Use defaults from config if not specified
-	if maxTokens == 0 {
-		maxTokens = c.config.DefaultMaxTokens
-	}
-	if temperature == 0.0 {
-		temperature = c.config.DefaultTemperature
-	}
-	
-	reqBody := OpenRouterRequest{
-		Model:       modelID,
-		Messages:    messages,
-		MaxTokens:   maxTokens,
-		Temperature: temperature,
-		TopP:        c.config.DefaultTopP,
-	}
-	
-	jsonData, err := json.Marshal(reqBody)
+	req, err := c.newRequest(ctx, modelID, messages, tools, maxTokens, temperature, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 	
-	req, err := http.NewRequest("POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("HTTP-Referer", c.config.HTTPReferer)
-	req.Header.Set("X-Title", c.config.XTitle)
-	
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -148,9 +133,52 @@ func (c *OpenRouterClient) Call(modelID string, messages []Message, maxTokens in
 		Cost:         cost,
 		ResponseTime: responseTime,
 		FinishReason: openRouterResp.Choices[0].FinishReason,
+		ToolCalls:    toolCallsFromChat(openRouterResp.Choices[0].Message.ToolCalls),
 	}, nil
 }
 
+// newRequest builds a request to the OpenRouter API, asking for the response to
+// be streamed when stream is set
+func (c *OpenRouterClient) newRequest(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64, stream bool) (*http.Request, error) {
+	// Use defaults from config if not specified
+	if maxTokens == 0 {
+		maxTokens = c.config.DefaultMaxTokens
+	}
+	if temperature == 0.0 {
+		temperature = c.config.DefaultTemperature
+	}
+	
+	reqBody := OpenRouterRequest{
+		Model:       modelID,
+		Messages:    chatMessages(messages),
+		Tools:       chatTools(tools),
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		TopP:        c.config.DefaultTopP,
+		Stream:      stream,
+	}
+	if stream {
+		reqBody.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("HTTP-Referer", c.config.HTTPReferer)
+	req.Header.Set("X-Title", c.config.XTitle)
+	
+	return req, nil
+}
+
 // calculateCost calculates the cost based on token usage
 func (c *OpenRouterClient) calculateCost(promptTokens, completionTokens int, inputPrice, outputPrice float64) float64 {
 	promptCost := (float64(promptTokens) / 1_000_000.0) * inputPrice
@@ -159,24 +187,18 @@ func (c *OpenRouterClient) calculateCost(promptTokens, completionTokens int, inp
 }
 
 // CallWithRetry makes a request with retry logic
-func (c *OpenRouterClient) CallWithRetry(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
-	var lastErr error
-	
-	for attempt := 0; attempt < c.config.MaxRetries; attempt++ {
-		if attempt > 0 {
-			// Exponential backoff using configured backoff time
-			backoff := time.Duration(attempt) * time.Duration(c.config.RetryBackoff) * time.Second
-			time.Sleep(backoff)
-		}
-		
-		resp, err := c.Call(modelID, messages, maxTokens, temperature)
-		if err == nil {
-			return resp, nil
-		}
-		
-		lastErr = err
-	}
-	
-	return nil, fmt.Errorf("failed after %d retries: %w", c.config.MaxRetries, lastErr)
+func (c *OpenRouterClient) CallWithRetry(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return callWithRetry(ctx, c.config, func(ctx context.Context) (*Response, error) {
+		return c.Call(ctx, modelID, messages, maxTokens, temperature)
+	})
 }
 
+// StreamCall makes a request to OpenRouter API, passing the response text to
+// onChunk as it is generated
+func (c *OpenRouterClient) StreamCall(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64, onChunk StreamHandler) (*Response, error) {
+	req, err := c.newRequest(ctx, modelID, messages, nil, maxTokens, temperature, true)
+	if err != nil {
+		return nil, err
+	}
+	return streamChatCompletion(c.httpClient, req, modelID, messages, onChunk)
+}
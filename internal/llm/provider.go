@@ -1,17 +1,29 @@
 package llm
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 )
 
-// Provider defines the interface for LLM providers
+// Provider defines the interface for LLM providers. Every call gives up as
+// soon as its context is cancelled or its deadline passes.
 type Provider interface {
-	// Call makes a request to the LLM API
-	Call(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error)
+	// Call makes a request to the LLM API, allowing it Config.RequestTimeout
+	Call(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error)
 	
 	// CallWithRetry makes a request with retry logic
-	CallWithRetry(modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error)
+	CallWithRetry(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error)
+	
+	// CallWithTools makes a request offering tools to the model. The tool
+	// calls it asks for come back in Response.ToolCalls.
+	CallWithTools(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64) (*Response, error)
+	
+	// StreamCall makes a request, passing the response text to onChunk as it
+	// is generated, and returns the complete response once the stream ends.
+	// Only ctx bounds how long the stream may run.
+	StreamCall(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64, onChunk StreamHandler) (*Response, error)
 	
 	// GetName returns the provider name
 	GetName() string
@@ -52,10 +64,79 @@ func (pf *ProviderFactory) CreateProvider() (Provider, error) {
 	}
 }
 
-// Message represents a chat message
+// Message represents a chat message. An assistant message may carry the
+// tool calls the model asked for; each result goes back in a message with
+// role "tool" naming the call it answers.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"` // the call a tool result answers
+	Name       string     `json:"name,omitempty"`         // the tool that produced a tool result
+	IsError    bool       `json:"is_error,omitempty"`     // the tool result reports a failure
+}
+
+// callWithRetry makes up to Config.MaxRetries attempts at a call, backing off
+// longer after each failure. It stops early once ctx is done.
+func callWithRetry(ctx context.Context, config *Config, call func(ctx context.Context) (*Response, error)) (*Response, error) {
+	attempts := config.MaxRetries
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(attempt) * time.Duration(config.RetryBackoff) * time.Second
+			if err := sleepContext(ctx, backoff); err != nil {
+				return nil, fmt.Errorf("gave up retrying: %w", err)
+			}
+		}
+
+		resp, err := call(ctx)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("gave up after %d attempts: %w", attempt+1, err)
+		}
+	}
+
+	return nil, fmt.Errorf("failed after %d retries: %w", attempts, lastErr)
+}
+
+// sleepContext waits for d, returning early with ctx's error if it is done
+// first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// splitSystem separates the system messages from the rest of the
+// conversation, joining them into one instruction for providers that take it
+// outside the message list
+func splitSystem(messages []Message) (string, []Message) {
+	var system []string
+	conversation := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			if content := strings.TrimSpace(msg.Content); content != "" {
+				system = append(system, content)
+			}
+			continue
+		}
+		conversation = append(conversation, msg)
+	}
+	return strings.Join(system, "\n\n"), conversation
 }
 
 // Response represents an LLM response
@@ -66,6 +147,7 @@ type Response struct {
 	Cost         float64
 	ResponseTime time.Duration
 	FinishReason string
+	ToolCalls    []ToolCall // tools the model asked to call before it answers
 }
 
 // TokenUsage tracks token consumption
@@ -0,0 +1,155 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingProvider answers nothing until its context is done, except for
+// the calls after the first blocked ones
+type blockingProvider struct {
+	calls   atomic.Int32
+	blocked int32 // calls that block before it answers; zero blocks every call
+}
+
+func (p *blockingProvider) Call(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	if call := p.calls.Add(1); p.blocked > 0 && call > p.blocked {
+		return &Response{Content: "Hello from " + modelID, Model: modelID}, nil
+	}
+	<-ctx.Done()
+	return nil, fmt.Errorf("failed to make request: %w", ctx.Err())
+}
+
+func (p *blockingProvider) CallWithRetry(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64) (*Response, error) {
+	return p.Call(ctx, modelID, messages, maxTokens, temperature)
+}
+
+func (p *blockingProvider) CallWithTools(ctx context.Context, modelID string, messages []Message, tools []Tool, maxTokens int, temperature float64) (*Response, error) {
+	return p.Call(ctx, modelID, messages, maxTokens, temperature)
+}
+
+func (p *blockingProvider) StreamCall(ctx context.Context, modelID string, messages []Message, maxTokens int, temperature float64, onChunk StreamHandler) (*Response, error) {
+	return p.Call(ctx, modelID, messages, maxTokens, temperature)
+}
+
+func (p *blockingProvider) GetName() string   { return "blocking" }
+func (p *blockingProvider) IsAvailable() bool { return true }
+
+func TestCancellation(t *testing.T) {
+	messages := []Message{{Role: "user", Content: "Hello"}}
+
+	t.Run("Test cancel aborts a request in flight", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}))
+		defer server.Close()
+		defer close(release)
+		config := &Config{OpenAIAPIKey: "key", OpenAIBaseURL: server.URL, RequestTimeout: 60, MaxRetries: 3, RetryBackoff: 1}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := NewOpenAIClient(config).CallWithRetry(ctx, "test-model", messages, 100, 0.5)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected a cancelled request, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the request to stop at once, took %v", elapsed)
+		}
+	})
+
+	t.Run("Test deadline cuts retry backoff short", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		config := &Config{AnthropicAPIKey: "key", AnthropicBaseURL: server.URL, RequestTimeout: 5, MaxRetries: 3, RetryBackoff: 10}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := NewAnthropicClient(config).CallWithRetry(ctx, "test-model", messages, 100, 0.5)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the deadline to end the retries, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the backoff to be cut short, took %v", elapsed)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("Expected a single attempt, got %d", calls.Load())
+		}
+	})
+
+	config := &Config{PrimaryModel: "openai/gpt-4-turbo", SecondaryModel: "anthropic/claude-3-opus"}
+	task := Task{Type: TaskTypeConsciousReasoning, Prompt: "Hello", Timeout: 50 * time.Millisecond}
+
+	t.Run("Test a model that times out falls back to the next", func(t *testing.T) {
+		provider := &blockingProvider{blocked: 1}
+		router := NewRouter(provider, config, nil)
+
+		resp, err := router.RouteToOptimalModel(context.Background(), task)
+		if err != nil {
+			t.Fatalf("Expected the second model to answer, got %v", err)
+		}
+		if provider.calls.Load() != 2 {
+			t.Errorf("Expected both models to be tried, got %d calls", provider.calls.Load())
+		}
+		// The model that hung is held to account
+		for modelID, perf := range router.performance {
+			if modelID == resp.Model && perf.TasksCompleted != 1 {
+				t.Errorf("Expected a success for %s, got %+v", modelID, perf)
+			}
+			if modelID != resp.Model && perf.TasksFailed != 1 {
+				t.Errorf("Expected the timeout to count against %s, got %+v", modelID, perf)
+			}
+		}
+		if len(router.performance) != 2 {
+			t.Errorf("Expected both models to be recorded, got %v", router.performance)
+		}
+	})
+
+	t.Run("Test cancel stops routing", func(t *testing.T) {
+		provider := &blockingProvider{}
+		router := NewRouter(provider, config, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		noTimeout := task
+		noTimeout.Timeout = 0
+
+		_, err := router.RouteToOptimalModel(ctx, noTimeout)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the task to be abandoned, got %v", err)
+		}
+		if provider.calls.Load() != 1 {
+			t.Errorf("Expected no other model to be tried, got %d calls", provider.calls.Load())
+		}
+		if len(router.performance) != 0 {
+			t.Errorf("Expected no failure to be recorded against the model, got %v", router.performance)
+		}
+	})
+
+	t.Run("Test model timeout covers every retry", func(t *testing.T) {
+		config := &Config{RequestTimeout: 30, MaxRetries: 3, RetryBackoff: 2}
+		if timeout := config.ModelTimeout(); timeout != 96*time.Second {
+			t.Errorf("Expected 3 requests and 2 backoffs, got %v", timeout)
+		}
+		if timeout := (&Config{}).ModelTimeout(); timeout != 60*time.Second {
+			t.Errorf("Expected the default request timeout, got %v", timeout)
+		}
+	})
+}
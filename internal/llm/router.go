@@ -1,6 +1,8 @@
 package llm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 )
@@ -24,8 +26,39 @@ func NewRouter(provider Provider, config *Config, costManager *CostManager) *Rou
 	}
 }
 
-// RouteToOptimalModel routes a task to the best model based on requirements
-func (r *Router) RouteToOptimalModel(task Task) (*Response, error) {
+// RouteToOptimalModel routes a task to the best model based on requirements.
+// A model that doesn't answer within the task's timeout is given up on for
+// the next best one; ctx being done ends the task.
+func (r *Router) RouteToOptimalModel(ctx context.Context, task Task) (*Response, error) {
+	return r.route(ctx, task, func(ctx context.Context, modelID string) (*Response, error) {
+		return r.provider.CallWithRetry(ctx, modelID, task.Conversation(), task.MaxTokens, task.Temperature)
+	})
+}
+
+// StreamToOptimalModel routes a task like RouteToOptimalModel, passing the
+// response text to onChunk as it is generated. A model that fails before
+// producing any text is skipped for the next best one; a stream that breaks
+// off part way is returned as an error.
+func (r *Router) StreamToOptimalModel(ctx context.Context, task Task, onChunk StreamHandler) (*Response, error) {
+	return r.route(ctx, task, func(ctx context.Context, modelID string) (*Response, error) {
+		return r.provider.StreamCall(ctx, modelID, task.Conversation(), task.MaxTokens, task.Temperature, onChunk)
+	})
+}
+
+// RouteWithTools routes a task like RouteToOptimalModel, offering tools to
+// the model. Models that can use tools are preferred.
+func (r *Router) RouteWithTools(ctx context.Context, task Task, tools []Tool) (*Response, error) {
+	task.RequiresToolUse = true
+	return r.route(ctx, task, func(ctx context.Context, modelID string) (*Response, error) {
+		return callWithRetry(ctx, r.config, func(ctx context.Context) (*Response, error) {
+			return r.provider.CallWithTools(ctx, modelID, task.Conversation(), tools, task.MaxTokens, task.Temperature)
+		})
+	})
+}
+
+// route tries the models that fit a task, best first, until call succeeds.
+// Each model gets the task's timeout to itself.
+func (r *Router) route(ctx context.Context, task Task, call func(ctx context.Context, modelID string) (*Response, error)) (*Response, error) {
 	// Get available models
 	availableModels := GetAvailableModels()
 	
@@ -59,6 +92,10 @@ func (r *Router) RouteToOptimalModel(task Task) (*Response, error) {
 	
 	// Try models in order of fitness, checking budget
 	for _, scored := range scoredModels {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("task abandoned: %w", err)
+		}
+		
 		// Check if we can afford this model
 		estimatedCost := r.estimateCost(scored.model, task)
 		if task.Budget > 0 && estimatedCost > task.Budget {
@@ -74,18 +111,7 @@ func (r *Router) RouteToOptimalModel(task Task) (*Response, error) {
 		}
 		
 		// Try this model
-		// Note: For now, we pass the prompt directly
-		// In the future, we can use the message builder from prompts
-		messages := []Message{
-			{Role: "user", Content: task.Prompt},
-		}
-		
-		resp, err := r.provider.CallWithRetry(
-			scored.model.ID,
-			messages,
-			task.MaxTokens,
-			task.Temperature,
-		)
+		resp, err := r.callModel(ctx, task, scored.model.ID, call)
 		
 		if err == nil {
 			// Record performance
@@ -93,14 +119,34 @@ func (r *Router) RouteToOptimalModel(task Task) (*Response, error) {
 			return resp, nil
 		}
 		
+		// A cancelled task says nothing about the model
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("task abandoned: %w", err)
+		}
+		
 		// Record failure
 		r.recordPerformance(scored.model.ID, nil, false)
+		
+		// Text already shown can't be taken back by switching models
+		if errors.Is(err, errStreamInterrupted) {
+			return nil, err
+		}
 	}
 	
 	// If all models failed, return error
 	return nil, fmt.Errorf("all models failed or exceeded budget")
 }
 
+// callModel calls a single model, within the task's timeout
+func (r *Router) callModel(ctx context.Context, task Task, modelID string, call func(ctx context.Context, modelID string) (*Response, error)) (*Response, error) {
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.Timeout)
+		defer cancel()
+	}
+	return call(ctx, modelID)
+}
+
 // calculateModelFitness calculates how well a model fits a task
 func (r *Router) calculateModelFitness(model Model, task Task) float64 {
 	score := 0.0
@@ -156,7 +202,7 @@ func (r *Router) calculateModelFitness(model Model, task Task) float64 {
 // estimateCost estimates the cost of a task with a given model
 func (r *Router) estimateCost(model Model, task Task) float64 {
 	// Estimate tokens (rough approximation: 1 token ≈ 4 characters)
-	estimatedPromptTokens := len(task.Prompt) / 4
+	estimatedPromptTokens := task.promptLength() / 4
 	estimatedCompletionTokens := task.MaxTokens
 	
 	if estimatedCompletionTokens == 0 {
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultContextLength is the context window assumed when none of the
+// configured models is known
+const defaultContextLength = 8192
+
+// Session is a multi-turn conversation. Every turn is kept; only the newest
+// ones that fit the model's context window are sent, with a summary of the
+// turns before them.
+type Session struct {
+	ID         string    `json:"id"`
+	Turns      []Turn    `json:"turns"`
+	Summary    string    `json:"summary,omitempty"`    // condensed form of the first Summarized turns
+	Summarized int       `json:"summarized,omitempty"` // turns covered by Summary
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Turn is one message of a session
+type Turn struct {
+	Role    string    `json:"role"` // "user" or "assistant"
+	Content string    `json:"content"`
+	Model   string    `json:"model,omitempty"` // the model that wrote an assistant turn
+	Time    time.Time `json:"time"`
+}
+
+// NewSession starts an empty session identified by its start time. A random
+// suffix keeps sessions started in the same second apart.
+func NewSession() *Session {
+	now := time.Now()
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return &Session{
+		ID:        fmt.Sprintf("%s-%x", now.Format("20060102-150405"), suffix),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Append adds a turn to the session
+func (s *Session) Append(role, content, model string) {
+	now := time.Now()
+	s.Turns = append(s.Turns, Turn{Role: role, Content: content, Model: model, Time: now})
+	s.UpdatedAt = now
+}
+
+// Title returns the session's first user message, shortened for listings
+func (s *Session) Title() string {
+	for _, turn := range s.Turns {
+		if turn.Role == "user" {
+			if runes := []rune(turn.Content); len(runes) > 60 {
+				return string(runes[:60]) + "…"
+			}
+			return turn.Content
+		}
+	}
+	return "(empty)"
+}
+
+// Window splits the turns not yet summarized into those that fit in budget
+// tokens, newest first, and the older ones that don't. At least the newest
+// turn is always kept.
+func (s *Session) Window(budget int) (dropped, kept []Turn) {
+	pending := s.Turns[s.Summarized:]
+	if s.Summary != "" {
+		budget -= EstimateTokens(s.Summary)
+	}
+
+	start := len(pending)
+	for start > 0 {
+		cost := EstimateTokens(pending[start-1].Content)
+		if budget < cost && start < len(pending) {
+			break
+		}
+		budget -= cost
+		start--
+	}
+	return pending[:start], pending[start:]
+}
+
+// Messages returns the session's summary and turns as chat messages
+func (s *Session) Messages(turns []Turn) []Message {
+	messages := make([]Message, 0, len(turns)+1)
+	if s.Summary != "" {
+		messages = append(messages, Message{
+			Role:    "system",
+			Content: "Summary of the conversation so far:\n" + s.Summary,
+		})
+	}
+	for _, turn := range turns {
+		messages = append(messages, Message{Role: turn.Role, Content: turn.Content})
+	}
+	return messages
+}
+
+// Transcript renders the session as Markdown
+func (s *Session) Transcript() string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("# Session %s\n\n", s.ID))
+	builder.WriteString(fmt.Sprintf("Started %s, %d turns\n", s.CreatedAt.Format("2006-01-02 15:04:05"), len(s.Turns)))
+	for _, turn := range s.Turns {
+		speaker := "You"
+		if turn.Role == "assistant" {
+			speaker = "Phoenix"
+		}
+		builder.WriteString(fmt.Sprintf("\n**%s** (%s):\n\n%s\n", speaker, turn.Time.Format("15:04:05"), turn.Content))
+	}
+	return builder.String()
+}
+
+// EstimateTokens roughly counts the tokens in a text, at 4 characters a token
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	// Each turn is 100 characters, 25 tokens
+	newSession := func(turns int) *Session {
+		session := NewSession()
+		for i := 0; i < turns; i++ {
+			role := "user"
+			if i%2 == 1 {
+				role = "assistant"
+			}
+			session.Append(role, strings.Repeat(string(rune('a'+i)), 100), "")
+		}
+		return session
+	}
+
+	t.Run("Test sessions started together get their own IDs", func(t *testing.T) {
+		first, second := NewSession(), NewSession()
+		if first.ID == second.ID {
+			t.Errorf("Expected distinct session IDs, both were %s", first.ID)
+		}
+	})
+
+	t.Run("Test window keeps the newest turns", func(t *testing.T) {
+		session := newSession(6)
+
+		dropped, kept := session.Window(60)
+		if len(dropped) != 4 || len(kept) != 2 {
+			t.Fatalf("Expected 4 dropped and 2 kept turns, got %d and %d", len(dropped), len(kept))
+		}
+		if kept[1].Content != session.Turns[5].Content {
+			t.Errorf("Expected the newest turn to be kept, got %q", kept[1].Content)
+		}
+
+		if dropped, kept := session.Window(1000); len(dropped) != 0 || len(kept) != 6 {
+			t.Errorf("Expected every turn to fit, got %d dropped", len(dropped))
+		}
+		if _, kept := session.Window(0); len(kept) != 1 {
+			t.Errorf("Expected the newest turn to be kept whatever the budget, got %d", len(kept))
+		}
+	})
+
+	t.Run("Test summary replaces old turns", func(t *testing.T) {
+		session := newSession(6)
+		session.Summary = strings.Repeat("s", 40) // 10 tokens
+		session.Summarized = 2
+
+		dropped, kept := session.Window(60)
+		if len(dropped) != 2 || len(kept) != 2 {
+			t.Fatalf("Expected 2 dropped and 2 kept turns, got %d and %d", len(dropped), len(kept))
+		}
+		if dropped[0].Content != session.Turns[2].Content {
+			t.Errorf("Expected summarized turns to be skipped, got %q", dropped[0].Content)
+		}
+
+		messages := session.Messages(kept)
+		if len(messages) != 3 || messages[0].Role != "system" || !strings.Contains(messages[0].Content, session.Summary) {
+			t.Errorf("Expected the summary ahead of the turns, got %+v", messages)
+		}
+		if messages[2].Role != "assistant" {
+			t.Errorf("Expected turn roles to be kept, got %q", messages[2].Role)
+		}
+	})
+
+	t.Run("Test title and transcript", func(t *testing.T) {
+		session := NewSession()
+		if session.Title() != "(empty)" {
+			t.Errorf("Unexpected title for an empty session: %q", session.Title())
+		}
+		session.Append("user", "Hello Phoenix", "")
+		session.Append("assistant", "Hi Dad", "test-model")
+		if session.Title() != "Hello Phoenix" {
+			t.Errorf("Expected the first message as title, got %q", session.Title())
+		}
+		transcript := session.Transcript()
+		if !strings.Contains(transcript, session.ID) || !strings.Contains(transcript, "**Phoenix**") {
+			t.Errorf("Unexpected transcript:\n%s", transcript)
+		}
+	})
+}
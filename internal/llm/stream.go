@@ -0,0 +1,251 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StreamHandler receives each piece of a response's text as it arrives
+type StreamHandler func(chunk string)
+
+// StreamOptions asks OpenAI-compatible APIs to report token usage in the
+// last chunk of a stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// maxStreamLine bounds a single line of a streamed response
+const maxStreamLine = 1024 * 1024
+
+// errStreamDone stops reading a stream once its end marker arrives
+var errStreamDone = errors.New("stream done")
+
+// errStreamInterrupted marks a stream that failed after text had already
+// been handed out, so it must not be retried on another model
+var errStreamInterrupted = errors.New("stream interrupted")
+
+// doStream sends a streaming request and checks its status. The caller
+// closes the response body.
+func doStream(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+	return resp, nil
+}
+
+// readSSE reads a server-sent event stream, calling fn with the name and
+// data of each event. fn returns errStreamDone to stop reading early.
+func readSSE(body io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		err := fn(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return ignoreStreamDone(err)
+			}
+			event = ""
+		case strings.HasPrefix(line, ":"):
+			// Comment, such as a keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return ignoreStreamDone(dispatch())
+}
+
+// readNDJSON reads a stream of newline-delimited JSON values, calling fn
+// with each line. fn returns errStreamDone to stop reading early.
+func readNDJSON(body io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return ignoreStreamDone(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
+
+func ignoreStreamDone(err error) error {
+	if errors.Is(err, errStreamDone) {
+		return nil
+	}
+	return err
+}
+
+// streamResult gathers a streamed completion into a Response
+type streamResult struct {
+	content      strings.Builder
+	model        string
+	finishReason string
+	usage        TokenUsage
+	onChunk      StreamHandler
+}
+
+// add records a piece of text and hands it to the stream handler
+func (r *streamResult) add(text string) {
+	if text == "" {
+		return
+	}
+	r.content.WriteString(text)
+	if r.onChunk != nil {
+		r.onChunk(text)
+	}
+}
+
+// fail wraps an error that ended the stream, marking it as interrupted if
+// text had already been handed out
+func (r *streamResult) fail(err error) error {
+	if r.content.Len() > 0 {
+		return fmt.Errorf("%w: %w", errStreamInterrupted, err)
+	}
+	return err
+}
+
+// response builds the final response once the stream has ended. Token counts
+// the API didn't report are estimated from the text, and the cost is priced
+// from the model table.
+func (r *streamResult) response(modelID string, messages []Message, startTime time.Time) (*Response, error) {
+	if r.content.Len() == 0 {
+		return nil, fmt.Errorf("no content in stream")
+	}
+
+	usage := r.usage
+	if usage.PromptTokens == 0 {
+		for _, msg := range messages {
+			usage.PromptTokens += EstimateTokens(msg.Content)
+		}
+	}
+	if usage.CompletionTokens == 0 {
+		usage.CompletionTokens = EstimateTokens(r.content.String())
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	model, exists := GetModel(modelID)
+	if !exists {
+		model = Model{InputPrice: 1.0, OutputPrice: 1.0}
+	}
+	cost := (float64(usage.PromptTokens)/1_000_000.0)*model.InputPrice +
+		(float64(usage.CompletionTokens)/1_000_000.0)*model.OutputPrice
+
+	name := r.model
+	if name == "" {
+		name = modelID
+	}
+	return &Response{
+		Content:      r.content.String(),
+		Model:        name,
+		TokensUsed:   usage,
+		Cost:         cost,
+		ResponseTime: time.Since(startTime),
+		FinishReason: r.finishReason,
+	}, nil
+}
+
+// chatCompletionChunk is one event of an OpenAI-compatible stream
+type chatCompletionChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// streamChatCompletion sends a request to an OpenAI-compatible API and
+// reads the server-sent events of the completion as they arrive
+func streamChatCompletion(client *http.Client, req *http.Request, modelID string, messages []Message, onChunk StreamHandler) (*Response, error) {
+	startTime := time.Now()
+
+	resp, err := doStream(client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &streamResult{onChunk: onChunk}
+	err = readSSE(resp.Body, func(_, data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+
+		if chunk.Model != "" {
+			result.model = chunk.Model
+		}
+		if len(chunk.Choices) > 0 {
+			result.add(chunk.Choices[0].Delta.Content)
+			if chunk.Choices[0].FinishReason != "" {
+				result.finishReason = chunk.Choices[0].FinishReason
+			}
+		}
+		if chunk.Usage != nil {
+			result.usage = TokenUsage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, result.fail(err)
+	}
+
+	return result.response(modelID, messages, startTime)
+}
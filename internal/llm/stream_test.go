@@ -0,0 +1,202 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamCall(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "You are Phoenix"},
+		{Role: "user", Content: "Hello"},
+	}
+
+	// serve answers every request with body, after checking it asked for a stream
+	serve := func(t *testing.T, contentType string, body string, check func(r *http.Request, req map[string]any)) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req map[string]any
+			json.NewDecoder(r.Body).Decode(&req)
+			if check != nil {
+				check(r, req)
+			}
+			w.Header().Set("Content-Type", contentType)
+			fmt.Fprint(w, body)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	// collect streams a call and returns the chunks it handed out
+	collect := func(t *testing.T, provider Provider) (*Response, []string) {
+		t.Helper()
+		var chunks []string
+		resp, err := provider.StreamCall(context.Background(), "test-model", messages, 100, 0.5, func(chunk string) {
+			chunks = append(chunks, chunk)
+		})
+		if err != nil {
+			t.Fatalf("Failed to stream: %v", err)
+		}
+		if resp.Content != strings.Join(chunks, "") {
+			t.Errorf("Expected the response to hold every chunk, got %q and %q", resp.Content, chunks)
+		}
+		return resp, chunks
+	}
+
+	newConfig := func() *Config {
+		return &Config{RequestTimeout: 5, MaxRetries: 1, DefaultMaxTokens: 100, DefaultTemperature: 0.7}
+	}
+
+	t.Run("Test OpenAI server-sent events", func(t *testing.T) {
+		body := `data: {"model":"gpt-test","choices":[{"delta":{"role":"assistant"}}]}
+
+data: {"model":"gpt-test","choices":[{"delta":{"content":"Hi "}}]}
+
+: keep-alive
+
+data: {"model":"gpt-test","choices":[{"delta":{"content":"Dad"},"finish_reason":"stop"}]}
+
+data: {"model":"gpt-test","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":2,"total_tokens":14}}
+
+data: [DONE]
+
+`
+		server := serve(t, "text/event-stream", body, func(r *http.Request, req map[string]any) {
+			if r.URL.Path != "/chat/completions" || req["stream"] != true || req["stream_options"] == nil {
+				t.Errorf("Unexpected request to %s: %v", r.URL.Path, req)
+			}
+		})
+		config := newConfig()
+		config.OpenAIAPIKey, config.OpenAIBaseURL = "key", server.URL
+
+		resp, chunks := collect(t, NewOpenAIClient(config))
+		if len(chunks) != 2 || resp.Content != "Hi Dad" || resp.Model != "gpt-test" || resp.FinishReason != "stop" {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+		if resp.TokensUsed.PromptTokens != 12 || resp.TokensUsed.CompletionTokens != 2 || resp.TokensUsed.TotalTokens != 14 {
+			t.Errorf("Expected the reported usage, got %+v", resp.TokensUsed)
+		}
+	})
+
+	t.Run("Test Anthropic server-sent events", func(t *testing.T) {
+		body := `event: message_start
+data: {"type":"message_start","message":{"model":"claude-test","usage":{"input_tokens":9}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Hello"}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","delta":{"type":"text_delta","text":" there"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+		server := serve(t, "text/event-stream", body, func(r *http.Request, req map[string]any) {
+			if req["stream"] != true || req["system"] != "You are Phoenix" || len(req["messages"].([]any)) != 1 {
+				t.Errorf("Unexpected request: %v", req)
+			}
+		})
+		config := newConfig()
+		config.AnthropicAPIKey, config.AnthropicBaseURL = "key", server.URL
+
+		resp, _ := collect(t, NewAnthropicClient(config))
+		if resp.Content != "Hello there" || resp.Model != "claude-test" || resp.FinishReason != "end_turn" {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+		if resp.TokensUsed.PromptTokens != 9 || resp.TokensUsed.CompletionTokens != 3 {
+			t.Errorf("Expected the reported usage, got %+v", resp.TokensUsed)
+		}
+	})
+
+	t.Run("Test Gemini stream", func(t *testing.T) {
+		body := `data: {"candidates":[{"content":{"parts":[{"text":"Sun"}]}}]}
+
+data: {"candidates":[{"content":{"parts":[{"text":"rise"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":2,"totalTokenCount":9}}
+
+`
+		server := serve(t, "text/event-stream", body, func(r *http.Request, req map[string]any) {
+			if !strings.HasSuffix(r.URL.Path, ":streamGenerateContent") || r.URL.Query().Get("alt") != "sse" {
+				t.Errorf("Unexpected request URL: %s", r.URL)
+			}
+			if req["systemInstruction"] == nil {
+				t.Errorf("Expected a system instruction, got %v", req)
+			}
+		})
+		config := newConfig()
+		config.GeminiAPIKey, config.GeminiBaseURL = "key", server.URL
+
+		resp, _ := collect(t, NewGeminiClient(config))
+		if resp.Content != "Sunrise" || resp.FinishReason != "STOP" || resp.TokensUsed.TotalTokens != 9 {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("Test Ollama newline-delimited JSON", func(t *testing.T) {
+		body := `{"model":"llama-test","message":{"role":"assistant","content":"Local"},"done":false}
+{"model":"llama-test","message":{"role":"assistant","content":" answer"},"done":false}
+{"model":"llama-test","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":5,"eval_count":2}
+`
+		server := serve(t, "application/x-ndjson", body, func(r *http.Request, req map[string]any) {
+			if r.URL.Path != "/api/chat" || req["stream"] != true {
+				t.Errorf("Unexpected request to %s: %v", r.URL.Path, req)
+			}
+		})
+		config := newConfig()
+		config.OllamaBaseURL = server.URL
+
+		resp, _ := collect(t, NewOllamaClient(config))
+		if resp.Content != "Local answer" || resp.FinishReason != "length" || resp.Cost != 0 {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+		if resp.TokensUsed.PromptTokens != 5 || resp.TokensUsed.CompletionTokens != 2 {
+			t.Errorf("Expected the reported usage, got %+v", resp.TokensUsed)
+		}
+	})
+
+	t.Run("Test missing usage is estimated", func(t *testing.T) {
+		body := "data: {\"choices\":[{\"delta\":{\"content\":\"12345678\"}}]}\n\ndata: [DONE]\n\n"
+		server := serve(t, "text/event-stream", body, nil)
+		config := newConfig()
+		config.LMStudioBaseURL = server.URL
+
+		resp, _ := collect(t, NewLMStudioClient(config))
+		if resp.TokensUsed.CompletionTokens != 2 || resp.TokensUsed.PromptTokens == 0 || resp.Model != "test-model" {
+			t.Errorf("Expected estimated usage, got %+v", resp)
+		}
+	})
+
+	t.Run("Test errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+		config := newConfig()
+		config.GrokAPIKey, config.GrokBaseURL = "key", server.URL
+
+		_, err := NewGrokClient(config).StreamCall(context.Background(), "test-model", messages, 0, 0, func(string) {})
+		if err == nil || !strings.Contains(err.Error(), "429") || errors.Is(err, errStreamInterrupted) {
+			t.Errorf("Expected a status error, got %v", err)
+		}
+
+		// A stream that breaks off after some text can't be retried elsewhere
+		body := "data: {\"choices\":[{\"delta\":{\"content\":\"Half\"}}]}\n\ndata: {not json}\n\n"
+		broken := serve(t, "text/event-stream", body, nil)
+		config.GrokBaseURL = broken.URL
+		_, err = NewGrokClient(config).StreamCall(context.Background(), "test-model", messages, 0, 0, func(string) {})
+		if !errors.Is(err, errStreamInterrupted) {
+			t.Errorf("Expected an interrupted stream, got %v", err)
+		}
+	})
+}
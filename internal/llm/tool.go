@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Schema is the subset of JSON Schema used to describe tool parameters. Every
+// provider accepts it as is: OpenAI-compatible APIs and Ollama as
+// "parameters", Anthropic as "input_schema" and Gemini as a function
+// declaration's "parameters".
+type Schema struct {
+	Type        string             `json:"type"` // "object", "string", "integer", "number", "boolean" or "array"
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"` // element schema of an array
+	Enum        []string           `json:"enum,omitempty"`
+}
+
+// ToolHandler runs a tool with the arguments a model chose, a JSON object
+// matching the tool's parameters, and returns the result to show the model
+type ToolHandler func(ctx context.Context, args json.RawMessage) (string, error)
+
+// Tool is a function a model may ask to call
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  *Schema // an object schema; a tool without parameters takes an empty object
+	Handler     ToolHandler
+}
+
+// parameters returns the tool's parameter schema, which providers require
+// even for tools that take no arguments
+func (t Tool) parameters() *Schema {
+	if t.Parameters == nil {
+		return &Schema{Type: "object", Properties: map[string]*Schema{}}
+	}
+	return t.Parameters
+}
+
+// ToolCall is a model's request to run a tool
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// toolNamePattern matches the names every provider accepts
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]{0,63}$`)
+
+// ToolRegistry holds the tools offered to models
+type ToolRegistry struct {
+	tools map[string]Tool
+	mu    sync.RWMutex
+}
+
+// NewToolRegistry creates an empty tool registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]Tool)}
+}
+
+// Register adds a tool to the registry
+func (r *ToolRegistry) Register(tool Tool) error {
+	if !toolNamePattern.MatchString(tool.Name) {
+		return fmt.Errorf("invalid tool name %q", tool.Name)
+	}
+	if tool.Handler == nil {
+		return fmt.Errorf("tool %s has no handler", tool.Name)
+	}
+	if tool.Parameters != nil && tool.Parameters.Type != "object" {
+		return fmt.Errorf("tool %s parameters must be an object schema", tool.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[tool.Name]; exists {
+		return fmt.Errorf("tool %s is already registered", tool.Name)
+	}
+	r.tools[tool.Name] = tool
+	return nil
+}
+
+// Get returns the tool registered under name
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool, exists := r.tools[name]
+	return tool, exists
+}
+
+// List returns the registered tools sorted by name
+func (r *ToolRegistry) List() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// Execute runs the tool a model asked for, after checking its arguments
+// against the tool's parameters
+func (r *ToolRegistry) Execute(ctx context.Context, call ToolCall) (string, error) {
+	tool, exists := r.Get(call.Name)
+	if !exists {
+		return "", fmt.Errorf("unknown tool %s", call.Name)
+	}
+
+	args := call.Arguments
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(args, &fields); err != nil {
+		return "", fmt.Errorf("arguments of %s are not a JSON object: %w", call.Name, err)
+	}
+	for _, name := range tool.parameters().Required {
+		if _, ok := fields[name]; !ok {
+			return "", fmt.Errorf("missing required argument %s of %s", name, call.Name)
+		}
+	}
+
+	result, err := tool.Handler(ctx, args)
+	if err != nil {
+		return "", fmt.Errorf("tool %s failed: %w", call.Name, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,361 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// weatherTool is a tool with a required parameter that echoes its city
+func weatherTool() Tool {
+	return Tool{
+		Name:        "weather",
+		Description: "Get the weather in a city",
+		Parameters: &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"city": {Type: "string"}},
+			Required:   []string{"city"},
+		},
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			var params struct {
+				City string `json:"city"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return "", err
+			}
+			if params.City == "Atlantis" {
+				return "", errors.New("city not found")
+			}
+			return "Sunny in " + params.City, nil
+		},
+	}
+}
+
+func TestToolRegistry(t *testing.T) {
+	registry := NewToolRegistry()
+	if err := registry.Register(weatherTool()); err != nil {
+		t.Fatalf("Failed to register tool: %v", err)
+	}
+
+	t.Run("Test registration is validated", func(t *testing.T) {
+		handler := weatherTool().Handler
+		invalid := []Tool{
+			weatherTool(), // already registered
+			{Name: "bad name", Handler: handler},
+			{Name: "no_handler"},
+			{Name: "not_object", Parameters: &Schema{Type: "string"}, Handler: handler},
+		}
+		for _, tool := range invalid {
+			if err := registry.Register(tool); err == nil {
+				t.Errorf("Expected tool %q to be rejected", tool.Name)
+			}
+		}
+
+		registry.Register(Tool{Name: "clock", Handler: handler})
+		tools := registry.List()
+		if len(tools) != 2 || tools[0].Name != "clock" || tools[1].Name != "weather" {
+			t.Errorf("Expected the tools sorted by name, got %+v", tools)
+		}
+	})
+
+	t.Run("Test execute", func(t *testing.T) {
+		ctx := context.Background()
+		result, err := registry.Execute(ctx, ToolCall{Name: "weather", Arguments: json.RawMessage(`{"city":"Paris"}`)})
+		if err != nil || result != "Sunny in Paris" {
+			t.Errorf("Unexpected result %q: %v", result, err)
+		}
+
+		failing := []ToolCall{
+			{Name: "unknown", Arguments: json.RawMessage(`{}`)},
+			{Name: "weather", Arguments: json.RawMessage(`{}`)},
+			{Name: "weather", Arguments: json.RawMessage(`"Paris"`)},
+			{Name: "weather", Arguments: json.RawMessage(`{"city":"Atlantis"}`)},
+		}
+		for _, call := range failing {
+			if _, err := registry.Execute(ctx, call); err == nil {
+				t.Errorf("Expected %s %s to fail", call.Name, call.Arguments)
+			}
+		}
+	})
+}
+
+func TestToolTranslation(t *testing.T) {
+	// The conversation after the model asked for the weather in two cities,
+	// one of which the tool failed to find
+	messages := []Message{
+		{Role: "system", Content: "You are Phoenix"},
+		{Role: "user", Content: "Weather in Paris and Rome?"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "call_1", Name: "weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+			{ID: "call_2", Name: "weather", Arguments: json.RawMessage(`{"city":"Rome"}`)},
+		}},
+		{Role: "tool", ToolCallID: "call_1", Name: "weather", Content: "Sunny in Paris"},
+		{Role: "tool", ToolCallID: "call_2", Name: "weather", Content: "Error: city not found", IsError: true},
+	}
+	tools := []Tool{weatherTool()}
+
+	// serve answers with body and hands the request it received to check
+	serve := func(t *testing.T, body string, check func(req map[string]any)) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req map[string]any
+			json.NewDecoder(r.Body).Decode(&req)
+			check(req)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, body)
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+
+	// expectCall checks a response asked for the weather in Oslo
+	expectCall := func(t *testing.T, resp *Response, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Failed to call with tools: %v", err)
+		}
+		if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "weather" || resp.ToolCalls[0].ID == "" {
+			t.Fatalf("Expected a weather call, got %+v", resp.ToolCalls)
+		}
+		var args map[string]string
+		if err := json.Unmarshal(resp.ToolCalls[0].Arguments, &args); err != nil || args["city"] != "Oslo" {
+			t.Errorf("Unexpected arguments %s", resp.ToolCalls[0].Arguments)
+		}
+	}
+
+	newConfig := func() *Config {
+		return &Config{RequestTimeout: 5, MaxRetries: 1, DefaultMaxTokens: 100, DefaultTemperature: 0.7}
+	}
+
+	t.Run("Test OpenAI tools", func(t *testing.T) {
+		body := `{"model":"gpt-test","choices":[{"message":{"role":"assistant","content":null,"tool_calls":[
+			{"id":"call_9","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Oslo\"}"}}]},
+			"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":20,"completion_tokens":5,"total_tokens":25}}`
+		config := newConfig()
+		config.OpenAIAPIKey = "key"
+		config.OpenAIBaseURL = serve(t, body, func(req map[string]any) {
+			declared := req["tools"].([]any)[0].(map[string]any)
+			if declared["type"] != "function" || declared["function"].(map[string]any)["parameters"] == nil {
+				t.Errorf("Unexpected tool declaration: %v", declared)
+			}
+			sent := req["messages"].([]any)
+			call := sent[2].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)
+			if call["function"].(map[string]any)["arguments"] != `{"city":"Paris"}` {
+				t.Errorf("Expected arguments encoded as a string, got %v", call)
+			}
+			if result := sent[3].(map[string]any); result["role"] != "tool" || result["tool_call_id"] != "call_1" {
+				t.Errorf("Unexpected tool result: %v", result)
+			}
+		})
+
+		resp, err := NewOpenAIClient(config).CallWithTools(context.Background(), "gpt-test", messages, tools, 0, 0)
+		expectCall(t, resp, err)
+		if resp.ToolCalls[0].ID != "call_9" || resp.FinishReason != "tool_calls" {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("Test Anthropic tool_use blocks", func(t *testing.T) {
+		body := `{"model":"claude-test","content":[{"type":"text","text":"Checking."},
+			{"type":"tool_use","id":"toolu_1","name":"weather","input":{"city":"Oslo"}}],
+			"stop_reason":"tool_use","usage":{"input_tokens":20,"output_tokens":5}}`
+		config := newConfig()
+		config.AnthropicAPIKey = "key"
+		config.AnthropicBaseURL = serve(t, body, func(req map[string]any) {
+			declared := req["tools"].([]any)[0].(map[string]any)
+			if declared["name"] != "weather" || declared["input_schema"] == nil {
+				t.Errorf("Unexpected tool declaration: %v", declared)
+			}
+			sent := req["messages"].([]any)
+			if len(sent) != 3 {
+				t.Fatalf("Expected both results in one message, got %v", sent)
+			}
+			use := sent[1].(map[string]any)["content"].([]any)[0].(map[string]any)
+			if use["type"] != "tool_use" || use["id"] != "call_1" {
+				t.Errorf("Unexpected tool_use block: %v", use)
+			}
+			results := sent[2].(map[string]any)
+			blocks := results["content"].([]any)
+			if results["role"] != "user" || len(blocks) != 2 || blocks[1].(map[string]any)["tool_use_id"] != "call_2" {
+				t.Fatalf("Unexpected tool results: %v", results)
+			}
+			if blocks[0].(map[string]any)["is_error"] != nil || blocks[1].(map[string]any)["is_error"] != true {
+				t.Errorf("Expected only the failed result marked as an error: %v", blocks)
+			}
+		})
+
+		resp, err := NewAnthropicClient(config).CallWithTools(context.Background(), "claude-test", messages, tools, 0, 0)
+		expectCall(t, resp, err)
+		if resp.Content != "Checking." {
+			t.Errorf("Expected only the text blocks as content, got %q", resp.Content)
+		}
+	})
+
+	t.Run("Test Gemini function declarations", func(t *testing.T) {
+		body := `{"candidates":[{"content":{"parts":[{"functionCall":{"name":"weather","args":{"city":"Oslo"}}}]},
+			"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":20,"candidatesTokenCount":5,"totalTokenCount":25}}`
+		config := newConfig()
+		config.GeminiAPIKey = "key"
+		config.GeminiBaseURL = serve(t, body, func(req map[string]any) {
+			declarations := req["tools"].([]any)[0].(map[string]any)["functionDeclarations"].([]any)
+			if len(declarations) != 1 || declarations[0].(map[string]any)["name"] != "weather" {
+				t.Errorf("Unexpected function declarations: %v", declarations)
+			}
+			contents := req["contents"].([]any)
+			if len(contents) != 3 {
+				t.Fatalf("Expected both results in one message, got %v", contents)
+			}
+			call := contents[1].(map[string]any)
+			if call["role"] != "model" || call["parts"].([]any)[0].(map[string]any)["functionCall"] == nil {
+				t.Errorf("Unexpected function call: %v", call)
+			}
+			parts := contents[2].(map[string]any)["parts"].([]any)
+			response := parts[0].(map[string]any)["functionResponse"].(map[string]any)
+			if len(parts) != 2 || response["name"] != "weather" {
+				t.Errorf("Unexpected function responses: %v", parts)
+			}
+		})
+
+		resp, err := NewGeminiClient(config).CallWithTools(context.Background(), "gemini-test", messages, tools, 0, 0)
+		expectCall(t, resp, err)
+	})
+
+	t.Run("Test Gemini responses follow call order", func(t *testing.T) {
+		body := `{"candidates":[{"content":{"parts":[{"functionCall":{"name":"weather","args":{"city":"Oslo"}}},
+			{"functionCall":{"name":"weather","args":{"city":"Bergen"}}}]},"finishReason":"STOP"}],
+			"usageMetadata":{"promptTokenCount":20,"candidatesTokenCount":5,"totalTokenCount":25}}`
+		config := newConfig()
+		config.GeminiAPIKey = "key"
+		config.GeminiBaseURL = serve(t, body, func(req map[string]any) {})
+
+		resp, err := NewGeminiClient(config).CallWithTools(context.Background(), "gemini-test", messages[:2], tools, 0, 0)
+		if err != nil {
+			t.Fatalf("Failed to call with tools: %v", err)
+		}
+		if len(resp.ToolCalls) != 2 || resp.ToolCalls[0].ID == resp.ToolCalls[1].ID {
+			t.Fatalf("Expected two distinct calls, got %+v", resp.ToolCalls)
+		}
+
+		// The results come back in the opposite order to the calls
+		followUp := append(messages[:2:2],
+			Message{Role: "assistant", ToolCalls: resp.ToolCalls},
+			Message{Role: "tool", ToolCallID: resp.ToolCalls[1].ID, Name: "weather", Content: "Rain in Bergen"},
+			Message{Role: "tool", ToolCallID: resp.ToolCalls[0].ID, Name: "weather", Content: "Sunny in Oslo"},
+		)
+		config.GeminiBaseURL = serve(t, body, func(req map[string]any) {
+			contents := req["contents"].([]any)
+			parts := contents[len(contents)-1].(map[string]any)["parts"].([]any)
+			var order []any
+			for _, part := range parts {
+				order = append(order, part.(map[string]any)["functionResponse"].(map[string]any)["response"].(map[string]any)["content"])
+			}
+			if len(order) != 2 || order[0] != "Sunny in Oslo" || order[1] != "Rain in Bergen" {
+				t.Errorf("Expected the responses in the order of the calls, got %v", order)
+			}
+		})
+		if _, err := NewGeminiClient(config).CallWithTools(context.Background(), "gemini-test", followUp, tools, 0, 0); err != nil {
+			t.Fatalf("Failed to call with tools: %v", err)
+		}
+	})
+
+	t.Run("Test Ollama tool calls", func(t *testing.T) {
+		body := `{"model":"llama-test","message":{"role":"assistant","content":"","tool_calls":[
+			{"function":{"name":"weather","arguments":{"city":"Oslo"}}}]},"done":true}`
+		config := newConfig()
+		config.OllamaBaseURL = serve(t, body, func(req map[string]any) {
+			if len(req["tools"].([]any)) != 1 {
+				t.Errorf("Expected the tool to be declared, got %v", req["tools"])
+			}
+			sent := req["messages"].([]any)
+			call := sent[2].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)
+			if call["function"].(map[string]any)["arguments"].(map[string]any)["city"] != "Paris" {
+				t.Errorf("Expected arguments as an object, got %v", call)
+			}
+			if result := sent[3].(map[string]any); result["tool_name"] != "weather" {
+				t.Errorf("Unexpected tool result: %v", result)
+			}
+		})
+
+		resp, err := NewOllamaClient(config).CallWithTools(context.Background(), "llama-test", messages, tools, 0, 0)
+		expectCall(t, resp, err)
+	})
+}
+
+func TestAgent(t *testing.T) {
+	// The model asks for the weather twice, once for a city that fails, then
+	// answers with what it learned, unless it never answers
+	var requests []map[string]any
+	answers := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+
+		usage := `"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}`
+		if len(requests) == 1 || !answers {
+			fmt.Fprintf(w, `{"model":"gpt-test","choices":[{"message":{"role":"assistant","tool_calls":[
+				{"id":"a","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Oslo\"}"}},
+				{"id":"b","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Atlantis\"}"}}]}}],%s}`, usage)
+			return
+		}
+		fmt.Fprintf(w, `{"model":"gpt-test","choices":[{"message":{"role":"assistant","content":"Sunny in Oslo"},"finish_reason":"stop"}],%s}`, usage)
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Provider:         "openai",
+		OpenAIAPIKey:     "key",
+		OpenAIBaseURL:    server.URL,
+		PrimaryModel:     "openai/gpt-4-turbo",
+		RequestTimeout:   5,
+		MaxRetries:       1,
+		DefaultMaxTokens: 100,
+		DailyBudget:      10,
+		MonthlyBudget:    100,
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	registry := NewToolRegistry()
+	registry.Register(weatherTool())
+
+	agent := NewAgent(client, registry)
+	var ran []string
+	agent.OnToolCall = func(call ToolCall, result string, err error) {
+		ran = append(ran, call.ID)
+	}
+
+	resp, err := agent.Run(context.Background(), "Weather?", nil)
+	if err != nil {
+		t.Fatalf("Failed to run agent: %v", err)
+	}
+	if resp.Content != "Sunny in Oslo" || len(ran) != 2 {
+		t.Errorf("Unexpected answer %q after running %v", resp.Content, ran)
+	}
+	if resp.TokensUsed.TotalTokens != 24 {
+		t.Errorf("Expected the tokens of both rounds, got %+v", resp.TokensUsed)
+	}
+
+	sent := requests[1]["messages"].([]any)
+	results := sent[len(sent)-2:]
+	if results[0].(map[string]any)["content"] != "Sunny in Oslo" ||
+		!strings.HasPrefix(results[1].(map[string]any)["content"].(string), "Error:") {
+		t.Errorf("Expected the results and the error to be fed back, got %v", results)
+	}
+
+	t.Run("Test rounds are bounded", func(t *testing.T) {
+		requests, answers = nil, false
+		agent.MaxRounds = 2
+		if _, err := agent.Run(context.Background(), "Weather?", nil); err == nil {
+			t.Error("Expected an agent that never answers to give up")
+		}
+		if len(requests) != 3 {
+			t.Errorf("Expected 2 rounds of tool calls and a last chance to answer, got %d requests", len(requests))
+		}
+	})
+}
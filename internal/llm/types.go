@@ -28,6 +28,7 @@ type Capabilities struct {
 type Task struct {
 	Type            TaskType
 	Prompt          string
+	Messages        []Message // the conversation to send; a lone user message with Prompt when empty
 	ContextLength   int
 	RequiresReasoning bool
 	RequiresCreativity bool
@@ -36,6 +37,24 @@ type Task struct {
 	MaxTokens       int
 	Temperature     float64
 	Budget          float64 // Maximum cost for this task
+	Timeout         time.Duration // Deadline for each model tried, its retries included; none when zero
+}
+
+// Conversation returns the messages to send for the task
+func (t Task) Conversation() []Message {
+	if len(t.Messages) > 0 {
+		return t.Messages
+	}
+	return []Message{{Role: "user", Content: t.Prompt}}
+}
+
+// promptLength returns the number of characters sent for the task
+func (t Task) promptLength() int {
+	length := 0
+	for _, msg := range t.Conversation() {
+		length += len(msg.Content)
+	}
+	return length
 }
 
 // TaskType represents the type of task